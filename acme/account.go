@@ -2,17 +2,18 @@ package acme
 
 import (
 	"crypto"
-	"crypto/rand"
-	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/containous/traefik/cluster"
 	"github.com/containous/traefik/log"
 	"github.com/xenolf/lego/acme"
 )
@@ -24,6 +25,7 @@ type Account struct {
 	PrivateKey         []byte
 	DomainsCertificate DomainsCertificates
 	ChallengeCerts     map[string]*ChallengeCert
+	HTTPChallenge      map[string]map[string][]byte
 }
 
 // ChallengeCert stores a challenge certificate
@@ -59,10 +61,85 @@ func (a *Account) Init() error {
 	return nil
 }
 
-// NewAccount creates an account
-func NewAccount(email string) (*Account, error) {
+var _ cluster.SplitObject = (*Account)(nil)
+
+const (
+	accountKey         = "account"
+	certificatesPrefix = "certificates/"
+)
+
+// accountMetadata is the account stored without its certificates
+type accountMetadata struct {
+	Email          string
+	Registration   *acme.RegistrationResource
+	PrivateKey     []byte
+	ChallengeCerts map[string]*ChallengeCert
+	HTTPChallenge  map[string]map[string][]byte
+}
+
+// Split returns the account metadata and each of its certificates as separate
+// values, the certificates being keyed by their domains
+func (a *Account) Split() (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	metadata, err := json.Marshal(accountMetadata{
+		Email:          a.Email,
+		Registration:   a.Registration,
+		PrivateKey:     a.PrivateKey,
+		ChallengeCerts: a.ChallengeCerts,
+		HTTPChallenge:  a.HTTPChallenge,
+	})
+	if err != nil {
+		return nil, err
+	}
+	values[accountKey] = metadata
+
+	a.DomainsCertificate.lock.RLock()
+	defer a.DomainsCertificate.lock.RUnlock()
+	for _, domainsCertificate := range a.DomainsCertificate.Certs {
+		value, err := json.Marshal(domainsCertificate)
+		if err != nil {
+			return nil, err
+		}
+		values[certificatesPrefix+domainsCertificate.Domains.name()] = value
+	}
+	return values, nil
+}
+
+// Join fills the account from the values returned by Split
+func (a *Account) Join(values map[string][]byte) error {
+	metadata := accountMetadata{}
+	if value, ok := values[accountKey]; ok {
+		if err := json.Unmarshal(value, &metadata); err != nil {
+			return fmt.Errorf("invalid account: %v", err)
+		}
+	}
+	var certs []*DomainsCertificate
+	for key, value := range values {
+		if !strings.HasPrefix(key, certificatesPrefix) {
+			continue
+		}
+		domainsCertificate := &DomainsCertificate{}
+		if err := json.Unmarshal(value, domainsCertificate); err != nil {
+			return fmt.Errorf("invalid certificate %s: %v", strings.TrimPrefix(key, certificatesPrefix), err)
+		}
+		certs = append(certs, domainsCertificate)
+	}
+
+	a.Email = metadata.Email
+	a.Registration = metadata.Registration
+	a.PrivateKey = metadata.PrivateKey
+	a.ChallengeCerts = metadata.ChallengeCerts
+	a.HTTPChallenge = metadata.HTTPChallenge
+	a.DomainsCertificate.lock.Lock()
+	a.DomainsCertificate.Certs = certs
+	a.DomainsCertificate.lock.Unlock()
+	return nil
+}
+
+// NewAccount creates an account with a private key of the key type
+func NewAccount(email string, keyType acme.KeyType) (*Account, error) {
 	// Create a user. New accounts need an email and private key to start
-	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	_, privateKey, err := generatePrivateKey(keyType)
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +147,10 @@ func NewAccount(email string) (*Account, error) {
 	domainsCerts.Init()
 	return &Account{
 		Email:              email,
-		PrivateKey:         x509.MarshalPKCS1PrivateKey(privateKey),
+		PrivateKey:         privateKey,
 		DomainsCertificate: DomainsCertificates{Certs: domainsCerts.Certs},
-		ChallengeCerts:     map[string]*ChallengeCert{}}, nil
+		ChallengeCerts:     map[string]*ChallengeCert{},
+		HTTPChallenge:      map[string]map[string][]byte{}}, nil
 }
 
 // GetEmail returns email
@@ -87,7 +165,7 @@ func (a *Account) GetRegistration() *acme.RegistrationResource {
 
 // GetPrivateKey returns private key
 func (a *Account) GetPrivateKey() crypto.PrivateKey {
-	if privateKey, err := x509.ParsePKCS1PrivateKey(a.PrivateKey); err == nil {
+	if privateKey, err := parsePrivateKey(a.PrivateKey); err == nil {
 		return privateKey
 	}
 	log.Errorf("Cannot unmarshall private key %+v", a.PrivateKey)
@@ -228,15 +306,16 @@ type DomainsCertificate struct {
 	tlsCert     *tls.Certificate
 }
 
-func (dc *DomainsCertificate) needRenew() bool {
+// needRenew checks if the certificate, or one of its chain, expires within the
+// renewal lead time
+func (dc *DomainsCertificate) needRenew(renewBefore time.Duration) bool {
 	for _, c := range dc.tlsCert.Certificate {
 		crt, err := x509.ParseCertificate(c)
 		if err != nil {
 			// If there's an error, we assume the cert is broken, and needs update
 			return true
 		}
-		// <= 30 days left, renew certificate
-		if crt.NotAfter.Before(time.Now().Add(time.Duration(24 * 30 * time.Hour))) {
+		if crt.NotAfter.Before(time.Now().Add(renewBefore)) {
 			return true
 		}
 	}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	fmtlog "log"
+	"math"
+	"net"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
@@ -14,6 +17,8 @@ import (
 
 	"github.com/BurntSushi/ty/fun"
 	"github.com/cenk/backoff"
+	"github.com/containous/flaeg"
+	"github.com/containous/mux"
 	"github.com/containous/staert"
 	"github.com/containous/traefik/cluster"
 	"github.com/containous/traefik/log"
@@ -31,24 +36,30 @@ var (
 
 // ACME allows to connect to lets encrypt and retrieve certs
 type ACME struct {
-	Email               string   `description:"Email address used for registration"`
-	Domains             []Domain `description:"SANs (alternative domains) to each main domain using format: --acme.domains='main.com,san1.com,san2.com' --acme.domains='main.net,san1.net,san2.net'"`
-	Storage             string   `description:"File or key used for certificates storage."`
-	StorageFile         string   // deprecated
-	OnDemand            bool     `description:"Enable on demand certificate. This will request a certificate from Let's Encrypt during the first TLS handshake for a hostname that does not yet have a certificate."`
-	OnHostRule          bool     `description:"Enable certificate generation on frontends Host rules."`
-	CAServer            string   `description:"CA server to use."`
-	EntryPoint          string   `description:"Entrypoint to proxy acme challenge to."`
-	DNSProvider         string   `description:"Use a DNS based challenge provider rather than HTTPS."`
-	DelayDontCheckDNS   int      `description:"Assume DNS propagates after a delay in seconds rather than finding and querying nameservers."`
-	ACMELogging         bool     `description:"Enable debug logging of ACME actions."`
-	client              *acme.Client
-	defaultCertificate  *tls.Certificate
-	store               cluster.Store
-	challengeProvider   *challengeProvider
-	checkOnDemandDomain func(domain string) bool
-	jobs                *channels.InfiniteChannel
-	TLSConfig           *tls.Config `description:"TLS config in case wildcard certs are used"`
+	Email                 string          `description:"Email address used for registration"`
+	Domains               []Domain        `description:"SANs (alternative domains) to each main domain using format: --acme.domains='main.com,san1.com,san2.com' --acme.domains='main.net,san1.net,san2.net'"`
+	Storage               string          `description:"File or key used for certificates storage."`
+	StorageFile           string          // deprecated
+	OnDemand              bool            `description:"Enable on demand certificate. This will request a certificate from Let's Encrypt during the first TLS handshake for a hostname that does not yet have a certificate."`
+	OnDemandPolicy        *OnDemandPolicy `description:"Restrict the on demand certificates with allowed domains, a rate limit and an ask URL."`
+	OnHostRule            bool            `description:"Enable certificate generation on frontends Host rules."`
+	CAServer              string          `description:"CA server to use."`
+	EntryPoint            string          `description:"Entrypoint to proxy acme challenge to."`
+	DNSProvider           string          `description:"Use a DNS based challenge provider rather than HTTPS."`
+	HTTPChallenge         *HTTPChallenge  `description:"Use the HTTP-01 challenge served from an HTTP entrypoint rather than the TLS-SNI-01 one."`
+	DelayDontCheckDNS     int             `description:"Assume DNS propagates after a delay in seconds rather than finding and querying nameservers."`
+	KeyType               string          `description:"Key type of the account and certificates keys: RSA2048, RSA4096, EC256 or EC384. Default: RSA4096"`
+	RenewBefore           flaeg.Duration  `description:"Renew the certificates expiring within this duration. Default: 720h"`
+	RenewInterval         flaeg.Duration  `description:"Interval between the checks of the certificates to renew. Default: 24h"`
+	ACMELogging           bool            `description:"Enable debug logging of ACME actions."`
+	client                *acme.Client
+	defaultCertificate    *tls.Certificate
+	store                 cluster.Store
+	challengeProvider     *challengeProvider
+	challengeHTTPProvider *challengeHTTPProvider
+	checkOnDemandDomain   func(domain string) bool
+	jobs                  *channels.InfiniteChannel
+	TLSConfig             *tls.Config `description:"TLS config in case wildcard certs are used"`
 }
 
 //Domains parse []Domain
@@ -86,12 +97,22 @@ func (ds *Domains) SetValue(val interface{}) {
 	*ds = Domains(val.([]Domain))
 }
 
+// HTTPChallenge holds the HTTP-01 challenge configuration
+type HTTPChallenge struct {
+	EntryPoint string `description:"HTTP entrypoint used to answer the HTTP-01 challenges."`
+}
+
 // Domain holds a domain name with SANs
 type Domain struct {
 	Main string
 	SANs []string
 }
 
+// name returns the main domain and the SANs, in the format of the domains flag
+func (d Domain) name() string {
+	return strings.Join(append([]string{d.Main}, d.SANs...), ",")
+}
+
 func (a *ACME) init() error {
 	if a.ACMELogging {
 		acme.Logger = fmtlog.New(os.Stderr, "legolog: ", fmtlog.LstdFlags)
@@ -109,10 +130,50 @@ func (a *ACME) init() error {
 		log.Warnf("ACME.StorageFile is deprecated, use ACME.Storage instead")
 		a.Storage = a.StorageFile
 	}
+	if len(a.KeyType) == 0 {
+		a.KeyType = defaultKeyType
+	}
+	if _, ok := keyTypes[a.KeyType]; !ok {
+		return fmt.Errorf("Unknown ACME key type %s", a.KeyType)
+	}
+	if a.RenewBefore < 0 || a.RenewInterval < 0 {
+		return errors.New("Invalid negative ACME renewal duration")
+	}
+	// a certificate would expire between two checks otherwise
+	if a.renewInterval() >= a.renewBefore() {
+		return fmt.Errorf("Invalid ACME renewal interval %s, must be shorter than the renewal delay %s", a.renewInterval(), a.renewBefore())
+	}
+	if a.OnDemandPolicy != nil {
+		if err := a.OnDemandPolicy.init(); err != nil {
+			return err
+		}
+	}
+	registerMetrics()
 	a.jobs = channels.NewInfiniteChannel()
 	return nil
 }
 
+func (a *ACME) keyType() acme.KeyType {
+	if keyType, ok := keyTypes[a.KeyType]; ok {
+		return keyType
+	}
+	return keyTypes[defaultKeyType]
+}
+
+func (a *ACME) renewBefore() time.Duration {
+	if a.RenewBefore == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(a.RenewBefore)
+}
+
+func (a *ACME) renewInterval() time.Duration {
+	if a.RenewInterval == 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(a.RenewInterval)
+}
+
 // CreateClusterConfig creates a tls.config using ACME configuration in cluster mode
 func (a *ACME) CreateClusterConfig(leadership *cluster.Leadership, tlsConfig *tls.Config, checkOnDemandDomain func(domain string) bool) error {
 	err := a.init()
@@ -129,6 +190,7 @@ func (a *ACME) CreateClusterConfig(leadership *cluster.Leadership, tlsConfig *tl
 	listener := func(object cluster.Object) error {
 		account := object.(*Account)
 		account.Init()
+		updateCertificatesMetrics(&account.DomainsCertificate)
 		if !leadership.IsLeader() {
 			a.client, err = a.buildACMEClient(account)
 			if err != nil {
@@ -152,8 +214,9 @@ func (a *ACME) CreateClusterConfig(leadership *cluster.Leadership, tlsConfig *tl
 
 	a.store = datastore
 	a.challengeProvider = &challengeProvider{store: a.store}
+	a.challengeHTTPProvider = &challengeHTTPProvider{store: a.store}
 
-	ticker := time.NewTicker(24 * time.Hour)
+	ticker := time.NewTicker(a.renewInterval())
 	leadership.Pool.AddGoCtx(func(ctx context.Context) {
 		log.Infof("Starting ACME renew job...")
 		defer log.Infof("Stopped ACME renew job...")
@@ -181,7 +244,7 @@ func (a *ACME) CreateClusterConfig(leadership *cluster.Leadership, tlsConfig *tl
 			account.Init()
 			var needRegister bool
 			if account == nil || len(account.Email) == 0 {
-				account, err = NewAccount(a.Email)
+				account, err = NewAccount(a.Email, a.keyType())
 				if err != nil {
 					return err
 				}
@@ -249,6 +312,7 @@ func (a *ACME) CreateLocalConfig(tlsConfig *tls.Config, checkOnDemandDomain func
 	localStore := NewLocalStore(a.Storage)
 	a.store = localStore
 	a.challengeProvider = &challengeProvider{store: a.store}
+	a.challengeHTTPProvider = &challengeHTTPProvider{store: a.store}
 
 	var needRegister bool
 	var account *Account
@@ -263,7 +327,7 @@ func (a *ACME) CreateLocalConfig(tlsConfig *tls.Config, checkOnDemandDomain func
 		account = object.(*Account)
 	} else {
 		log.Infof("Generating ACME Account...")
-		account, err = NewAccount(a.Email)
+		account, err = NewAccount(a.Email, a.keyType())
 		if err != nil {
 			return err
 		}
@@ -315,7 +379,7 @@ func (a *ACME) CreateLocalConfig(tlsConfig *tls.Config, checkOnDemandDomain func
 	a.renewCertificates()
 	a.runJobs()
 
-	ticker := time.NewTicker(24 * time.Hour)
+	ticker := time.NewTicker(a.renewInterval())
 	safe.Go(func() {
 		for range ticker.C {
 			a.renewCertificates()
@@ -348,12 +412,54 @@ func (a *ACME) getCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificat
 		if a.checkOnDemandDomain != nil && !a.checkOnDemandDomain(domain) {
 			return nil, nil
 		}
-		return a.loadCertificateOnDemand(clientHello)
+		if a.OnDemandPolicy == nil {
+			return a.loadCertificateOnDemand(clientHello)
+		}
+		if err := a.OnDemandPolicy.allowed(domain); err != nil {
+			log.Debugf("ACME on demand certificate refused: %v", err)
+			return nil, nil
+		}
+		cert, err := a.loadCertificateOnDemand(clientHello)
+		if err != nil {
+			a.OnDemandPolicy.failed(domain, time.Now())
+			return nil, err
+		}
+		a.OnDemandPolicy.succeeded(domain)
+		return cert, nil
 	}
 	log.Debugf("ACME got nothing %s", domain)
 	return nil, nil
 }
 
+// AddRoutes adds the route answering the HTTP-01 challenges to the router of
+// the HTTP challenge entrypoint, ahead of the routes of the frontends
+func (a *ACME) AddRoutes(router *mux.Router) {
+	router.Methods(http.MethodGet).
+		Path(acme.HTTP01ChallengePath("{token}")).
+		Handler(http.HandlerFunc(a.serveHTTPChallenge)).
+		Priority(math.MaxInt32)
+}
+
+func (a *ACME) serveHTTPChallenge(rw http.ResponseWriter, req *http.Request) {
+	if a.challengeHTTPProvider == nil {
+		http.NotFound(rw, req)
+		return
+	}
+	domain := req.Host
+	if host, _, err := net.SplitHostPort(req.Host); err == nil {
+		domain = host
+	}
+	token := mux.Vars(req)["token"]
+	keyAuth := a.challengeHTTPProvider.getTokenValue(token, types.CanonicalDomain(domain))
+	if len(keyAuth) == 0 {
+		http.NotFound(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(keyAuth)
+}
+
 func (a *ACME) retrieveCertificates() {
 	a.jobs.In() <- func() {
 		log.Infof("Retrieving ACME certificates...")
@@ -396,16 +502,23 @@ func (a *ACME) renewCertificates() {
 		log.Debugf("Testing certificate renew...")
 		account := a.store.Get().(*Account)
 		for _, certificateResource := range account.DomainsCertificate.Certs {
-			if certificateResource.needRenew() {
+			if certificateResource.needRenew(a.renewBefore()) {
 				log.Debugf("Renewing certificate %+v", certificateResource.Domains)
+				// the key is kept, unless the key type has been changed since it was generated
+				privateKey := certificateResource.Certificate.PrivateKey
+				if !matchKeyType(privateKey, a.keyType()) {
+					log.Infof("Generating a new %s key for certificate %+v", a.KeyType, certificateResource.Domains)
+					privateKey = nil
+				}
 				renewedCert, err := a.client.RenewCertificate(acme.CertificateResource{
 					Domain:        certificateResource.Certificate.Domain,
 					CertURL:       certificateResource.Certificate.CertURL,
 					CertStableURL: certificateResource.Certificate.CertStableURL,
-					PrivateKey:    certificateResource.Certificate.PrivateKey,
+					PrivateKey:    privateKey,
 					Certificate:   certificateResource.Certificate.Certificate,
 				}, true, OSCPMustStaple)
 				if err != nil {
+					certificateFailures.WithLabelValues(renewalOperation).Inc()
 					log.Errorf("Error renewing certificate: %v", err)
 					continue
 				}
@@ -435,6 +548,7 @@ func (a *ACME) renewCertificates() {
 				}
 			}
 		}
+		updateCertificatesMetrics(&account.DomainsCertificate)
 	}
 }
 
@@ -458,7 +572,7 @@ func (a *ACME) buildACMEClient(account *Account) (*acme.Client, error) {
 	if len(a.CAServer) > 0 {
 		caServer = a.CAServer
 	}
-	client, err := acme.NewClient(caServer, account, acme.RSA4096)
+	client, err := acme.NewClient(caServer, account, a.keyType())
 	if err != nil {
 		return nil, err
 	}
@@ -479,6 +593,10 @@ func (a *ACME) buildACMEClient(account *Account) (*acme.Client, error) {
 
 		client.ExcludeChallenges([]acme.Challenge{acme.HTTP01, acme.TLSSNI01})
 		err = client.SetChallengeProvider(acme.DNS01, provider)
+	} else if a.HTTPChallenge != nil {
+		log.Debugf("Using HTTP Challenge provider on entrypoint %s", a.HTTPChallenge.EntryPoint)
+		client.ExcludeChallenges([]acme.Challenge{acme.DNS01, acme.TLSSNI01})
+		err = client.SetChallengeProvider(acme.HTTP01, a.challengeHTTPProvider)
 	} else {
 		client.ExcludeChallenges([]acme.Challenge{acme.HTTP01, acme.DNS01})
 		err = client.SetChallengeProvider(acme.TLSSNI01, a.challengeProvider)
@@ -514,6 +632,7 @@ func (a *ACME) loadCertificateOnDemand(clientHello *tls.ClientHelloInfo) (*tls.C
 	if err = transaction.Commit(account); err != nil {
 		return nil, err
 	}
+	updateCertificatesMetrics(&account.DomainsCertificate)
 	return cert.tlsCert, nil
 }
 
@@ -575,6 +694,7 @@ func (a *ACME) LoadCertificateForDomains(domains []string) {
 			log.Errorf("Error Saving ACME account %+v: %v", account, err)
 			return
 		}
+		updateCertificatesMetrics(&account.DomainsCertificate)
 	}
 }
 
@@ -584,6 +704,7 @@ func (a *ACME) getDomainsCertificates(domains []string) (*Certificate, error) {
 	bundle := true
 	certificate, failures := a.client.ObtainCertificate(domains, bundle, nil, OSCPMustStaple)
 	if len(failures) > 0 {
+		certificateFailures.WithLabelValues(issuanceOperation).Inc()
 		log.Error(failures)
 		return nil, fmt.Errorf("Cannot obtain certificates %s+v", failures)
 	}
@@ -1,15 +1,31 @@
 package acme
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/containous/flaeg"
+	"github.com/containous/mux"
+	"github.com/containous/traefik/types"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/xenolf/lego/acme"
+	"gopkg.in/square/go-jose.v1"
 )
 
 func TestDomainsSet(t *testing.T) {
@@ -277,3 +293,587 @@ cijFkALeQp/qyeXdFld2v9gUN3eCgljgcl0QweRoIc=---`)
 		t.Errorf("No change to acme.PreCheckDNS when meant to be adding enforcing override function.")
 	}
 }
+
+func TestAcmeClientCreationHTTPChallenge(t *testing.T) {
+	account := &Account{Email: "f@f"}
+	account.PrivateKey, _ = base64.StdEncoding.DecodeString(`
+MIIBPAIBAAJBAMp2Ni92FfEur+CAvFkgC12LT4l9D53ApbBpDaXaJkzzks+KsLw9zyAxvlrfAyTCQ
+7tDnEnIltAXyQ0uOFUUdcMCAwEAAQJAK1FbipATZcT9cGVa5x7KD7usytftLW14heQUPXYNV80r/3
+lmnpvjL06dffRpwkYeN8DATQF/QOcy3NNNGDw/4QIhAPAKmiZFxA/qmRXsuU8Zhlzf16WrNZ68K64
+asn/h3qZrAiEA1+wFR3WXCPIolOvd7AHjfgcTKQNkoMPywU4FYUNQ1AkCIQDv8yk0qPjckD6HVCPJ
+llJh9MC0svjevGtNlxJoE3lmEQIhAKXy1wfZ32/XtcrnENPvi6lzxI0T94X7s5pP3aCoPPoJAiEAl
+cijFkALeQp/qyeXdFld2v9gUN3eCgljgcl0QweRoIc=---`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+"new-authz": "https://foo/acme/new-authz",
+"new-cert": "https://foo/acme/new-cert",
+"new-reg": "https://foo/acme/new-reg",
+"revoke-cert": "https://foo/acme/revoke-cert"
+}`))
+	}))
+	defer ts.Close()
+	a := ACME{HTTPChallenge: &HTTPChallenge{EntryPoint: "http"}, CAServer: ts.URL}
+	a.challengeHTTPProvider = &challengeHTTPProvider{}
+
+	client, err := a.buildACMEClient(account)
+	if err != nil {
+		t.Errorf("Error in buildACMEClient: %v", err)
+	}
+	if client == nil {
+		t.Errorf("No client from buildACMEClient!")
+	}
+}
+
+func newTestLocalStore(t *testing.T) (*LocalStore, func()) {
+	dir, err := ioutil.TempDir("", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "acme.json")
+	if err := ioutil.WriteFile(file, []byte(`{"Email": "f@f"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewLocalStore(file)
+	if _, err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestChallengeHTTPProvider(t *testing.T) {
+	store, clean := newTestLocalStore(t)
+	defer clean()
+	provider := &challengeHTTPProvider{store: store}
+
+	if err := provider.Present("foo.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("Error in Present: %v", err)
+	}
+	if err := provider.Present("bar.com", "token", "otherKeyAuth"); err != nil {
+		t.Fatalf("Error in Present: %v", err)
+	}
+
+	// another node of the cluster loading the same storage
+	otherStore := NewLocalStore(store.file)
+	if _, err := otherStore.Load(); err != nil {
+		t.Fatal(err)
+	}
+	otherProvider := &challengeHTTPProvider{store: otherStore}
+	if keyAuth := otherProvider.getTokenValue("token", "foo.com"); string(keyAuth) != "keyAuth" {
+		t.Errorf("Expected key authorization %q for foo.com, got %q", "keyAuth", keyAuth)
+	}
+	if keyAuth := otherProvider.getTokenValue("token", "bar.com"); string(keyAuth) != "otherKeyAuth" {
+		t.Errorf("Expected key authorization %q for bar.com, got %q", "otherKeyAuth", keyAuth)
+	}
+
+	if err := provider.CleanUp("foo.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("Error in CleanUp: %v", err)
+	}
+	account := store.Get().(*Account)
+	if _, ok := account.HTTPChallenge["token"]["foo.com"]; ok {
+		t.Errorf("Challenge of foo.com not removed by CleanUp")
+	}
+	if err := provider.CleanUp("bar.com", "token", "otherKeyAuth"); err != nil {
+		t.Fatalf("Error in CleanUp: %v", err)
+	}
+	if len(account.HTTPChallenge) != 0 {
+		t.Errorf("Expected no challenge left after CleanUp, got %v", account.HTTPChallenge)
+	}
+}
+
+func TestServeHTTPChallenge(t *testing.T) {
+	store, clean := newTestLocalStore(t)
+	defer clean()
+	a := &ACME{
+		HTTPChallenge:         &HTTPChallenge{EntryPoint: "http"},
+		challengeHTTPProvider: &challengeHTTPProvider{store: store},
+	}
+	if err := a.challengeHTTPProvider.Present("foo.com", "token", "token.thumbprint"); err != nil {
+		t.Fatalf("Error in Present: %v", err)
+	}
+
+	router := mux.NewRouter()
+	a.AddRoutes(router)
+	// a frontend of the entrypoint matching the domain, which must not catch the challenge
+	router.Host("foo.com").Priority(len("Host:foo.com")).Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(rw, "frontend")
+	}))
+	router.SortRoutes()
+	entryPoint := httptest.NewServer(router)
+	defer entryPoint.Close()
+
+	// the validation authority requests the challenge path with the domain as Host
+	req, err := http.NewRequest(http.MethodGet, entryPoint.URL+acme.HTTP01ChallengePath("token"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "Foo.com:80"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if string(body) != "token.thumbprint" {
+		t.Errorf("Expected key authorization %q, got %q", "token.thumbprint", body)
+	}
+
+	resp, err = http.Post(entryPoint.URL+acme.HTTP01ChallengePath("token"), "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("Expected the challenge to be served only on GET requests")
+	}
+
+	// an unknown token is not waited for
+	req, err = http.NewRequest(http.MethodGet, entryPoint.URL+acme.HTTP01ChallengePath("unknown"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "foo.com"
+	start := time.Now()
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for an unknown token, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected an unknown token to be answered right away, took %s", elapsed)
+	}
+}
+
+func newTestAccount(t *testing.T) *Account {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeCert, challengeKey, err := generateKeyPair("challenge.acme.invalid", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := &Account{
+		Email: "test@traefik.io",
+		Registration: &acme.RegistrationResource{
+			Body: acme.Registration{ID: 1, Key: jose.JsonWebKey{Key: &privateKey.PublicKey}},
+			URI:  "http://boulder/acme/reg/1",
+		},
+		PrivateKey: x509.MarshalPKCS1PrivateKey(privateKey),
+		ChallengeCerts: map[string]*ChallengeCert{
+			"challenge.acme.invalid": {Certificate: challengeCert, PrivateKey: challengeKey},
+		},
+		HTTPChallenge: map[string]map[string][]byte{
+			"token": {"foo1.com": []byte("keyAuth")},
+		},
+	}
+	for _, domain := range []Domain{{Main: "foo1.com", SANs: []string{}}, {Main: "foo2.com", SANs: []string{"bar.net", "baz.net"}}} {
+		cert, key, err := generateKeyPair(domain.Main, time.Now().Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		account.DomainsCertificate.Certs = append(account.DomainsCertificate.Certs, &DomainsCertificate{
+			Domains: domain,
+			Certificate: &Certificate{
+				Domain:        domain.Main,
+				CertURL:       "http://boulder/acme/cert/" + domain.Main,
+				CertStableURL: "http://boulder/acme/cert/" + domain.Main,
+				PrivateKey:    key,
+				Certificate:   cert,
+			},
+		})
+	}
+	return account
+}
+
+func TestAccountSplitJoin(t *testing.T) {
+	account := newTestAccount(t)
+
+	values, err := account.Split()
+	if err != nil {
+		t.Fatalf("Error in Split: %v", err)
+	}
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expectedKeys := []string{"account", "certificates/foo1.com", "certificates/foo2.com,bar.net,baz.net"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Fatalf("Expected keys %v, got %v", expectedKeys, keys)
+	}
+	if strings.Contains(string(values["account"]), "foo2.com") {
+		t.Errorf("Expected the account value without the certificates, got %s", values["account"])
+	}
+
+	joined := &Account{}
+	if err := joined.Join(values); err != nil {
+		t.Fatalf("Error in Join: %v", err)
+	}
+	if err := joined.Init(); err != nil {
+		t.Fatalf("Error in Init: %v", err)
+	}
+	if joined.Email != account.Email || joined.Registration.URI != account.Registration.URI ||
+		!reflect.DeepEqual(joined.PrivateKey, account.PrivateKey) || !reflect.DeepEqual(joined.HTTPChallenge, account.HTTPChallenge) ||
+		len(joined.ChallengeCerts) != 1 {
+		t.Errorf("Expected account %+v, got %+v", account, joined)
+	}
+	if len(joined.DomainsCertificate.Certs) != 2 {
+		t.Fatalf("Expected 2 certificates, got %d", len(joined.DomainsCertificate.Certs))
+	}
+	for i, domainsCertificate := range joined.DomainsCertificate.Certs {
+		if !reflect.DeepEqual(domainsCertificate.Domains, account.DomainsCertificate.Certs[i].Domains) ||
+			!reflect.DeepEqual(domainsCertificate.Certificate, account.DomainsCertificate.Certs[i].Certificate) {
+			t.Errorf("Expected certificate %+v, got %+v", account.DomainsCertificate.Certs[i], domainsCertificate)
+		}
+	}
+
+	// a certificate removed from the account is removed by a later Join
+	delete(values, "certificates/foo1.com")
+	if err := joined.Join(values); err != nil {
+		t.Fatalf("Error in Join: %v", err)
+	}
+	if len(joined.DomainsCertificate.Certs) != 1 || joined.DomainsCertificate.Certs[0].Domains.Main != "foo2.com" {
+		t.Errorf("Expected only the certificate of foo2.com, got %+v", joined.DomainsCertificate.Certs)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	account := newTestAccount(t)
+	dir, err := ioutil.TempDir("", "acme-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Export(account, dir); err != nil {
+		t.Fatalf("Error in Export: %v", err)
+	}
+	for _, file := range []string{"account.json", "account.key", "certificates/foo1.com.crt", "certificates/foo1.com.key", "certificates/foo2.com,bar.net,baz.net.crt"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("Expected exported file %s: %v", file, err)
+		}
+		if file != "account.json" {
+			if block, _ := pem.Decode(data); block == nil {
+				t.Errorf("Expected PEM data in %s", file)
+			}
+		}
+	}
+
+	imported, err := Import(dir)
+	if err != nil {
+		t.Fatalf("Error in Import: %v", err)
+	}
+	if imported.Email != account.Email || imported.Registration.URI != account.Registration.URI || !reflect.DeepEqual(imported.PrivateKey, account.PrivateKey) {
+		t.Errorf("Expected account %+v, got %+v", account, imported)
+	}
+	if len(imported.DomainsCertificate.Certs) != 2 {
+		t.Fatalf("Expected 2 certificates, got %d", len(imported.DomainsCertificate.Certs))
+	}
+	for i, domainsCertificate := range imported.DomainsCertificate.Certs {
+		if !reflect.DeepEqual(domainsCertificate.Certificate, account.DomainsCertificate.Certs[i].Certificate) {
+			t.Errorf("Expected certificate %+v, got %+v", account.DomainsCertificate.Certs[i].Certificate, domainsCertificate.Certificate)
+		}
+	}
+	if cert, ok := imported.DomainsCertificate.getCertificateForDomain("baz.net"); !ok || cert.tlsCert == nil {
+		t.Errorf("Expected an initialized certificate for baz.net")
+	}
+}
+
+func TestImportInvalidDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acme-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := Import(dir); err == nil {
+		t.Errorf("Expected an error importing a directory without account")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "account.json"), []byte(`{"Email": "test@traefik.io"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "account.key"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(dir); err == nil {
+		t.Errorf("Expected an error importing an invalid account key")
+	}
+}
+
+func TestGeneratePrivateKey(t *testing.T) {
+	for _, keyType := range []string{"RSA2048", "EC256", "EC384"} {
+		privateKey, der, err := generatePrivateKey(keyTypes[keyType])
+		if err != nil {
+			t.Fatalf("Error generating %s key: %v", keyType, err)
+		}
+		parsed, err := parsePrivateKey(der)
+		if err != nil {
+			t.Fatalf("Error parsing %s key: %v", keyType, err)
+		}
+		if !reflect.DeepEqual(parsed, privateKey) {
+			t.Errorf("Expected the parsed %s key to be the generated one", keyType)
+		}
+		for otherKeyType, other := range keyTypes {
+			if matched := matchKeyType(pemEncode(privateKey), other); matched != (otherKeyType == keyType) {
+				t.Errorf("Expected %s key to match %s: %t, got %t", keyType, otherKeyType, otherKeyType == keyType, matched)
+			}
+		}
+	}
+	if _, _, err := generatePrivateKey(acme.RSA8192); err == nil {
+		t.Errorf("Expected an error generating an unsupported key type")
+	}
+}
+
+func TestACMEInit(t *testing.T) {
+	a := ACME{}
+	if err := a.init(); err != nil {
+		t.Fatal(err)
+	}
+	if a.KeyType != "RSA4096" || a.keyType() != acme.RSA4096 {
+		t.Errorf("Expected default key type RSA4096, got %s", a.KeyType)
+	}
+	if a.renewBefore() != 30*24*time.Hour || a.renewInterval() != 24*time.Hour {
+		t.Errorf("Expected default renewal of 720h every 24h, got %s every %s", a.renewBefore(), a.renewInterval())
+	}
+
+	a = ACME{KeyType: "EC384", RenewBefore: flaeg.Duration(72 * time.Hour), RenewInterval: flaeg.Duration(time.Hour)}
+	if err := a.init(); err != nil {
+		t.Fatal(err)
+	}
+	if a.keyType() != acme.EC384 || a.renewBefore() != 72*time.Hour || a.renewInterval() != time.Hour {
+		t.Errorf("Expected EC384 key type and renewal of 72h every 1h, got %s, %s every %s", a.keyType(), a.renewBefore(), a.renewInterval())
+	}
+
+	invalids := []ACME{
+		{KeyType: "DSA"},
+		{RenewBefore: flaeg.Duration(-time.Hour)},
+		{RenewBefore: flaeg.Duration(72 * time.Hour), RenewInterval: flaeg.Duration(72 * time.Hour)},
+		{RenewBefore: flaeg.Duration(12 * time.Hour)},
+	}
+	for _, invalid := range invalids {
+		if err := invalid.init(); err == nil {
+			t.Errorf("Expected an error for invalid configuration %+v", invalid)
+		}
+	}
+}
+
+func TestNeedRenew(t *testing.T) {
+	cert, key, err := generateKeyPair("foo1.com", time.Now().Add(10*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domainsCertificates := DomainsCertificates{
+		Certs: []*DomainsCertificate{
+			{
+				Domains:     Domain{Main: "foo1.com", SANs: []string{}},
+				Certificate: &Certificate{Domain: "foo1.com", PrivateKey: key, Certificate: cert},
+			},
+		},
+	}
+	if err := domainsCertificates.Init(); err != nil {
+		t.Fatal(err)
+	}
+	domainsCertificate := domainsCertificates.Certs[0]
+	if !domainsCertificate.needRenew(30 * 24 * time.Hour) {
+		t.Errorf("Expected certificate expiring in 10 days to be renewed 30 days before")
+	}
+	if domainsCertificate.needRenew(5 * 24 * time.Hour) {
+		t.Errorf("Expected certificate expiring in 10 days not to be renewed 5 days before")
+	}
+}
+
+func TestUpdateCertificatesMetrics(t *testing.T) {
+	expiration := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	cert, key, err := generateKeyPair("foo1.com", expiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domainsCertificates := DomainsCertificates{}
+	if _, err := domainsCertificates.addCertificateForDomains(&Certificate{PrivateKey: key, Certificate: cert}, Domain{Main: "foo1.com", SANs: []string{"bar.net"}}); err != nil {
+		t.Fatal(err)
+	}
+	certificateExpiry.WithLabelValues("removed.com", "").Set(1)
+
+	updateCertificatesMetrics(&domainsCertificates)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(certificateExpiry)
+	metricFamilies, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metricFamilies) != 1 || len(metricFamilies[0].Metric) != 1 {
+		t.Fatalf("Expected a single expiry metric, got %v", metricFamilies)
+	}
+	metric := metricFamilies[0].Metric[0]
+	labels := map[string]string{}
+	for _, label := range metric.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	if expected := map[string]string{"domain": "foo1.com", "sans": "bar.net"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, labels)
+	}
+	if metric.GetGauge().GetValue() != float64(expiration.Unix()) {
+		t.Errorf("Expected expiry %d, got %f", expiration.Unix(), metric.GetGauge().GetValue())
+	}
+}
+
+func TestCompileDomainPattern(t *testing.T) {
+	testCases := []struct {
+		pattern    string
+		matched    []string
+		notMatched []string
+	}{
+		{
+			pattern:    "*.example.com",
+			matched:    []string{"foo.example.com", "foo.bar.example.com"},
+			notMatched: []string{"example.com", "fooexample.com", "foo.example.com.evil.net"},
+		},
+		{
+			pattern:    "Foo?.Example.com",
+			matched:    []string{"foo1.example.com"},
+			notMatched: []string{"foo.example.com", "foo12.example.com"},
+		},
+		{
+			pattern:    `/^[a-z]+\.example\.com$/`,
+			matched:    []string{"foo.example.com"},
+			notMatched: []string{"foo1.example.com", "foo.bar.example.com"},
+		},
+		{
+			pattern:    `/example\.com|example\.net/`,
+			matched:    []string{"example.com", "example.net"},
+			notMatched: []string{"example.com.attacker.net", "foo.example.com", "attacker.net/example.net"},
+		},
+	}
+
+	for _, test := range testCases {
+		pattern, err := compileDomainPattern(test.pattern)
+		if err != nil {
+			t.Fatalf("Error compiling %s: %v", test.pattern, err)
+		}
+		for _, domain := range test.matched {
+			if !pattern.MatchString(domain) {
+				t.Errorf("Expected %s to match %s", test.pattern, domain)
+			}
+		}
+		for _, domain := range test.notMatched {
+			if pattern.MatchString(domain) {
+				t.Errorf("Expected %s not to match %s", test.pattern, domain)
+			}
+		}
+	}
+
+	if _, err := compileDomainPattern("/[a-z/"); err == nil {
+		t.Errorf("Expected an error compiling an invalid regular expression")
+	}
+}
+
+func TestOnDemandPolicyAllowed(t *testing.T) {
+	var asked []string
+	var askedLock sync.Mutex
+	askServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		domain := req.URL.Query().Get("domain")
+		askedLock.Lock()
+		asked = append(asked, domain)
+		askedLock.Unlock()
+		if domain == "refused.example.com" {
+			rw.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer askServer.Close()
+
+	policy := &OnDemandPolicy{
+		Domains:   []string{"*.example.com"},
+		RateLimit: &types.Rate{Period: flaeg.Duration(time.Hour), Average: 3},
+		Ask:       askServer.URL + "/ask",
+	}
+	if err := policy.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := policy.allowed("foo.other.com"); err == nil {
+		t.Errorf("Expected foo.other.com not to be allowed")
+	}
+	if err := policy.allowed("refused.example.com"); err == nil {
+		t.Errorf("Expected refused.example.com to be refused by the ask URL")
+	}
+	if err := policy.allowed("foo.example.com"); err != nil {
+		t.Errorf("Expected foo.example.com to be allowed, got %v", err)
+	}
+	askedLock.Lock()
+	if expected := []string{"refused.example.com", "foo.example.com"}; !reflect.DeepEqual(asked, expected) {
+		t.Errorf("Expected the ask URL to be queried for %v, got %v", expected, asked)
+	}
+	askedLock.Unlock()
+
+	now := time.Now()
+	policy.failed("bar.example.com", now)
+	if err := policy.allowed("bar.example.com"); err == nil {
+		t.Errorf("Expected bar.example.com to be delayed after a failure")
+	}
+	firstRetry, _ := policy.failedUntil("bar.example.com", now)
+	policy.failed("bar.example.com", firstRetry)
+	secondRetry, _ := policy.failedUntil("bar.example.com", firstRetry)
+	if secondRetry.Sub(firstRetry) <= firstRetry.Sub(now)/2 {
+		t.Errorf("Expected the delay after a second failure to grow, got %s then %s", firstRetry.Sub(now), secondRetry.Sub(firstRetry))
+	}
+	policy.succeeded("bar.example.com")
+
+	// the failures do not consume the rate limit, and the ask URL is not
+	// queried once it is reached
+	if err := policy.allowed("bar.example.com"); err != nil {
+		t.Errorf("Expected bar.example.com to be allowed after a success, got %v", err)
+	}
+	if err := policy.allowed("baz.example.com"); err == nil {
+		t.Errorf("Expected baz.example.com to be refused by the rate limit")
+	}
+	askedLock.Lock()
+	if expected := []string{"refused.example.com", "foo.example.com", "bar.example.com"}; !reflect.DeepEqual(asked, expected) {
+		t.Errorf("Expected the ask URL to be queried for %v, got %v", expected, asked)
+	}
+	askedLock.Unlock()
+}
+
+func TestOnDemandPolicyInvalid(t *testing.T) {
+	for _, policy := range []*OnDemandPolicy{
+		{Domains: []string{"/(/"}},
+		{RateLimit: &types.Rate{Average: 1}},
+		{Ask: "http://[::1"},
+	} {
+		if err := policy.init(); err == nil {
+			t.Errorf("Expected an error for invalid policy %+v", policy)
+		}
+	}
+}
+
+func TestGetCertificateOnDemandPolicy(t *testing.T) {
+	store, clean := newTestLocalStore(t)
+	defer clean()
+	a := &ACME{
+		OnDemand:       true,
+		OnDemandPolicy: &OnDemandPolicy{Domains: []string{"*.example.com"}},
+		store:          store,
+		TLSConfig:      &tls.Config{},
+	}
+	if err := a.init(); err != nil {
+		t.Fatal(err)
+	}
+	a.challengeProvider = &challengeProvider{store: a.store}
+	a.checkOnDemandDomain = func(domain string) bool { return true }
+
+	// a domain out of the policy is refused before any certificate request
+	cert, err := a.getCertificate(&tls.ClientHelloInfo{ServerName: "foo.other.com"})
+	if cert != nil || err != nil {
+		t.Errorf("Expected no certificate and no error for a domain out of the policy, got %v, %v", cert, err)
+	}
+}
@@ -0,0 +1,96 @@
+package acme
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenk/backoff"
+	"github.com/containous/traefik/cluster"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/xenolf/lego/acme"
+)
+
+var _ acme.ChallengeProviderTimeout = (*challengeHTTPProvider)(nil)
+
+// challengeHTTPMaxWait bounds the time spent looking for a challenge token
+const challengeHTTPMaxWait = 500 * time.Millisecond
+
+// challengeHTTPProvider stores the key authorizations of the HTTP-01
+// challenges in the account, so that any node of a cluster can answer them
+type challengeHTTPProvider struct {
+	store cluster.Store
+	lock  sync.RWMutex
+}
+
+func (c *challengeHTTPProvider) getTokenValue(token, domain string) []byte {
+	log.Debugf("Looking for an existing ACME HTTP challenge for token %s...", token)
+
+	var result []byte
+	operation := func() error {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		account := c.store.Get().(*Account)
+		if keyAuth, ok := account.HTTPChallenge[token][domain]; ok {
+			result = keyAuth
+			return nil
+		}
+		return fmt.Errorf("cannot find challenge for token %s", token)
+	}
+	notify := func(err error, time time.Duration) {
+		log.Debugf("Error getting challenge for token: %v, retrying in %s", err, time)
+	}
+	// the challenges started by the leader of a cluster may take a moment to be
+	// synchronized, the unknown tokens are not waited for any longer
+	ebo := backoff.NewExponentialBackOff()
+	ebo.InitialInterval = 50 * time.Millisecond
+	ebo.MaxElapsedTime = challengeHTTPMaxWait
+	err := backoff.RetryNotify(safe.OperationWithRecover(operation), ebo, notify)
+	if err != nil {
+		log.Debugf("Error getting challenge for token: %v", err)
+		return nil
+	}
+	return result
+}
+
+func (c *challengeHTTPProvider) Present(domain, token, keyAuth string) error {
+	log.Debugf("Challenge Present %s", domain)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	transaction, object, err := c.store.Begin()
+	if err != nil {
+		return err
+	}
+	account := object.(*Account)
+	if account.HTTPChallenge == nil {
+		account.HTTPChallenge = map[string]map[string][]byte{}
+	}
+	if account.HTTPChallenge[token] == nil {
+		account.HTTPChallenge[token] = map[string][]byte{}
+	}
+	account.HTTPChallenge[token][domain] = []byte(keyAuth)
+	return transaction.Commit(account)
+}
+
+func (c *challengeHTTPProvider) CleanUp(domain, token, keyAuth string) error {
+	log.Debugf("Challenge CleanUp %s", domain)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	transaction, object, err := c.store.Begin()
+	if err != nil {
+		return err
+	}
+	account := object.(*Account)
+	if _, ok := account.HTTPChallenge[token]; ok {
+		delete(account.HTTPChallenge[token], domain)
+		if len(account.HTTPChallenge[token]) == 0 {
+			delete(account.HTTPChallenge, token)
+		}
+	}
+	return transaction.Commit(account)
+}
+
+func (c *challengeHTTPProvider) Timeout() (timeout, interval time.Duration) {
+	return 60 * time.Second, 5 * time.Second
+}
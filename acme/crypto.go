@@ -3,6 +3,7 @@ package acme
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -11,11 +12,84 @@ import (
 	"crypto/x509/pkix"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
+
+	"github.com/xenolf/lego/acme"
 )
 
+const defaultKeyType = "RSA4096"
+
+// keyTypes are the key types which can be used for the account and the
+// certificates keys, by their name in the configuration
+var keyTypes = map[string]acme.KeyType{
+	"RSA2048": acme.RSA2048,
+	"RSA4096": acme.RSA4096,
+	"EC256":   acme.EC256,
+	"EC384":   acme.EC384,
+}
+
+// generatePrivateKey generates a key of the type, and returns it with its DER
+// encoding, PKCS1 for a RSA key and SEC1 for an EC one
+func generatePrivateKey(keyType acme.KeyType) (crypto.PrivateKey, []byte, error) {
+	switch keyType {
+	case acme.EC256, acme.EC384:
+		curve := elliptic.P256()
+		if keyType == acme.EC384 {
+			curve = elliptic.P384()
+		}
+		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(privateKey)
+		return privateKey, der, err
+	case acme.RSA2048, acme.RSA4096:
+		bits := 2048
+		if keyType == acme.RSA4096 {
+			bits = 4096
+		}
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKey, x509.MarshalPKCS1PrivateKey(privateKey), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported key type %s", keyType)
+}
+
+// parsePrivateKey parses a DER key, PKCS1 for a RSA key and SEC1 for an EC one
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	if privateKey, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return privateKey, nil
+	}
+	if privateKey, err := x509.ParseECPrivateKey(der); err == nil {
+		return privateKey, nil
+	}
+	return nil, errors.New("unknown private key type")
+}
+
+// matchKeyType checks if the PEM key has the key type
+func matchKeyType(keyPEM []byte, keyType acme.KeyType) bool {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return false
+	}
+	privateKey, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return false
+	}
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return (keyType == acme.RSA2048 && key.N.BitLen() == 2048) || (keyType == acme.RSA4096 && key.N.BitLen() == 4096)
+	case *ecdsa.PrivateKey:
+		return (keyType == acme.EC256 && key.Curve == elliptic.P256()) || (keyType == acme.EC384 && key.Curve == elliptic.P384())
+	}
+	return false
+}
+
 func generateDefaultCertificate() (*tls.Certificate, error) {
 	randomBytes := make([]byte, 100)
 	_, err := rand.Read(randomBytes)
@@ -0,0 +1,142 @@
+package acme
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/xenolf/lego/acme"
+)
+
+const (
+	exportAccountFile    = "account.json"
+	exportAccountKeyFile = "account.key"
+	exportCertificateDir = "certificates"
+)
+
+// exportedAccount is the account.json file written by Export, the keys and the
+// certificates being written in their own PEM files
+type exportedAccount struct {
+	Email        string
+	Registration *acme.RegistrationResource
+	Certificates []exportedCertificate
+}
+
+type exportedCertificate struct {
+	Domains       Domain
+	CertURL       string
+	CertStableURL string
+	CertFile      string
+	KeyFile       string
+}
+
+// Export writes the account in the directory, as an account.json file holding
+// its registration and the domains of its certificates, an account.key PEM
+// file, and a PEM cert/key pair for each certificate in the certificates directory
+func Export(account *Account, dir string) error {
+	if account == nil || len(account.Email) == 0 {
+		return errors.New("no ACME account to export")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, exportCertificateDir), 0700); err != nil {
+		return err
+	}
+
+	privateKey, err := parsePrivateKey(account.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, exportAccountKeyFile), pemEncode(privateKey), 0600); err != nil {
+		return err
+	}
+
+	exported := exportedAccount{
+		Email:        account.Email,
+		Registration: account.Registration,
+	}
+	for _, domainsCertificate := range account.DomainsCertificate.Certs {
+		if domainsCertificate.Certificate == nil {
+			continue
+		}
+		name := domainsCertificate.Domains.name()
+		certificate := exportedCertificate{
+			Domains:       domainsCertificate.Domains,
+			CertURL:       domainsCertificate.Certificate.CertURL,
+			CertStableURL: domainsCertificate.Certificate.CertStableURL,
+			CertFile:      filepath.Join(exportCertificateDir, name+".crt"),
+			KeyFile:       filepath.Join(exportCertificateDir, name+".key"),
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, certificate.CertFile), domainsCertificate.Certificate.Certificate, 0600); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, certificate.KeyFile), domainsCertificate.Certificate.PrivateKey, 0600); err != nil {
+			return err
+		}
+		exported.Certificates = append(exported.Certificates, certificate)
+	}
+
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, exportAccountFile), data, 0600)
+}
+
+// Import reads an account written by Export from the directory
+func Import(dir string) (*Account, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, exportAccountFile))
+	if err != nil {
+		return nil, err
+	}
+	exported := exportedAccount{}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", exportAccountFile, err)
+	}
+
+	accountKey, err := ioutil.ReadFile(filepath.Join(dir, exportAccountKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(accountKey)
+	if block == nil {
+		return nil, fmt.Errorf("invalid %s: no PEM data found", exportAccountKeyFile)
+	}
+
+	account := &Account{
+		Email:          exported.Email,
+		Registration:   exported.Registration,
+		PrivateKey:     block.Bytes,
+		ChallengeCerts: map[string]*ChallengeCert{},
+		HTTPChallenge:  map[string]map[string][]byte{},
+	}
+	for _, certificate := range exported.Certificates {
+		cert, err := ioutil.ReadFile(filepath.Join(dir, certificate.CertFile))
+		if err != nil {
+			return nil, err
+		}
+		key, err := ioutil.ReadFile(filepath.Join(dir, certificate.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		account.DomainsCertificate.Certs = append(account.DomainsCertificate.Certs, &DomainsCertificate{
+			Domains: certificate.Domains,
+			Certificate: &Certificate{
+				Domain:        certificate.Domains.Main,
+				CertURL:       certificate.CertURL,
+				CertStableURL: certificate.CertStableURL,
+				PrivateKey:    key,
+				Certificate:   cert,
+			},
+		})
+	}
+	if _, err := parsePrivateKey(account.PrivateKey); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", exportAccountKeyFile, err)
+	}
+	if err := account.Init(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
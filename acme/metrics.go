@@ -0,0 +1,68 @@
+package acme
+
+import (
+	"crypto/x509"
+	"strings"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	certificateExpiryName   = "traefik_acme_certificate_expiry_timestamp_seconds"
+	certificateFailuresName = "traefik_acme_certificate_failures_total"
+
+	issuanceOperation = "issuance"
+	renewalOperation  = "renewal"
+)
+
+var (
+	certificateExpiry = stdprometheus.NewGaugeVec(
+		stdprometheus.GaugeOpts{
+			Name: certificateExpiryName,
+			Help: "Expiration date of the ACME certificates, as a Unix timestamp, partitioned by main domain and SANs.",
+		},
+		[]string{"domain", "sans"},
+	)
+	certificateFailures = stdprometheus.NewCounterVec(
+		stdprometheus.CounterOpts{
+			Name: certificateFailuresName,
+			Help: "How many ACME certificate issuances and renewals failed, partitioned by operation.",
+		},
+		[]string{"operation"},
+	)
+)
+
+// registerMetrics registers the ACME metrics with the Prometheus registry
+// served by the web provider
+func registerMetrics() {
+	for _, collector := range []stdprometheus.Collector{certificateExpiry, certificateFailures} {
+		if err := stdprometheus.Register(collector); err != nil {
+			if _, ok := err.(stdprometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
+
+// updateCertificatesMetrics sets the expiry gauges to the certificates of the
+// account, dropping the ones of the certificates it does not have anymore
+func updateCertificatesMetrics(dc *DomainsCertificates) {
+	dc.lock.RLock()
+	defer dc.lock.RUnlock()
+	certificateExpiry.Reset()
+	for _, domainsCertificate := range dc.Certs {
+		if domainsCertificate.tlsCert == nil || len(domainsCertificate.tlsCert.Certificate) == 0 {
+			continue
+		}
+		leaf := domainsCertificate.tlsCert.Leaf
+		if leaf == nil {
+			var err error
+			leaf, err = x509.ParseCertificate(domainsCertificate.tlsCert.Certificate[0])
+			if err != nil {
+				continue
+			}
+		}
+		certificateExpiry.WithLabelValues(domainsCertificate.Domains.Main, strings.Join(domainsCertificate.Domains.SANs, ",")).
+			Set(float64(leaf.NotAfter.Unix()))
+	}
+}
@@ -0,0 +1,191 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenk/backoff"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/juju/ratelimit"
+)
+
+const (
+	onDemandAskTimeout          = 5 * time.Second
+	onDemandFailureBackOff      = time.Minute
+	onDemandFailureMaxBackOff   = 24 * time.Hour
+	onDemandFailureCleanupDelay = time.Hour
+)
+
+// OnDemandPolicy restricts the domains for which certificates are requested
+// on demand, during the TLS handshakes
+type OnDemandPolicy struct {
+	Domains   []string    `description:"Domains allowed, as globs like *.example.com or as regular expressions between slashes like /^[a-z]+\\.example\\.com$/"`
+	RateLimit *types.Rate `description:"Certificates requested on average and in burst over a period, for all the domains"`
+	Ask       string      `description:"URL queried with the domain parameter before requesting a certificate, which must answer 200"`
+
+	patterns    []*regexp.Regexp
+	bucket      *ratelimit.Bucket
+	client      *http.Client
+	lock        sync.Mutex
+	failures    map[string]*onDemandFailure
+	nextCleanup time.Time
+}
+
+// onDemandFailure holds when a certificate of a domain can be requested again
+// after a failure
+type onDemandFailure struct {
+	backOff *backoff.ExponentialBackOff
+	retryAt time.Time
+}
+
+func (p *OnDemandPolicy) init() error {
+	p.patterns = nil
+	for _, domain := range p.Domains {
+		pattern, err := compileDomainPattern(domain)
+		if err != nil {
+			return err
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	p.bucket = nil
+	if p.RateLimit != nil {
+		if p.RateLimit.Period <= 0 || p.RateLimit.Average <= 0 {
+			return errors.New("Invalid ACME on demand rate limit, period and average must be positive")
+		}
+		burst := p.RateLimit.Burst
+		if burst < p.RateLimit.Average {
+			burst = p.RateLimit.Average
+		}
+		p.bucket = ratelimit.NewBucketWithQuantum(time.Duration(p.RateLimit.Period), burst, p.RateLimit.Average)
+	}
+	if len(p.Ask) > 0 {
+		if _, err := url.Parse(p.Ask); err != nil {
+			return fmt.Errorf("Invalid ACME on demand ask URL %s: %v", p.Ask, err)
+		}
+		p.client = &http.Client{Timeout: onDemandAskTimeout}
+	}
+	p.failures = make(map[string]*onDemandFailure)
+	return nil
+}
+
+// compileDomainPattern compiles a regular expression between slashes, and a
+// glob, where * matches any characters and ? a single one, as a regular
+// expression matching the whole domain
+func compileDomainPattern(domain string) (*regexp.Regexp, error) {
+	if len(domain) > 2 && strings.HasPrefix(domain, "/") && strings.HasSuffix(domain, "/") {
+		pattern, err := regexp.Compile("^(?:" + domain[1:len(domain)-1] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ACME on demand domain pattern %s: %v", domain, err)
+		}
+		return pattern, nil
+	}
+	pattern := regexp.QuoteMeta(types.CanonicalDomain(domain))
+	pattern = strings.Replace(pattern, `\*`, ".*", -1)
+	pattern = strings.Replace(pattern, `\?`, ".", -1)
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// allowed checks the domain against the allow-list and the failures of its
+// previous requests, then takes a token of the rate limit before querying the
+// ask URL, which is not queried more often than certificates are requested
+func (p *OnDemandPolicy) allowed(domain string) error {
+	if len(p.patterns) > 0 && !p.matchDomain(domain) {
+		return fmt.Errorf("domain %s is not allowed", domain)
+	}
+	if retryAt, failed := p.failedUntil(domain, time.Now()); failed {
+		return fmt.Errorf("domain %s failed, retrying after %s", domain, retryAt.Format(time.RFC3339))
+	}
+	if p.bucket != nil && p.bucket.TakeAvailable(1) == 0 {
+		return fmt.Errorf("rate limit reached, not requesting certificate for domain %s", domain)
+	}
+	if p.client != nil {
+		if err := p.ask(domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *OnDemandPolicy) matchDomain(domain string) bool {
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// ask queries the ask URL with the domain, and returns an error unless it
+// answers 200
+func (p *OnDemandPolicy) ask(domain string) error {
+	askURL, err := url.Parse(p.Ask)
+	if err != nil {
+		return err
+	}
+	query := askURL.Query()
+	query.Set("domain", domain)
+	askURL.RawQuery = query.Encode()
+
+	resp, err := p.client.Get(askURL.String())
+	if err != nil {
+		return fmt.Errorf("error asking %s for domain %s: %v", p.Ask, domain, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("domain %s refused by %s with status %d", domain, p.Ask, resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *OnDemandPolicy) failedUntil(domain string, now time.Time) (time.Time, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if failure, ok := p.failures[domain]; ok && now.Before(failure.retryAt) {
+		return failure.retryAt, true
+	}
+	return time.Time{}, false
+}
+
+// failed records a failure of the request of the domain certificate, the next
+// one being delayed exponentially with the number of failures
+func (p *OnDemandPolicy) failed(domain string, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// the domains whose failures are forgotten are dropped once in a while
+	if now.After(p.nextCleanup) {
+		for name, failure := range p.failures {
+			if now.Sub(failure.retryAt) > onDemandFailureMaxBackOff {
+				delete(p.failures, name)
+			}
+		}
+		p.nextCleanup = now.Add(onDemandFailureCleanupDelay)
+	}
+
+	failure, ok := p.failures[domain]
+	if !ok {
+		ebo := backoff.NewExponentialBackOff()
+		ebo.InitialInterval = onDemandFailureBackOff
+		ebo.MaxInterval = onDemandFailureMaxBackOff
+		ebo.MaxElapsedTime = 0
+		ebo.Reset()
+		failure = &onDemandFailure{backOff: ebo}
+		p.failures[domain] = failure
+	}
+	failure.retryAt = now.Add(failure.backOff.NextBackOff())
+	log.Warnf("Error getting ACME certificate on demand for domain %s, retrying after %s", domain, failure.retryAt.Format(time.RFC3339))
+}
+
+// succeeded forgets the failures of the domain
+func (p *OnDemandPolicy) succeeded(domain string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.failures, domain)
+}
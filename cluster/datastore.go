@@ -1,9 +1,11 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -45,6 +47,14 @@ func (m *Metadata) unmarshall() error {
 // Listener is called when Object has been changed in KV store
 type Listener func(Object) error
 
+// SplitObject is an Object stored under several keys of the KV store rather than
+// as a single value, so that its size is not limited by the one of the values
+// The keys are relative to the data prefix of the Datastore
+type SplitObject interface {
+	Split() (map[string][]byte, error)
+	Join(values map[string][]byte) error
+}
+
 var _ Store = (*Datastore)(nil)
 
 // Datastore holds a struct synced in a KV store
@@ -54,6 +64,7 @@ type Datastore struct {
 	localLock *sync.RWMutex
 	meta      *Metadata
 	lockKey   string
+	dataKey   string
 	listener  Listener
 }
 
@@ -64,6 +75,7 @@ func NewDataStore(ctx context.Context, kvSource staert.KvSource, object Object,
 		ctx:       ctx,
 		meta:      &Metadata{object: object},
 		lockKey:   kvSource.Prefix + "/lock",
+		dataKey:   kvSource.Prefix + "/data/",
 		localLock: &sync.RWMutex{},
 		listener:  listener,
 	}
@@ -126,7 +138,7 @@ func (d *Datastore) reload() error {
 		d.localLock.Unlock()
 		return err
 	}
-	err = d.meta.unmarshall()
+	err = d.unmarshall()
 	if err != nil {
 		d.localLock.Unlock()
 		return err
@@ -204,13 +216,104 @@ func (d *Datastore) Load() (Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = d.meta.unmarshall()
+	err = d.unmarshall()
 	if err != nil {
 		return nil, err
 	}
 	return d.meta.object, nil
 }
 
+// unmarshall fills the object from its single value, as stored before it was
+// split, or from its values under the data prefix
+func (d *Datastore) unmarshall() error {
+	splitObject, ok := d.meta.object.(SplitObject)
+	if !ok || len(d.meta.Object) > 0 {
+		return d.meta.unmarshall()
+	}
+	values, err := d.listData()
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return splitObject.Join(values)
+}
+
+// listData returns the values under the data prefix, by relative key
+func (d *Datastore) listData() (map[string][]byte, error) {
+	values := map[string][]byte{}
+	if err := d.listDataRecursive(strings.Trim(d.dataKey, "/"), values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// listDataRecursive adds the values of the leaves under the key, which is
+// relative to the root of the KV store. The stores list the children as
+// absolute keys with or without a leading slash (etcd and consul), or as bare
+// names (zookeeper), which are all made relative to the root.
+func (d *Datastore) listDataRecursive(key string, values map[string][]byte) error {
+	pairs, err := d.kv.List(key)
+	if err == store.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		pair, err := d.kv.Get(key)
+		if err == store.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		values[strings.TrimPrefix(key, strings.Trim(d.dataKey, "/")+"/")] = pair.Value
+		return nil
+	}
+	for _, pair := range pairs {
+		child := strings.Trim(pair.Key, "/")
+		if !strings.HasPrefix(child, key+"/") {
+			child = key + "/" + child
+		}
+		if err := d.listDataRecursive(child, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeData stores the values of the split object under the data prefix that
+// changed, and removes the ones it does not have anymore
+func (d *Datastore) storeData(object SplitObject) error {
+	values, err := object.Split()
+	if err != nil {
+		return err
+	}
+	previousValues, err := d.listData()
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if previousValue, ok := previousValues[key]; ok && bytes.Equal(previousValue, value) {
+			continue
+		}
+		if err := d.kv.Put(d.dataKey+key, value, nil); err != nil {
+			return err
+		}
+	}
+	for key := range previousValues {
+		if _, ok := values[key]; !ok {
+			// another node may have removed it in the meantime
+			if err := d.kv.Delete(d.dataKey + key); err != nil && err != store.ErrKeyNotFound {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Get atomically a struct from the KV store
 func (d *Datastore) Get() Object {
 	d.localLock.RLock()
@@ -235,11 +338,21 @@ func (s *datastoreTransaction) Commit(object Object) error {
 		return fmt.Errorf("Transaction already used, please begin a new one")
 	}
 	s.Datastore.meta.object = object
-	err := s.Datastore.meta.Marshall()
-	if err != nil {
-		return fmt.Errorf("Marshall error: %s", err)
+	if splitObject, ok := object.(SplitObject); ok {
+		err := s.Datastore.storeData(splitObject)
+		if err != nil {
+			return fmt.Errorf("StoreData error: %s", err)
+		}
+		// the single value of the object is emptied, which migrates it once split,
+		// with no way back for the previous versions reading only that value
+		s.Datastore.meta.Object = nil
+	} else {
+		err := s.Datastore.meta.Marshall()
+		if err != nil {
+			return fmt.Errorf("Marshall error: %s", err)
+		}
 	}
-	err = s.kv.StoreConfig(s.Datastore.meta)
+	err := s.kv.StoreConfig(s.Datastore.meta)
 	if err != nil {
 		return fmt.Errorf("StoreConfig error: %s", err)
 	}
@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/containous/staert"
+	"github.com/docker/libkv/store"
+)
+
+// splitTestObject is stored under one key per value
+type splitTestObject struct {
+	Values map[string]string
+}
+
+func (o *splitTestObject) Split() (map[string][]byte, error) {
+	values := map[string][]byte{}
+	for key, value := range o.Values {
+		values[key] = []byte(value)
+	}
+	return values, nil
+}
+
+func (o *splitTestObject) Join(values map[string][]byte) error {
+	o.Values = map[string]string{}
+	for key, value := range values {
+		o.Values[key] = string(value)
+	}
+	return nil
+}
+
+// mockStore is an in-memory KV store listing the children of a key like etcd,
+// consul or zookeeper do
+type mockStore struct {
+	style  string
+	values map[string][]byte
+	// vanishing keys are removed by another node while being deleted
+	vanishing map[string]bool
+	// written are the keys put in the store
+	written []string
+}
+
+func newMockStore(style string) *mockStore {
+	return &mockStore{style: style, values: map[string][]byte{}, vanishing: map[string]bool{}}
+}
+
+func (s *mockStore) keys(prefix string) []string {
+	var keys []string
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *mockStore) Put(key string, value []byte, options *store.WriteOptions) error {
+	s.values[strings.Trim(key, "/")] = value
+	s.written = append(s.written, strings.Trim(key, "/"))
+	return nil
+}
+
+// writtenKeys returns the keys with the prefix put in the store
+func (s *mockStore) writtenKeys(prefix string) []string {
+	var keys []string
+	for _, key := range s.written {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *mockStore) Get(key string) (*store.KVPair, error) {
+	key = strings.Trim(key, "/")
+	value, ok := s.values[key]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+	return &store.KVPair{Key: key, Value: value}, nil
+}
+
+func (s *mockStore) Delete(key string) error {
+	key = strings.Trim(key, "/")
+	if _, ok := s.values[key]; !ok {
+		return store.ErrKeyNotFound
+	}
+	delete(s.values, key)
+	if s.vanishing[key] {
+		return store.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *mockStore) Exists(key string) (bool, error) {
+	_, ok := s.values[strings.Trim(key, "/")]
+	return ok, nil
+}
+
+func (s *mockStore) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
+	return make(chan *store.KVPair), nil
+}
+
+func (s *mockStore) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return nil, errors.New("WatchTree not supported")
+}
+
+func (s *mockStore) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
+	return nil, errors.New("NewLock not supported")
+}
+
+// List returns the direct children of the directory, with absolute keys with
+// a leading slash for etcd, without for consul, and bare names for zookeeper
+func (s *mockStore) List(directory string) ([]*store.KVPair, error) {
+	directory = strings.Trim(directory, "/")
+	_, exists := s.values[directory]
+	names := map[string]bool{}
+	for key := range s.values {
+		if strings.HasPrefix(key, directory+"/") {
+			names[strings.SplitN(strings.TrimPrefix(key, directory+"/"), "/", 2)[0]] = true
+			exists = true
+		}
+	}
+	if !exists {
+		return nil, store.ErrKeyNotFound
+	}
+	pairs := []*store.KVPair{}
+	for name := range names {
+		key := directory + "/" + name
+		pair := &store.KVPair{Key: key, Value: s.values[key]}
+		switch s.style {
+		case "etcd":
+			pair.Key = "/" + key
+		case "zookeeper":
+			pair.Key = name
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+func (s *mockStore) DeleteTree(directory string) error {
+	return errors.New("DeleteTree not supported")
+}
+
+func (s *mockStore) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	return false, nil, errors.New("AtomicPut not supported")
+}
+
+func (s *mockStore) AtomicDelete(key string, previous *store.KVPair) (bool, error) {
+	return false, errors.New("AtomicDelete not supported")
+}
+
+func (s *mockStore) Close() {}
+
+type mockLocker struct{}
+
+func (mockLocker) Lock(stopChan chan struct{}) (<-chan struct{}, error) {
+	return nil, nil
+}
+
+func (mockLocker) Unlock() error {
+	return nil
+}
+
+func newTestDatastore(t *testing.T, kvStore store.Store, prefix string) *Datastore {
+	datastore, err := NewDataStore(context.Background(), staert.KvSource{Store: kvStore, Prefix: prefix}, &splitTestObject{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return datastore
+}
+
+func commitTestObject(t *testing.T, datastore *Datastore, object *splitTestObject) {
+	transaction := &datastoreTransaction{Datastore: datastore, remoteLock: mockLocker{}, id: "id"}
+	if err := transaction.Commit(object); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+}
+
+func loadTestObject(t *testing.T, datastore *Datastore) *splitTestObject {
+	object, err := datastore.Load()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	return object.(*splitTestObject)
+}
+
+func TestDatastoreSplitObject(t *testing.T) {
+	tests := []struct {
+		desc   string
+		prefix string
+	}{
+		{desc: "etcd", prefix: "/traefik/acme/account"},
+		{desc: "consul", prefix: "traefik/acme/account"},
+		{desc: "zookeeper", prefix: "/traefik/acme/account"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			kvStore := newMockStore(test.desc)
+			object := &splitTestObject{Values: map[string]string{
+				"account":                  "account",
+				"certificates/foo.com":     "foo",
+				"certificates/www.foo.com": "www",
+			}}
+			commitTestObject(t, newTestDatastore(t, kvStore, test.prefix), object)
+
+			wantKeys := []string{
+				"traefik/acme/account/data/account",
+				"traefik/acme/account/data/certificates/foo.com",
+				"traefik/acme/account/data/certificates/www.foo.com",
+			}
+			if keys := kvStore.keys("traefik/acme/account/data/"); !reflect.DeepEqual(keys, wantKeys) {
+				t.Errorf("got keys %v, want %v", keys, wantKeys)
+			}
+			// another node joins the values
+			if loaded := loadTestObject(t, newTestDatastore(t, kvStore, test.prefix)); !reflect.DeepEqual(loaded, object) {
+				t.Errorf("got object %+v, want %+v", loaded, object)
+			}
+
+			// the stale keys are deleted, even when another node did it first
+			kvStore.vanishing["traefik/acme/account/data/certificates/www.foo.com"] = true
+			delete(object.Values, "certificates/foo.com")
+			delete(object.Values, "certificates/www.foo.com")
+			commitTestObject(t, newTestDatastore(t, kvStore, test.prefix), object)
+
+			wantKeys = []string{"traefik/acme/account/data/account"}
+			if keys := kvStore.keys("traefik/acme/account/data/"); !reflect.DeepEqual(keys, wantKeys) {
+				t.Errorf("got keys %v, want %v", keys, wantKeys)
+			}
+			if loaded := loadTestObject(t, newTestDatastore(t, kvStore, test.prefix)); !reflect.DeepEqual(loaded, object) {
+				t.Errorf("got object %+v, want %+v", loaded, object)
+			}
+		})
+	}
+}
+
+func TestDatastoreStoreChangedValues(t *testing.T) {
+	kvStore := newMockStore("etcd")
+	object := &splitTestObject{Values: map[string]string{
+		"account":              "account",
+		"certificates/foo.com": "foo",
+		"certificates/bar.com": "bar",
+	}}
+	commitTestObject(t, newTestDatastore(t, kvStore, "traefik"), object)
+
+	kvStore.written = nil
+	object.Values["certificates/foo.com"] = "renewed foo"
+	object.Values["certificates/baz.com"] = "baz"
+	commitTestObject(t, newTestDatastore(t, kvStore, "traefik"), object)
+
+	wantKeys := []string{"traefik/data/certificates/baz.com", "traefik/data/certificates/foo.com"}
+	if keys := kvStore.writtenKeys("traefik/data/"); !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("got written keys %v, want %v", keys, wantKeys)
+	}
+	if loaded := loadTestObject(t, newTestDatastore(t, kvStore, "traefik")); !reflect.DeepEqual(loaded, object) {
+		t.Errorf("got object %+v, want %+v", loaded, object)
+	}
+}
+
+func TestDatastoreMigrateSingleValue(t *testing.T) {
+	object := &splitTestObject{Values: map[string]string{
+		"account":              "account",
+		"certificates/foo.com": "foo",
+	}}
+	value, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the object stored as a single value before it was split
+	kvStore := newMockStore("etcd")
+	kvStore.values["traefik/object"] = []byte(base64.StdEncoding.EncodeToString(value))
+	kvStore.values["traefik/lock"] = []byte("id")
+
+	datastore := newTestDatastore(t, kvStore, "traefik")
+	loaded := loadTestObject(t, datastore)
+	if !reflect.DeepEqual(loaded, object) {
+		t.Fatalf("got object %+v, want %+v", loaded, object)
+	}
+
+	commitTestObject(t, datastore, loaded)
+	if len(kvStore.values["traefik/object"]) != 0 {
+		t.Errorf("got single value %q, want it emptied", kvStore.values["traefik/object"])
+	}
+	wantKeys := []string{"traefik/data/account", "traefik/data/certificates/foo.com"}
+	if keys := kvStore.keys("traefik/data/"); !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("got keys %v, want %v", keys, wantKeys)
+	}
+	if loaded := loadTestObject(t, newTestDatastore(t, kvStore, "traefik")); !reflect.DeepEqual(loaded, object) {
+		t.Errorf("got object %+v, want %+v", loaded, object)
+	}
+}
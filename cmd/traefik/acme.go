@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	fmtlog "log"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/staert"
+	"github.com/containous/traefik/acme"
+	"github.com/containous/traefik/cluster"
+	"github.com/containous/traefik/server"
+	"github.com/docker/libkv/store"
+)
+
+// newACMECmd builds a new ACME command, exporting the ACME account and its
+// certificates from their storage as PEM files, or importing them back
+func newACMECmd(traefikConfiguration *server.TraefikConfiguration, traefikPointersConfiguration *server.TraefikConfiguration) *flaeg.Command {
+	return &flaeg.Command{
+		Name: "acme",
+		Description: `Export the ACME account and certificates to a directory, or import them from it.
+Usage: traefik acme export|import [directory]`,
+		Config:                traefikConfiguration,
+		DefaultPointersConfig: traefikPointersConfiguration,
+		Run: func() error {
+			action, dir, err := acmeArgs(os.Args[1:], valueShorthands(traefikConfiguration))
+			if err != nil {
+				return err
+			}
+			acmeConfiguration := traefikConfiguration.GlobalConfiguration.ACME
+			if acmeConfiguration == nil {
+				return errors.New("Error using command acme, no ACME configuration defined")
+			}
+			storage := acmeConfiguration.Storage
+			if len(storage) == 0 {
+				storage = acmeConfiguration.StorageFile
+			}
+			if len(storage) == 0 {
+				return errors.New("Error using command acme, no ACME storage defined")
+			}
+			kv, err := CreateKvSource(traefikConfiguration)
+			if err != nil {
+				return err
+			}
+
+			switch action {
+			case "export":
+				var account *acme.Account
+				if kv != nil {
+					account, err = loadACMEAccount(kv.Store, storage)
+				} else {
+					account, err = loadLocalACMEAccount(storage)
+				}
+				if err != nil {
+					return err
+				}
+				if err := acme.Export(account, dir); err != nil {
+					return err
+				}
+				fmtlog.Printf("Exported ACME account %s from %s to %s\n", account.Email, storage, dir)
+			case "import":
+				account, err := acme.Import(dir)
+				if err != nil {
+					return err
+				}
+				if kv != nil {
+					err = storeACMEAccount(kv.Store, storage, account)
+				} else {
+					err = storeLocalACMEAccount(storage, account)
+				}
+				if err != nil {
+					return err
+				}
+				fmtlog.Printf("Imported ACME account %s from %s to %s\n", account.Email, dir, storage)
+			}
+			return nil
+		},
+		Metadata: map[string]string{
+			"parseAllSources": "true",
+		},
+	}
+}
+
+// acmeArgs returns the action and the directory given after the acme command.
+// The flags are skipped the way flaeg parses them: the long ones take their
+// value after an =, and the short ones taking a value, whose shorthands are
+// given, take the next argument unless it is attached to them.
+func acmeArgs(args []string, valueShorthands string) (string, string, error) {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		if strings.HasPrefix(arg, "--") {
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			if strings.IndexByte(valueShorthands, arg[j]) < 0 {
+				continue
+			}
+			if j == len(arg)-1 {
+				i++
+			}
+			break
+		}
+	}
+	// the first one is the command itself
+	if len(positional) < 2 || len(positional) > 3 {
+		return "", "", errors.New("Error using command acme, usage: traefik acme export|import [directory]")
+	}
+	action, dir := positional[1], "."
+	if len(positional) == 3 {
+		dir = positional[2]
+	}
+	if action != "export" && action != "import" {
+		return "", "", fmt.Errorf("Error using command acme, unknown action %s, expected export or import", action)
+	}
+	return action, dir, nil
+}
+
+// valueShorthands returns the shorthands of the flags of the configuration
+// which take a value, flaeg handling the booleans and the pointers as switches
+func valueShorthands(config interface{}) string {
+	typ := reflect.TypeOf(config)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var shorthands string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			shorthands += valueShorthands(reflect.New(field.Type).Interface())
+			continue
+		}
+		if short := field.Tag.Get("short"); len(short) == 1 && field.Type.Kind() != reflect.Bool && field.Type.Kind() != reflect.Ptr {
+			shorthands += short
+		}
+	}
+	return shorthands
+}
+
+func loadLocalACMEAccount(file string) (*acme.Account, error) {
+	object, err := acme.NewLocalStore(file).Load()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*acme.Account), nil
+}
+
+func storeLocalACMEAccount(file string, account *acme.Account) error {
+	transaction, _, err := acme.NewLocalStore(file).Begin()
+	if err != nil {
+		return err
+	}
+	return transaction.Commit(account)
+}
+
+func newACMEDatastore(ctx context.Context, kvStore store.Store, prefix string) (*cluster.Datastore, error) {
+	return cluster.NewDataStore(ctx, staert.KvSource{Store: kvStore, Prefix: prefix}, &acme.Account{}, nil)
+}
+
+func loadACMEAccount(kvStore store.Store, prefix string) (*acme.Account, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	datastore, err := newACMEDatastore(ctx, kvStore, prefix)
+	if err != nil {
+		return nil, err
+	}
+	object, err := datastore.Load()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*acme.Account), nil
+}
+
+// storeACMEAccount stores the account under the prefix of the KV store, in the
+// layout of the cluster mode, one key per certificate
+func storeACMEAccount(kvStore store.Store, prefix string, account *acme.Account) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	datastore, err := newACMEDatastore(ctx, kvStore, prefix)
+	if err != nil {
+		return err
+	}
+	transaction, _, err := datastore.Begin()
+	if err != nil {
+		return err
+	}
+	return transaction.Commit(account)
+}
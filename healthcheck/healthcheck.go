@@ -3,8 +3,11 @@ package healthcheck
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -24,28 +27,51 @@ func GetHealthCheck() *HealthCheck {
 	return singleton
 }
 
+const (
+	defaultTimeout   = 5 * time.Second
+	defaultThreshold = 1
+)
+
+var defaultStatusRange = [2]int{http.StatusOK, http.StatusOK}
+
 // Options are the public health check options.
 type Options struct {
 	Path     string
+	Port     int
+	Scheme   string
+	Hostname string
+	Headers  map[string]string
 	Interval time.Duration
-	LB       LoadBalancer
+	Timeout  time.Duration
+	// StatusRange holds the lowest and highest status codes considered healthy
+	StatusRange        [2]int
+	HealthyThreshold   int
+	UnhealthyThreshold int
+	LB                 LoadBalancer
+	// Transport sends the health check requests, http.DefaultTransport being used when nil
+	Transport http.RoundTripper
 }
 
 func (opt Options) String() string {
-	return fmt.Sprintf("[Path: %s Interval: %s]", opt.Path, opt.Interval)
+	return fmt.Sprintf("[Path: %s Port: %d Scheme: %s Hostname: %s Interval: %s Timeout: %s Status: %d-%d Thresholds: %d/%d]",
+		opt.Path, opt.Port, opt.Scheme, opt.Hostname, opt.Interval, opt.Timeout, opt.StatusRange[0], opt.StatusRange[1], opt.HealthyThreshold, opt.UnhealthyThreshold)
 }
 
 // BackendHealthCheck HealthCheck configuration for a backend
 type BackendHealthCheck struct {
 	Options
-	disabledURLs   []*url.URL
-	requestTimeout time.Duration
+	disabledURLs []*url.URL
+	// consecutive check results going against the current state of a server, by URL
+	failures  map[string]int
+	successes map[string]int
 }
 
 //HealthCheck struct
 type HealthCheck struct {
-	Backends map[string]*BackendHealthCheck
-	cancel   context.CancelFunc
+	Backends        map[string]*BackendHealthCheck
+	passiveBackends map[string]*PassiveHealthCheck
+	passiveMutex    sync.RWMutex
+	cancel          context.CancelFunc
 }
 
 // LoadBalancer includes functionality for load-balancing management.
@@ -63,9 +89,22 @@ func newHealthCheck() *HealthCheck {
 
 // NewBackendHealthCheck Instantiate a new BackendHealthCheck
 func NewBackendHealthCheck(options Options) *BackendHealthCheck {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.StatusRange == [2]int{} {
+		options.StatusRange = defaultStatusRange
+	}
+	if options.HealthyThreshold <= 0 {
+		options.HealthyThreshold = defaultThreshold
+	}
+	if options.UnhealthyThreshold <= 0 {
+		options.UnhealthyThreshold = defaultThreshold
+	}
 	return &BackendHealthCheck{
-		Options:        options,
-		requestTimeout: 5 * time.Second,
+		Options:   options,
+		failures:  make(map[string]int),
+		successes: make(map[string]int),
 	}
 }
 
@@ -87,6 +126,32 @@ func (hc *HealthCheck) SetBackendsConfiguration(parentCtx context.Context, backe
 	}
 }
 
+//SetPassiveBackends set the passive health checks of the backends, stopping the previous ones
+func (hc *HealthCheck) SetPassiveBackends(backends map[string]*PassiveHealthCheck) {
+	hc.passiveMutex.Lock()
+	defer hc.passiveMutex.Unlock()
+	for _, backend := range hc.passiveBackends {
+		backend.Stop()
+	}
+	hc.passiveBackends = backends
+}
+
+// EjectedServers returns the servers currently ejected by the passive health checks
+func (hc *HealthCheck) EjectedServers() []EjectedServer {
+	hc.passiveMutex.RLock()
+	defer hc.passiveMutex.RUnlock()
+	var backendNames []string
+	for backendName := range hc.passiveBackends {
+		backendNames = append(backendNames, backendName)
+	}
+	sort.Strings(backendNames)
+	var ejected []EjectedServer
+	for _, backendName := range backendNames {
+		ejected = append(ejected, hc.passiveBackends[backendName].EjectedServers()...)
+	}
+	return ejected
+}
+
 func (hc *HealthCheck) execute(ctx context.Context, backendID string, backend *BackendHealthCheck) {
 	log.Debugf("Initial healthcheck for currentBackend %s ", backendID)
 	checkBackend(backend)
@@ -108,32 +173,89 @@ func checkBackend(currentBackend *BackendHealthCheck) {
 	enabledURLs := currentBackend.LB.Servers()
 	var newDisabledURLs []*url.URL
 	for _, url := range currentBackend.disabledURLs {
-		if checkHealth(url, currentBackend) {
-			log.Debugf("HealthCheck is up [%s]: Upsert in server list", url.String())
-			currentBackend.LB.UpsertServer(url, roundrobin.Weight(1))
-		} else {
-			log.Warnf("HealthCheck is still failing [%s]", url.String())
+		key := url.String()
+		if !checkHealth(url, currentBackend) {
+			log.Warnf("HealthCheck is still failing [%s]", key)
+			delete(currentBackend.successes, key)
 			newDisabledURLs = append(newDisabledURLs, url)
+			continue
 		}
+		currentBackend.successes[key]++
+		if currentBackend.successes[key] < currentBackend.HealthyThreshold {
+			log.Debugf("HealthCheck succeeded %d/%d times [%s]", currentBackend.successes[key], currentBackend.HealthyThreshold, key)
+			newDisabledURLs = append(newDisabledURLs, url)
+			continue
+		}
+		log.Debugf("HealthCheck is up [%s]: Upsert in server list", key)
+		delete(currentBackend.successes, key)
+		currentBackend.LB.UpsertServer(url, roundrobin.Weight(1))
 	}
 	currentBackend.disabledURLs = newDisabledURLs
 
 	for _, url := range enabledURLs {
-		if !checkHealth(url, currentBackend) {
-			log.Warnf("HealthCheck has failed [%s]: Remove from server list", url.String())
-			currentBackend.LB.RemoveServer(url)
-			currentBackend.disabledURLs = append(currentBackend.disabledURLs, url)
+		key := url.String()
+		if checkHealth(url, currentBackend) {
+			delete(currentBackend.failures, key)
+			continue
 		}
+		currentBackend.failures[key]++
+		if currentBackend.failures[key] < currentBackend.UnhealthyThreshold {
+			log.Warnf("HealthCheck failed %d/%d times [%s]", currentBackend.failures[key], currentBackend.UnhealthyThreshold, key)
+			continue
+		}
+		log.Warnf("HealthCheck has failed [%s]: Remove from server list", key)
+		delete(currentBackend.failures, key)
+		currentBackend.LB.RemoveServer(url)
+		currentBackend.disabledURLs = append(currentBackend.disabledURLs, url)
 	}
 }
 
+// newRequest builds the health check request of a server, on the health check
+// port and scheme when they are set
+func (backend *BackendHealthCheck) newRequest(serverURL *url.URL) (*http.Request, error) {
+	u := *serverURL
+	if len(backend.Scheme) > 0 {
+		u.Scheme = backend.Scheme
+	}
+	if backend.Port != 0 {
+		host, _, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			host = u.Host
+		}
+		u.Host = net.JoinHostPort(host, strconv.Itoa(backend.Port))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String()+backend.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range backend.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(backend.Hostname) > 0 {
+		req.Host = backend.Hostname
+	}
+	return req, nil
+}
+
 func checkHealth(serverURL *url.URL, backend *BackendHealthCheck) bool {
+	req, err := backend.newRequest(serverURL)
+	if err != nil {
+		log.Errorf("Error creating health check request for [%s]: %s", serverURL, err)
+		return false
+	}
+	// the status range is checked against the server itself, not the target of its redirections
 	client := http.Client{
-		Timeout: backend.requestTimeout,
+		Timeout:   backend.Timeout,
+		Transport: backend.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
-	resp, err := client.Get(serverURL.String() + backend.Path)
-	if err == nil {
-		defer resp.Body.Close()
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
 	}
-	return err == nil && resp.StatusCode == 200
+	defer resp.Body.Close()
+	return resp.StatusCode >= backend.StatusRange[0] && resp.StatusCode <= backend.StatusRange[1]
 }
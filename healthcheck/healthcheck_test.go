@@ -3,9 +3,11 @@ package healthcheck
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -96,6 +98,8 @@ func TestSetBackendsConfiguration(t *testing.T) {
 		desc                   string
 		startHealthy           bool
 		healthSequence         []bool
+		healthyThreshold       int
+		unhealthyThreshold     int
 		wantNumRemovedServers  int
 		wantNumUpsertedServers int
 	}{
@@ -134,6 +138,38 @@ func TestSetBackendsConfiguration(t *testing.T) {
 			wantNumRemovedServers:  1,
 			wantNumUpsertedServers: 1,
 		},
+		{
+			desc:                   "healthy server failing less than the unhealthy threshold",
+			startHealthy:           true,
+			healthSequence:         []bool{false, false, true, false},
+			unhealthyThreshold:     3,
+			wantNumRemovedServers:  0,
+			wantNumUpsertedServers: 0,
+		},
+		{
+			desc:                   "healthy server failing as many times as the unhealthy threshold",
+			startHealthy:           true,
+			healthSequence:         []bool{false, false, false},
+			unhealthyThreshold:     3,
+			wantNumRemovedServers:  1,
+			wantNumUpsertedServers: 0,
+		},
+		{
+			desc:                   "sick server succeeding less than the healthy threshold",
+			startHealthy:           false,
+			healthSequence:         []bool{true, false, true},
+			healthyThreshold:       2,
+			wantNumRemovedServers:  0,
+			wantNumUpsertedServers: 0,
+		},
+		{
+			desc:                   "sick server succeeding as many times as the healthy threshold",
+			startHealthy:           false,
+			healthSequence:         []bool{true, true},
+			healthyThreshold:       2,
+			wantNumRemovedServers:  0,
+			wantNumUpsertedServers: 1,
+		},
 	}
 
 	for _, test := range tests {
@@ -149,9 +185,11 @@ func TestSetBackendsConfiguration(t *testing.T) {
 
 			lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}}
 			backend := NewBackendHealthCheck(Options{
-				Path:     "/path",
-				Interval: healthCheckInterval,
-				LB:       lb,
+				Path:               "/path",
+				Interval:           healthCheckInterval,
+				HealthyThreshold:   test.healthyThreshold,
+				UnhealthyThreshold: test.unhealthyThreshold,
+				LB:                 lb,
 			})
 			serverURL := MustParseURL(ts.URL)
 			if test.startHealthy {
@@ -193,6 +231,110 @@ func TestSetBackendsConfiguration(t *testing.T) {
 	}
 }
 
+func TestCheckHealthRequest(t *testing.T) {
+	var gotReq *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReq = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	tsURL := MustParseURL(ts.URL)
+	_, port, err := net.SplitHostPort(tsURL.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := NewBackendHealthCheck(Options{
+		Path:     "/health?full=true",
+		Port:     portNumber,
+		Scheme:   "http",
+		Hostname: "health.localhost",
+		Headers:  map[string]string{"X-Health": "check"},
+		LB:       &testLoadBalancer{RWMutex: &sync.RWMutex{}},
+	})
+
+	// the server is declared on another port and scheme than the health check
+	if !checkHealth(MustParseURL("https://127.0.0.1:1"), backend) {
+		t.Fatal("got unhealthy server, want healthy")
+	}
+	if gotReq.URL.String() != "/health?full=true" {
+		t.Errorf("got request URI %s, want /health?full=true", gotReq.URL)
+	}
+	if gotReq.Host != "health.localhost" {
+		t.Errorf("got host %s, want health.localhost", gotReq.Host)
+	}
+	if gotReq.Header.Get("X-Health") != "check" {
+		t.Errorf("got X-Health header %q, want %q", gotReq.Header.Get("X-Health"), "check")
+	}
+}
+
+func TestCheckHealthStatusRange(t *testing.T) {
+	tests := []struct {
+		desc        string
+		statusRange [2]int
+		status      int
+		wantHealthy bool
+	}{
+		{
+			desc:        "default status",
+			status:      http.StatusOK,
+			wantHealthy: true,
+		},
+		{
+			desc:        "default status with redirection",
+			status:      http.StatusFound,
+			wantHealthy: false,
+		},
+		{
+			desc:        "default status with server error",
+			status:      http.StatusServiceUnavailable,
+			wantHealthy: false,
+		},
+		{
+			desc:        "custom range with redirection",
+			statusRange: [2]int{200, 399},
+			status:      http.StatusFound,
+			wantHealthy: true,
+		},
+		{
+			desc:        "custom range",
+			statusRange: [2]int{200, 200},
+			status:      http.StatusNoContent,
+			wantHealthy: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the redirections lead to a healthy page, which must not be followed
+				if r.URL.Path != "/path" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.Header().Set("Location", "/healthy")
+				w.WriteHeader(test.status)
+			}))
+			defer ts.Close()
+
+			backend := NewBackendHealthCheck(Options{
+				Path:        "/path",
+				StatusRange: test.statusRange,
+				LB:          &testLoadBalancer{RWMutex: &sync.RWMutex{}},
+			})
+			if healthy := checkHealth(MustParseURL(ts.URL), backend); healthy != test.wantHealthy {
+				t.Errorf("got healthy %t, want %t", healthy, test.wantHealthy)
+			}
+		})
+	}
+}
+
 func MustParseURL(rawurl string) *url.URL {
 	u, err := url.Parse(rawurl)
 	if err != nil {
@@ -0,0 +1,237 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	defaultConsecutiveErrors  = 5
+	defaultBaseEjectionTime   = 30 * time.Second
+	defaultMaxEjectionTime    = 300 * time.Second
+	defaultMaxEjectionPercent = 50
+)
+
+// PassiveOptions are the public passive health check options.
+type PassiveOptions struct {
+	ConsecutiveErrors  int
+	BaseEjectionTime   time.Duration
+	MaxEjectionTime    time.Duration
+	MaxEjectionPercent int
+}
+
+func (opt PassiveOptions) String() string {
+	return fmt.Sprintf("[ConsecutiveErrors: %d BaseEjectionTime: %s MaxEjectionTime: %s MaxEjectionPercent: %d]",
+		opt.ConsecutiveErrors, opt.BaseEjectionTime, opt.MaxEjectionTime, opt.MaxEjectionPercent)
+}
+
+// PassiveHealthCheck ejects from the load balancers the servers failing the proxied
+// requests, the ejection time doubling with each new ejection of the same server.
+// A backend has a single PassiveHealthCheck for the load balancers of all its
+// entrypoints.
+type PassiveHealthCheck struct {
+	PassiveOptions
+
+	backend string
+	lbs     []LoadBalancer
+	servers map[string]*passiveServer
+	stopped bool
+	mutex   sync.Mutex
+}
+
+type passiveServer struct {
+	url               *url.URL
+	weight            int
+	consecutiveErrors int
+	ejections         int
+	ejected           bool
+	ejectedUntil      time.Time
+	readmittedAt      time.Time
+	readmitTimer      *time.Timer
+}
+
+// EjectedServer describes a server currently ejected by a passive health check
+type EjectedServer struct {
+	Backend   string    `json:"backend"`
+	URL       string    `json:"url"`
+	Ejections int       `json:"ejections"`
+	Until     time.Time `json:"until"`
+}
+
+// NewPassiveHealthCheck Instantiate a new PassiveHealthCheck
+func NewPassiveHealthCheck(backend string, options PassiveOptions) *PassiveHealthCheck {
+	if options.ConsecutiveErrors <= 0 {
+		options.ConsecutiveErrors = defaultConsecutiveErrors
+	}
+	if options.BaseEjectionTime <= 0 {
+		options.BaseEjectionTime = defaultBaseEjectionTime
+	}
+	if options.MaxEjectionTime <= 0 {
+		options.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	if options.MaxEjectionTime < options.BaseEjectionTime {
+		options.MaxEjectionTime = options.BaseEjectionTime
+	}
+	if options.MaxEjectionPercent <= 0 || options.MaxEjectionPercent > 100 {
+		options.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+	return &PassiveHealthCheck{
+		PassiveOptions: options,
+		backend:        backend,
+		servers:        make(map[string]*passiveServer),
+	}
+}
+
+// AddLoadBalancer registers a load balancer the servers are ejected from, before
+// the first request goes through the handler
+func (p *PassiveHealthCheck) AddLoadBalancer(lb LoadBalancer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.lbs = append(p.lbs, lb)
+}
+
+// AddServer registers a server of the load balancers, with the weight used when
+// it is readmitted, keeping its state when it is already registered
+func (p *PassiveHealthCheck) AddServer(u *url.URL, weight int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if server, ok := p.servers[serverKey(u)]; ok {
+		server.weight = weight
+		return
+	}
+	p.servers[serverKey(u)] = &passiveServer{url: u, weight: weight}
+}
+
+// Stop cancels the pending readmissions, once the load balancers are replaced
+// by a new configuration
+func (p *PassiveHealthCheck) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stopped = true
+	for _, server := range p.servers {
+		if server.readmitTimer != nil {
+			server.readmitTimer.Stop()
+		}
+	}
+}
+
+// Handler wraps the handler forwarding the requests to the servers picked by the load balancer
+func (p *PassiveHealthCheck) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &utils.ProxyWriter{W: w}
+		next.ServeHTTP(pw, r)
+		p.record(r.URL, pw.StatusCode(), time.Now())
+	})
+}
+
+// EjectedServers returns the servers currently ejected
+func (p *PassiveHealthCheck) EjectedServers() []EjectedServer {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var ejected []EjectedServer
+	for _, server := range p.servers {
+		if server.ejected {
+			ejected = append(ejected, EjectedServer{
+				Backend:   p.backend,
+				URL:       server.url.String(),
+				Ejections: server.ejections,
+				Until:     server.ejectedUntil,
+			})
+		}
+	}
+	return ejected
+}
+
+// record counts the consecutive server errors, network errors being answered
+// with a 502 or a 504 by the forwarder
+func (p *PassiveHealthCheck) record(u *url.URL, code int, now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	server, ok := p.servers[serverKey(u)]
+	if !ok || server.ejected {
+		return
+	}
+	if code < http.StatusInternalServerError {
+		server.consecutiveErrors = 0
+		return
+	}
+	server.consecutiveErrors++
+	if server.consecutiveErrors < p.ConsecutiveErrors {
+		return
+	}
+	server.consecutiveErrors = 0
+
+	ejected := 0
+	for _, s := range p.servers {
+		if s.ejected {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(p.servers)*p.MaxEjectionPercent {
+		log.Warnf("Passive health check of backend %s: not ejecting [%s], %d%% of the servers would be ejected", p.backend, server.url, (ejected+1)*100/len(p.servers))
+		return
+	}
+	p.eject(server, now)
+}
+
+func (p *PassiveHealthCheck) eject(server *passiveServer, now time.Time) {
+	// a server behaving since long enough starts again from the base ejection time
+	if !server.readmittedAt.IsZero() && now.Sub(server.readmittedAt) > p.MaxEjectionTime {
+		server.ejections = 0
+	}
+	ejectionTime := p.BaseEjectionTime
+	for i := 0; i < server.ejections && ejectionTime < p.MaxEjectionTime; i++ {
+		ejectionTime *= 2
+	}
+	if ejectionTime > p.MaxEjectionTime {
+		ejectionTime = p.MaxEjectionTime
+	}
+	server.ejections++
+
+	log.Warnf("Passive health check of backend %s: ejecting [%s] for %s", p.backend, server.url, ejectionTime)
+	removed := false
+	for _, lb := range p.lbs {
+		if err := lb.RemoveServer(server.url); err != nil {
+			log.Errorf("Error ejecting server %s: %s", server.url, err)
+			continue
+		}
+		removed = true
+	}
+	if !removed {
+		return
+	}
+	server.ejected = true
+	server.ejectedUntil = now.Add(ejectionTime)
+	server.readmitTimer = time.AfterFunc(ejectionTime, func() {
+		p.readmit(server)
+	})
+}
+
+func (p *PassiveHealthCheck) readmit(server *passiveServer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stopped {
+		return
+	}
+
+	log.Debugf("Passive health check of backend %s: readmitting [%s]", p.backend, server.url)
+	for _, lb := range p.lbs {
+		if err := lb.UpsertServer(server.url, roundrobin.Weight(server.weight)); err != nil {
+			log.Errorf("Error readmitting server %s: %s", server.url, err)
+		}
+	}
+	server.ejected = false
+	server.readmittedAt = time.Now()
+}
+
+func serverKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
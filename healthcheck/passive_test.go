@@ -0,0 +1,184 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPassiveHealthCheck(options PassiveOptions, serverURLs ...string) (*PassiveHealthCheck, *testLoadBalancer) {
+	lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}}
+	passive := NewPassiveHealthCheck("backend", options)
+	passive.AddLoadBalancer(lb)
+	for _, serverURL := range serverURLs {
+		u := MustParseURL(serverURL)
+		lb.servers = append(lb.servers, u)
+		passive.AddServer(u, 1)
+	}
+	return passive, lb
+}
+
+func TestPassiveHealthCheckEjection(t *testing.T) {
+	tests := []struct {
+		desc           string
+		codes          []int
+		wantNumEjected int
+		wantNumRemoved int
+	}{
+		{
+			desc:  "successful requests",
+			codes: []int{http.StatusOK, http.StatusNotFound, http.StatusOK},
+		},
+		{
+			desc:  "errors interrupted by a success",
+			codes: []int{http.StatusBadGateway, http.StatusInternalServerError, http.StatusOK, http.StatusGatewayTimeout},
+		},
+		{
+			desc:           "consecutive errors",
+			codes:          []int{http.StatusBadGateway, http.StatusInternalServerError, http.StatusGatewayTimeout},
+			wantNumEjected: 1,
+			wantNumRemoved: 1,
+		},
+		{
+			desc:           "errors on an ejected server",
+			codes:          []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantNumEjected: 1,
+			wantNumRemoved: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 3, BaseEjectionTime: time.Minute}, "http://10.0.0.1:80", "http://10.0.0.2:80")
+
+			u := MustParseURL("http://10.0.0.1:80/foo")
+			for _, code := range test.codes {
+				passive.record(u, code, time.Now())
+			}
+
+			if ejected := passive.EjectedServers(); len(ejected) != test.wantNumEjected {
+				t.Errorf("got %d ejected servers, want %d", len(ejected), test.wantNumEjected)
+			}
+			lb.Lock()
+			defer lb.Unlock()
+			if lb.numRemovedServers != test.wantNumRemoved {
+				t.Errorf("got %d removed servers, want %d", lb.numRemovedServers, test.wantNumRemoved)
+			}
+		})
+	}
+}
+
+func TestPassiveHealthCheckMaxEjectionPercent(t *testing.T) {
+	passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 1, BaseEjectionTime: time.Minute, MaxEjectionPercent: 50},
+		"http://10.0.0.1:80", "http://10.0.0.2:80", "http://10.0.0.3:80", "http://10.0.0.4:80")
+
+	for _, serverURL := range []string{"http://10.0.0.1:80", "http://10.0.0.2:80", "http://10.0.0.3:80"} {
+		passive.record(MustParseURL(serverURL), http.StatusBadGateway, time.Now())
+	}
+
+	if ejected := passive.EjectedServers(); len(ejected) != 2 {
+		t.Errorf("got %d ejected servers, want 2", len(ejected))
+	}
+	lb.Lock()
+	defer lb.Unlock()
+	if len(lb.servers) != 2 {
+		t.Errorf("got %d servers in the load balancer, want 2", len(lb.servers))
+	}
+}
+
+func TestPassiveHealthCheckBackoff(t *testing.T) {
+	passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 1, BaseEjectionTime: time.Minute, MaxEjectionTime: 3 * time.Minute}, "http://10.0.0.1:80")
+	server := passive.servers["http://10.0.0.1:80"]
+
+	now := time.Now()
+	for _, wantEjectionTime := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		passive.mutex.Lock()
+		passive.eject(server, now)
+		gotEjectionTime := server.ejectedUntil.Sub(now)
+		// the readmission timer is far away, readmit by hand
+		server.ejected = false
+		server.readmittedAt = now
+		lb.UpsertServer(server.url)
+		passive.mutex.Unlock()
+
+		if gotEjectionTime != wantEjectionTime {
+			t.Errorf("got ejection time %s, want %s", gotEjectionTime, wantEjectionTime)
+		}
+	}
+
+	// a server behaving for longer than the max ejection time starts again from the base ejection time
+	passive.mutex.Lock()
+	passive.eject(server, now.Add(4*time.Minute))
+	gotEjectionTime := server.ejectedUntil.Sub(now.Add(4 * time.Minute))
+	passive.mutex.Unlock()
+	if gotEjectionTime != time.Minute {
+		t.Errorf("got ejection time %s, want %s", gotEjectionTime, time.Minute)
+	}
+}
+
+func TestPassiveHealthCheckReadmission(t *testing.T) {
+	passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 1, BaseEjectionTime: healthCheckInterval}, "http://10.0.0.1:80", "http://10.0.0.2:80")
+	handler := passive.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	// the load balancer hands the request over with the URL of the picked server
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://10.0.0.1:80/foo", nil))
+	if ejected := passive.EjectedServers(); len(ejected) != 1 || ejected[0].URL != "http://10.0.0.1:80" {
+		t.Fatalf("got ejected servers %+v, want http://10.0.0.1:80", ejected)
+	}
+
+	time.Sleep(3 * healthCheckInterval)
+	if ejected := passive.EjectedServers(); len(ejected) != 0 {
+		t.Errorf("got ejected servers %+v, want none", ejected)
+	}
+	lb.Lock()
+	defer lb.Unlock()
+	if lb.numUpsertedServers != 1 {
+		t.Errorf("got %d upserted servers, want 1", lb.numUpsertedServers)
+	}
+}
+
+func TestPassiveHealthCheckSharedLoadBalancers(t *testing.T) {
+	passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 1, BaseEjectionTime: healthCheckInterval}, "http://10.0.0.1:80", "http://10.0.0.2:80")
+	// the load balancer of the backend on another entrypoint
+	otherLB := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{MustParseURL("http://10.0.0.1:80"), MustParseURL("http://10.0.0.2:80")}}
+	passive.AddLoadBalancer(otherLB)
+	passive.AddServer(MustParseURL("http://10.0.0.1:80"), 1)
+
+	passive.record(MustParseURL("http://10.0.0.1:80/foo"), http.StatusBadGateway, time.Now())
+	if ejected := passive.EjectedServers(); len(ejected) != 1 {
+		t.Fatalf("got ejected servers %+v, want one", ejected)
+	}
+
+	time.Sleep(3 * healthCheckInterval)
+	for _, lb := range []*testLoadBalancer{lb, otherLB} {
+		lb.Lock()
+		if lb.numRemovedServers != 1 || lb.numUpsertedServers != 1 {
+			t.Errorf("got %d removed and %d upserted servers, want 1 and 1", lb.numRemovedServers, lb.numUpsertedServers)
+		}
+		lb.Unlock()
+	}
+}
+
+func TestPassiveHealthCheckStop(t *testing.T) {
+	passive, lb := newTestPassiveHealthCheck(PassiveOptions{ConsecutiveErrors: 1, BaseEjectionTime: healthCheckInterval}, "http://10.0.0.1:80", "http://10.0.0.2:80")
+	passive.record(MustParseURL("http://10.0.0.1:80/foo"), http.StatusBadGateway, time.Now())
+
+	// a new configuration replaces the load balancer and its passive health check
+	hc := newHealthCheck()
+	hc.SetPassiveBackends(map[string]*PassiveHealthCheck{"backend": passive})
+	hc.SetPassiveBackends(map[string]*PassiveHealthCheck{})
+
+	time.Sleep(3 * healthCheckInterval)
+	lb.Lock()
+	defer lb.Unlock()
+	if lb.numUpsertedServers != 0 {
+		t.Errorf("got %d upserted servers in the replaced load balancer, want 0", lb.numUpsertedServers)
+	}
+}
@@ -48,8 +48,17 @@ func (s *AcmeSuite) TearDownSuite(c *check.C) {
 }
 
 func (s *AcmeSuite) TestRetrieveAcmeCertificate(c *check.C) {
+	s.retrieveAcmeCertificate(c, "fixtures/acme/acme.toml")
+}
+
+// Boulder validates the HTTP-01 challenges on the port 5002
+func (s *AcmeSuite) TestRetrieveAcmeCertificateHTTP01(c *check.C) {
+	s.retrieveAcmeCertificate(c, "fixtures/acme/acme_http01.toml")
+}
+
+func (s *AcmeSuite) retrieveAcmeCertificate(c *check.C, fixture string) {
 	boulderHost := s.composeProject.Container(c, "boulder").NetworkSettings.IPAddress
-	file := s.adaptFile(c, "fixtures/acme/acme.toml", struct{ BoulderHost string }{boulderHost})
+	file := s.adaptFile(c, fixture, struct{ BoulderHost string }{boulderHost})
 	defer os.Remove(file)
 	cmd := exec.Command(traefikBinary, "--configFile="+file)
 	err := cmd.Start()
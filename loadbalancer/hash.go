@@ -0,0 +1,144 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+// DefaultHashKey is the key used by ConsistentHash when none is given
+const DefaultHashKey = "client.ip"
+
+// replicas is the number of points each server has on the hash ring per unit
+// of weight
+const replicas = 100
+
+type ringPoint struct {
+	hash   uint32
+	server *server
+}
+
+// ConsistentHash is a load balancer forwarding the requests sharing the same
+// key to the same server, using a hash ring so that adding or removing a
+// server only remaps the keys of that server
+type ConsistentHash struct {
+	pool
+	next http.Handler
+	key  func(req *http.Request) string
+	ring []ringPoint
+}
+
+// NewConsistentHash builds a new ConsistentHash load balancer, the key being
+// client.ip, request.host, request.header.<name> or request.cookie.<name>
+func NewConsistentHash(next http.Handler, key string) (*ConsistentHash, error) {
+	extractor, err := newKeyExtractor(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistentHash{next: next, key: extractor}, nil
+}
+
+func newKeyExtractor(key string) (func(req *http.Request) string, error) {
+	switch {
+	case key == "" || key == "client.ip":
+		return forwardedheaders.ClientIP, nil
+	case key == "request.host":
+		return func(req *http.Request) string {
+			return req.Host
+		}, nil
+	case strings.HasPrefix(key, "request.header.") && len(key) > len("request.header."):
+		name := strings.TrimPrefix(key, "request.header.")
+		return func(req *http.Request) string {
+			return req.Header.Get(name)
+		}, nil
+	case strings.HasPrefix(key, "request.cookie.") && len(key) > len("request.cookie."):
+		name := strings.TrimPrefix(key, "request.cookie.")
+		return func(req *http.Request) string {
+			cookie, err := req.Cookie(name)
+			if err != nil {
+				return ""
+			}
+			return cookie.Value
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid hash key %q", key)
+}
+
+// UpsertServer adds a server to the pool and the hash ring
+func (ch *ConsistentHash) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if err := ch.pool.UpsertServer(u, options...); err != nil {
+		return err
+	}
+	ch.buildRing()
+	return nil
+}
+
+// RemoveServer removes a server from the pool and the hash ring
+func (ch *ConsistentHash) RemoveServer(u *url.URL) error {
+	if err := ch.pool.RemoveServer(u); err != nil {
+		return err
+	}
+	ch.buildRing()
+	return nil
+}
+
+func (ch *ConsistentHash) buildRing() {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	var size int
+	for _, s := range ch.servers {
+		size += s.weight * replicas
+	}
+	ring := make([]ringPoint, 0, size)
+	for _, s := range ch.servers {
+		for i := 0; i < s.weight*replicas; i++ {
+			ring = append(ring, ringPoint{hash: hash(s.url.String() + "#" + strconv.Itoa(i)), server: s})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	ch.ring = ring
+}
+
+func (ch *ConsistentHash) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	key := ch.key(req)
+	if key == "" {
+		// requests without a key still stick to a server
+		key = forwardedheaders.ClientIP(req)
+	}
+	s, err := ch.nextServer(key)
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(w, req, err)
+		return
+	}
+	forward(w, req, s, ch.next)
+}
+
+func (ch *ConsistentHash) nextServer(key string) (*server, error) {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	if len(ch.ring) == 0 {
+		return nil, errNoServers
+	}
+	h := hash(key)
+	i := sort.Search(len(ch.ring), func(i int) bool {
+		return ch.ring[i].hash >= h
+	})
+	if i == len(ch.ring) {
+		i = 0
+	}
+	return ch.ring[i].server, nil
+}
+
+func hash(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
@@ -0,0 +1,50 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+// LeastConn is a load balancer forwarding each request to the server with the
+// fewest active requests
+type LeastConn struct {
+	pool
+	next http.Handler
+	// start rotates the first server considered, spreading the requests between
+	// the servers having the same number of active requests
+	start uint64
+}
+
+// NewLeastConn builds a new LeastConn load balancer
+func NewLeastConn(next http.Handler) *LeastConn {
+	return &LeastConn{next: next}
+}
+
+func (lc *LeastConn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s, err := lc.nextServer()
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(w, req, err)
+		return
+	}
+	forward(w, req, s, lc.next)
+}
+
+func (lc *LeastConn) nextServer() (*server, error) {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+	if len(lc.servers) == 0 {
+		return nil, errNoServers
+	}
+
+	start := int(atomic.AddUint64(&lc.start, 1) % uint64(len(lc.servers)))
+	var best *server
+	for i := range lc.servers {
+		s := lc.servers[(start+i)%len(lc.servers)]
+		if best == nil || atomic.LoadInt64(&s.active) < atomic.LoadInt64(&best.active) {
+			best = s
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,111 @@
+package loadbalancer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+var errNoServers = errors.New("no servers in the pool")
+
+type server struct {
+	url *url.URL
+	// weight is the weight given when upserting the server, 1 by default
+	weight int
+	// active is the number of requests being forwarded to the server
+	active int64
+}
+
+// pool holds the servers of a load balancer with their weights, which are only
+// taken into account by ConsistentHash
+type pool struct {
+	servers []*server
+	mutex   sync.RWMutex
+}
+
+// Servers returns the URLs of the servers of the pool
+func (p *pool) Servers() []*url.URL {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	urls := make([]*url.URL, len(p.servers))
+	for i, s := range p.servers {
+		urls[i] = utils.CopyURL(s.url)
+	}
+	return urls
+}
+
+// UpsertServer adds a server to the pool, or updates its weight if it is
+// already in it
+func (p *pool) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if u == nil {
+		return errors.New("server URL can't be nil")
+	}
+	weight, err := serverWeight(u, options...)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if i := p.indexOf(u); i != -1 {
+		p.servers[i].weight = weight
+		return nil
+	}
+	p.servers = append(p.servers, &server{url: utils.CopyURL(u), weight: weight})
+	return nil
+}
+
+// serverWeight returns the weight set by the server options, which can only be
+// applied to the servers of a roundrobin load balancer
+func serverWeight(u *url.URL, options ...roundrobin.ServerOption) (int, error) {
+	rr, err := roundrobin.New(nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := rr.UpsertServer(u, options...); err != nil {
+		return 0, err
+	}
+	weight, _ := rr.ServerWeight(u)
+	return weight, nil
+}
+
+// RemoveServer removes a server from the pool
+func (p *pool) RemoveServer(u *url.URL) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	i := p.indexOf(u)
+	if i == -1 {
+		return fmt.Errorf("server %v not found", u)
+	}
+	p.servers = append(p.servers[:i], p.servers[i+1:]...)
+	return nil
+}
+
+func (p *pool) indexOf(u *url.URL) int {
+	for i, s := range p.servers {
+		if sameURL(s.url, u) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameURL(a, b *url.URL) bool {
+	return a.Path == b.Path && a.Host == b.Host && a.Scheme == b.Scheme
+}
+
+// forward hands the request over to next with the URL of the server, as the
+// oxy load balancers do, counting it as active while it is forwarded
+func forward(w http.ResponseWriter, req *http.Request, s *server, next http.Handler) {
+	atomic.AddInt64(&s.active, 1)
+	defer atomic.AddInt64(&s.active, -1)
+
+	newReq := *req
+	newReq.URL = utils.CopyURL(s.url)
+	next.ServeHTTP(w, &newReq)
+}
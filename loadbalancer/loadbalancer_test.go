@@ -0,0 +1,295 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// serverRecorder answers the requests with the host of the picked server
+var serverRecorder = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.URL.Host))
+})
+
+func mustParseURL(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func serve(handler http.Handler, req *http.Request) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestNoServers(t *testing.T) {
+	consistentHash, err := NewConsistentHash(serverRecorder, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, handler := range map[string]http.Handler{
+		"LeastConn":      NewLeastConn(serverRecorder),
+		"P2C":            NewP2C(serverRecorder),
+		"ConsistentHash": consistentHash,
+	} {
+		if code, _ := serve(handler, httptest.NewRequest("GET", "http://localhost/", nil)); code == http.StatusOK {
+			t.Errorf("%s: got status %d without servers, want an error", name, code)
+		}
+	}
+}
+
+func TestPoolUpsertRemove(t *testing.T) {
+	lb := NewLeastConn(serverRecorder)
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"))
+	if servers := lb.Servers(); len(servers) != 2 {
+		t.Fatalf("got %d servers, want 2", len(servers))
+	}
+
+	if err := lb.RemoveServer(mustParseURL("http://10.0.0.1:80")); err != nil {
+		t.Fatal(err)
+	}
+	if err := lb.RemoveServer(mustParseURL("http://10.0.0.1:80")); err == nil {
+		t.Error("removing a server twice should fail")
+	}
+	if servers := lb.Servers(); len(servers) != 1 || servers[0].Host != "10.0.0.2:80" {
+		t.Errorf("got servers %v, want http://10.0.0.2:80", servers)
+	}
+}
+
+func TestLeastConn(t *testing.T) {
+	lb := NewLeastConn(serverRecorder)
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.3:80"))
+	lb.servers[0].active = 2
+	lb.servers[2].active = 1
+
+	for i := 0; i < 5; i++ {
+		if _, host := serve(lb, httptest.NewRequest("GET", "http://localhost/", nil)); host != "10.0.0.2:80" {
+			t.Errorf("got server %s, want 10.0.0.2:80", host)
+		}
+	}
+}
+
+func TestLeastConnSpreadsIdleServers(t *testing.T) {
+	lb := NewLeastConn(serverRecorder)
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"))
+
+	hosts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		_, host := serve(lb, httptest.NewRequest("GET", "http://localhost/", nil))
+		hosts[host]++
+	}
+	if hosts["10.0.0.1:80"] != 5 || hosts["10.0.0.2:80"] != 5 {
+		t.Errorf("got requests per server %v, want 5 each", hosts)
+	}
+}
+
+func TestP2C(t *testing.T) {
+	lb := NewP2C(serverRecorder)
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"))
+	lb.servers[0].active = 1
+
+	// with two servers both are always picked, the least loaded one wins
+	for i := 0; i < 10; i++ {
+		if _, host := serve(lb, httptest.NewRequest("GET", "http://localhost/", nil)); host != "10.0.0.2:80" {
+			t.Errorf("got server %s, want 10.0.0.2:80", host)
+		}
+	}
+}
+
+func TestP2CSingleServer(t *testing.T) {
+	lb := NewP2C(serverRecorder)
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	if _, host := serve(lb, httptest.NewRequest("GET", "http://localhost/", nil)); host != "10.0.0.1:80" {
+		t.Errorf("got server %s, want 10.0.0.1:80", host)
+	}
+}
+
+func TestConsistentHashKeys(t *testing.T) {
+	tests := []struct {
+		desc     string
+		hashKey  string
+		setKey   func(req *http.Request, key string)
+		wantFail bool
+	}{
+		{
+			desc:    "client IP",
+			hashKey: "client.ip",
+			setKey: func(req *http.Request, key string) {
+				req.RemoteAddr = key + ":1234"
+			},
+		},
+		{
+			desc:    "host",
+			hashKey: "request.host",
+			setKey: func(req *http.Request, key string) {
+				req.Host = key
+			},
+		},
+		{
+			desc:    "header",
+			hashKey: "request.header.X-User",
+			setKey: func(req *http.Request, key string) {
+				req.Header.Set("X-User", key)
+			},
+		},
+		{
+			desc:    "cookie",
+			hashKey: "request.cookie.user",
+			setKey: func(req *http.Request, key string) {
+				req.AddCookie(&http.Cookie{Name: "user", Value: key})
+			},
+		},
+		{
+			desc:     "unknown key",
+			hashKey:  "request.body",
+			wantFail: true,
+		},
+		{
+			desc:     "header without name",
+			hashKey:  "request.header.",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			lb, err := NewConsistentHash(serverRecorder, test.hashKey)
+			if test.wantFail {
+				if err == nil {
+					t.Errorf("got no error for hash key %q", test.hashKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 4; i++ {
+				lb.UpsertServer(mustParseURL(fmt.Sprintf("http://10.0.0.%d:80", i)))
+			}
+
+			hosts := make(map[string]bool)
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("192.168.0.%d", i)
+				req := httptest.NewRequest("GET", "http://localhost/", nil)
+				test.setKey(req, key)
+				_, first := serve(lb, req)
+				_, second := serve(lb, req)
+				if first != second {
+					t.Fatalf("key %s went to %s then %s", key, first, second)
+				}
+				hosts[first] = true
+			}
+			if len(hosts) != 4 {
+				t.Errorf("got %d servers used for 100 keys, want 4", len(hosts))
+			}
+		})
+	}
+}
+
+func TestConsistentHashForwardedClientIP(t *testing.T) {
+	xForwarded, err := forwardedheaders.NewXForwarded(false, []string{"10.1.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the requests of a trusted front load balancer are spread by client, the
+	// requests without a key falling back to the client IP
+	for _, hashKey := range []string{"client.ip", "request.header.X-User"} {
+		lb, err := NewConsistentHash(serverRecorder, hashKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 4; i++ {
+			lb.UpsertServer(mustParseURL(fmt.Sprintf("http://10.0.0.%d:80", i)))
+		}
+
+		hosts := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			req.RemoteAddr = "10.1.0.1:1234"
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.168.0.%d", i))
+			recorder := httptest.NewRecorder()
+			xForwarded.ServeHTTP(recorder, req, lb.ServeHTTP)
+			hosts[recorder.Body.String()] = true
+		}
+		if len(hosts) != 4 {
+			t.Errorf("got %d servers used for 100 clients with hash key %s, want 4", len(hosts), hashKey)
+		}
+	}
+}
+
+func TestConsistentHashWeights(t *testing.T) {
+	lb, err := NewConsistentHash(serverRecorder, "request.header.X-User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"), roundrobin.Weight(1))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"), roundrobin.Weight(3))
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		req := httptest.NewRequest("GET", "http://localhost/", nil)
+		req.Header.Set("X-User", fmt.Sprintf("user-%d", i))
+		_, host := serve(lb, req)
+		counts[host]++
+	}
+	// the keys are shared in proportion to the weights
+	if counts["10.0.0.2:80"] < 2*counts["10.0.0.1:80"] {
+		t.Errorf("got %v keys per server, want about three times more for the server of weight 3", counts)
+	}
+}
+
+func TestConsistentHashRemapping(t *testing.T) {
+	lb, err := NewConsistentHash(serverRecorder, "request.header.X-User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		lb.UpsertServer(mustParseURL(fmt.Sprintf("http://10.0.0.%d:80", i)))
+	}
+
+	route := func() map[string]string {
+		routes := make(map[string]string)
+		for i := 0; i < 1000; i++ {
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			req.Header.Set("X-User", fmt.Sprintf("user-%d", i))
+			_, routes[req.Header.Get("X-User")] = serve(lb, req)
+		}
+		return routes
+	}
+
+	before := route()
+	if err := lb.RemoveServer(mustParseURL("http://10.0.0.4:80")); err != nil {
+		t.Fatal(err)
+	}
+	afterRemoval := route()
+	for key, host := range before {
+		if host != "10.0.0.4:80" && afterRemoval[key] != host {
+			t.Errorf("key %s moved from %s to %s when removing another server", key, host, afterRemoval[key])
+		}
+	}
+
+	lb.UpsertServer(mustParseURL("http://10.0.0.4:80"))
+	afterReadmission := route()
+	for key, host := range before {
+		if afterReadmission[key] != host {
+			t.Errorf("key %s moved from %s to %s once the server was back", key, host, afterReadmission[key])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package loadbalancer
+
+import (
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+// P2C is a load balancer picking two servers at random and forwarding each
+// request to the one with the fewest active requests (power of two choices)
+type P2C struct {
+	pool
+	next http.Handler
+}
+
+// NewP2C builds a new P2C load balancer
+func NewP2C(next http.Handler) *P2C {
+	return &P2C{next: next}
+}
+
+func (p *P2C) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s, err := p.nextServer()
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(w, req, err)
+		return
+	}
+	forward(w, req, s, p.next)
+}
+
+func (p *P2C) nextServer() (*server, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	switch len(p.servers) {
+	case 0:
+		return nil, errNoServers
+	case 1:
+		return p.servers[0], nil
+	}
+
+	i := rand.Intn(len(p.servers))
+	j := rand.Intn(len(p.servers) - 1)
+	if j >= i {
+		j++
+	}
+	first, second := p.servers[i], p.servers[j]
+	if atomic.LoadInt64(&second.active) < atomic.LoadInt64(&first.active) {
+		return second, nil
+	}
+	return first, nil
+}
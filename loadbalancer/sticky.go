@@ -0,0 +1,125 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+// ServerLister lists the servers of a load balancer
+type ServerLister interface {
+	Servers() []*url.URL
+}
+
+// StickinessOptions are the public sticky session options.
+type StickinessOptions struct {
+	CookieName string
+	Secure     bool
+	HTTPOnly   bool
+	// SameSite is the SameSite attribute of the cookie, "Lax", "Strict" or
+	// "None", the attribute being omitted when it is empty
+	SameSite string
+	// MaxAge is the lifetime of the cookie in seconds, the cookie lasting for
+	// the browser session when it is zero
+	MaxAge int
+	// HashServer stores a hash of the server URL in the cookie instead of the URL
+	HashServer bool
+}
+
+// Stickiness sends the requests of a client to the server stored in its
+// cookie, as long as the server is still in the load balancer
+type Stickiness struct {
+	StickinessOptions
+	// LB must be set before the first request goes through the handler
+	LB ServerLister
+}
+
+// NewStickiness Instantiate a new Stickiness, the cookie name defaulting to a
+// name derived from the backend
+func NewStickiness(backend string, options StickinessOptions) *Stickiness {
+	if len(options.CookieName) == 0 {
+		options.CookieName = DefaultCookieName(backend)
+	}
+	return &Stickiness{StickinessOptions: options}
+}
+
+// DefaultCookieName returns the sticky cookie name of a backend
+func DefaultCookieName(backend string) string {
+	return cookieName("_TRAEFIK_BACKEND_", backend)
+}
+
+// cookieName replaces the characters not allowed in cookie names
+func cookieName(prefix, name string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Handler forwards the requests having a valid cookie to next with the URL of
+// their server, leaving the others to the balancer
+func (s *Stickiness) Handler(balancer http.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u := s.stuckServer(r); u != nil {
+			newReq := *r
+			newReq.URL = u
+			next.ServeHTTP(w, &newReq)
+			return
+		}
+		balancer.ServeHTTP(w, r)
+	})
+}
+
+// Stick wraps the handler forwarding the requests to the servers picked by the
+// load balancer, setting the cookie when the client is not stuck to the server yet
+func (s *Stickiness) Stick(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := s.cookieValue(r.URL)
+		if cookie, err := r.Cookie(s.CookieName); err != nil || cookie.Value != value {
+			cookie := &http.Cookie{
+				Name:     s.CookieName,
+				Value:    value,
+				Path:     "/",
+				Secure:   s.Secure,
+				HttpOnly: s.HTTPOnly,
+				MaxAge:   s.MaxAge,
+			}
+			// http.Cookie has no SameSite attribute before Go 1.11
+			header := cookie.String()
+			if len(s.SameSite) > 0 {
+				header += "; SameSite=" + s.SameSite
+			}
+			w.Header().Add("Set-Cookie", header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Stickiness) stuckServer(r *http.Request) *url.URL {
+	cookie, err := r.Cookie(s.CookieName)
+	if err != nil {
+		return nil
+	}
+	for _, u := range s.LB.Servers() {
+		if s.cookieValue(u) == cookie.Value {
+			return utils.CopyURL(u)
+		}
+	}
+	return nil
+}
+
+func (s *Stickiness) cookieValue(u *url.URL) string {
+	serverURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	if !s.HashServer {
+		return serverURL
+	}
+	h := fnv.New64a()
+	h.Write([]byte(serverURL))
+	return fmt.Sprintf("%x", h.Sum64())
+}
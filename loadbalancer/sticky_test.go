@@ -0,0 +1,117 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestStickiness(options StickinessOptions) (*Stickiness, *LeastConn, http.Handler) {
+	stickiness := NewStickiness("backend/foo", options)
+	lb := NewLeastConn(stickiness.Stick(serverRecorder))
+	lb.UpsertServer(mustParseURL("http://10.0.0.1:80"))
+	lb.UpsertServer(mustParseURL("http://10.0.0.2:80"))
+	stickiness.LB = lb
+	return stickiness, lb, stickiness.Handler(lb, stickiness.Stick(serverRecorder))
+}
+
+func TestDefaultCookieName(t *testing.T) {
+	tests := map[string]string{
+		"backend-foo":    "_TRAEFIK_BACKEND_backend-foo",
+		"foo.com/bar":    "_TRAEFIK_BACKEND_foo.com_bar",
+		"backend foo=;1": "_TRAEFIK_BACKEND_backend_foo__1",
+	}
+	for backend, want := range tests {
+		if got := DefaultCookieName(backend); got != want {
+			t.Errorf("got cookie name %s for backend %q, want %s", got, backend, want)
+		}
+	}
+}
+
+func TestStickiness(t *testing.T) {
+	tests := []struct {
+		desc    string
+		options StickinessOptions
+	}{
+		{
+			desc: "default options",
+		},
+		{
+			desc: "hashed server",
+			options: StickinessOptions{
+				CookieName: "sticky",
+				HashServer: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			stickiness, _, handler := newTestStickiness(test.options)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+			cookies := recorder.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != stickiness.CookieName {
+				t.Fatalf("got cookies %v, want one %s cookie", cookies, stickiness.CookieName)
+			}
+			if test.options.HashServer == (cookies[0].Value == "http://"+recorder.Body.String()) {
+				t.Errorf("got cookie value %s for server %s", cookies[0].Value, recorder.Body.String())
+			}
+			stuckServer := recorder.Body.String()
+
+			for i := 0; i < 4; i++ {
+				req := httptest.NewRequest("GET", "http://localhost/", nil)
+				req.AddCookie(cookies[0])
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				if recorder.Body.String() != stuckServer {
+					t.Errorf("got server %s, want %s", recorder.Body.String(), stuckServer)
+				}
+				if len(recorder.Result().Cookies()) != 0 {
+					t.Errorf("got cookies %v for a stuck client, want none", recorder.Result().Cookies())
+				}
+			}
+		})
+	}
+}
+
+func TestStickinessCookieAttributes(t *testing.T) {
+	_, _, handler := newTestStickiness(StickinessOptions{
+		CookieName: "sticky",
+		Secure:     true,
+		HTTPOnly:   true,
+		SameSite:   "Strict",
+		MaxAge:     3600,
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+	headers := recorder.Result().Header["Set-Cookie"]
+	wantHeader := "sticky=http://" + recorder.Body.String() + "; Path=/; Max-Age=3600; HttpOnly; Secure; SameSite=Strict"
+	if len(headers) != 1 || headers[0] != wantHeader {
+		t.Errorf("got Set-Cookie headers %q, want %q", headers, wantHeader)
+	}
+}
+
+func TestStickinessRemovedServer(t *testing.T) {
+	_, lb, handler := newTestStickiness(StickinessOptions{CookieName: "sticky"})
+
+	req := httptest.NewRequest("GET", "http://localhost/", nil)
+	req.AddCookie(&http.Cookie{Name: "sticky", Value: "http://10.0.0.1:80"})
+	if _, host := serve(handler, req); host != "10.0.0.1:80" {
+		t.Fatalf("got server %s, want 10.0.0.1:80", host)
+	}
+
+	lb.RemoveServer(mustParseURL("http://10.0.0.1:80"))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Body.String() != "10.0.0.2:80" {
+		t.Errorf("got server %s, want 10.0.0.2:80", recorder.Body.String())
+	}
+	if cookies := recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].Value != "http://10.0.0.2:80" {
+		t.Errorf("got cookies %v, want the client stuck to http://10.0.0.2:80", cookies)
+	}
+}
@@ -0,0 +1,103 @@
+package loadbalancer
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+var errNoBackends = errors.New("no backends with a weight")
+
+type weightedBackend struct {
+	name          string
+	weight        int
+	currentWeight int
+	handler       http.Handler
+}
+
+// WeightedBackends is a handler splitting the requests between the handlers of
+// several backends according to their weights, using a smooth weighted round
+// robin, optionally keeping the clients on the same backend with a cookie
+type WeightedBackends struct {
+	cookieName string
+	backends   []*weightedBackend
+	mutex      sync.Mutex
+}
+
+// NewWeightedBackends builds a new WeightedBackends, the clients sticking to a
+// backend when cookieName is not empty
+func NewWeightedBackends(cookieName string) *WeightedBackends {
+	return &WeightedBackends{cookieName: cookieName}
+}
+
+// DefaultFrontendCookieName returns the cookie name keeping the clients of a
+// frontend on the same backend
+func DefaultFrontendCookieName(frontend string) string {
+	return cookieName("_TRAEFIK_FRONTEND_", frontend)
+}
+
+// AddBackend adds the handler of a backend, backends with a zero weight only
+// receiving the requests of the clients stuck to them
+func (wb *WeightedBackends) AddBackend(name string, weight int, handler http.Handler) {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+	wb.backends = append(wb.backends, &weightedBackend{name: name, weight: weight, handler: handler})
+}
+
+func (wb *WeightedBackends) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if backend := wb.stuckBackend(req); backend != nil {
+		backend.handler.ServeHTTP(w, req)
+		return
+	}
+
+	backend, err := wb.nextBackend()
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(w, req, err)
+		return
+	}
+	if len(wb.cookieName) > 0 {
+		http.SetCookie(w, &http.Cookie{Name: wb.cookieName, Value: backend.name, Path: "/"})
+	}
+	backend.handler.ServeHTTP(w, req)
+}
+
+func (wb *WeightedBackends) stuckBackend(req *http.Request) *weightedBackend {
+	if len(wb.cookieName) == 0 {
+		return nil
+	}
+	cookie, err := req.Cookie(wb.cookieName)
+	if err != nil {
+		return nil
+	}
+	for _, backend := range wb.backends {
+		if backend.name == cookie.Value {
+			return backend
+		}
+	}
+	return nil
+}
+
+func (wb *WeightedBackends) nextBackend() (*weightedBackend, error) {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+
+	total := 0
+	var best *weightedBackend
+	for _, backend := range wb.backends {
+		if backend.weight <= 0 {
+			continue
+		}
+		total += backend.weight
+		backend.currentWeight += backend.weight
+		if best == nil || backend.currentWeight > best.currentWeight {
+			best = backend
+		}
+	}
+	if best == nil {
+		return nil, errNoBackends
+	}
+	best.currentWeight -= total
+	return best, nil
+}
@@ -0,0 +1,77 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func backendRecorder(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	})
+}
+
+func TestWeightedBackends(t *testing.T) {
+	wb := NewWeightedBackends("")
+	wb.AddBackend("v1", 9, backendRecorder("v1"))
+	wb.AddBackend("v2", 1, backendRecorder("v2"))
+	wb.AddBackend("drained", 0, backendRecorder("drained"))
+
+	backends := make(map[string]int)
+	var sequence string
+	for i := 0; i < 20; i++ {
+		_, backend := serve(wb, httptest.NewRequest("GET", "http://localhost/", nil))
+		backends[backend]++
+		if i < 10 {
+			sequence += backend + " "
+		}
+	}
+	if backends["v1"] != 18 || backends["v2"] != 2 || backends["drained"] != 0 {
+		t.Errorf("got requests per backend %v, want 18 on v1 and 2 on v2", backends)
+	}
+	// the smooth weighted round robin interleaves the backends
+	if sequence != "v1 v1 v1 v1 v1 v2 v1 v1 v1 v1 " {
+		t.Errorf("got sequence %q", sequence)
+	}
+}
+
+func TestWeightedBackendsNoWeight(t *testing.T) {
+	wb := NewWeightedBackends("")
+	wb.AddBackend("drained", 0, backendRecorder("drained"))
+	if code, _ := serve(wb, httptest.NewRequest("GET", "http://localhost/", nil)); code == http.StatusOK {
+		t.Errorf("got status %d without weighted backends, want an error", code)
+	}
+}
+
+func TestWeightedBackendsSticky(t *testing.T) {
+	wb := NewWeightedBackends("canary")
+	wb.AddBackend("v1", 1, backendRecorder("v1"))
+	wb.AddBackend("v2", 1, backendRecorder("v2"))
+	wb.AddBackend("drained", 0, backendRecorder("drained"))
+
+	recorder := httptest.NewRecorder()
+	wb.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "canary" || cookies[0].Value != recorder.Body.String() {
+		t.Fatalf("got cookies %v for backend %s", cookies, recorder.Body.String())
+	}
+
+	for _, stuckBackend := range []string{cookies[0].Value, "drained"} {
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			req.AddCookie(&http.Cookie{Name: "canary", Value: stuckBackend})
+			if _, backend := serve(wb, req); backend != stuckBackend {
+				t.Errorf("got backend %s, want %s", backend, stuckBackend)
+			}
+		}
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost/", nil)
+	req.AddCookie(&http.Cookie{Name: "canary", Value: "unknown"})
+	recorder = httptest.NewRecorder()
+	wb.ServeHTTP(recorder, req)
+	if cookies := recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].Value == "unknown" {
+		t.Errorf("got cookies %v, want the client moved to a known backend", cookies)
+	}
+}
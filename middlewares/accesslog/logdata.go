@@ -68,6 +68,11 @@ const (
 	GzipRatio = "GzipRatio"
 	// Overhead is the map key used for the processing time overhead caused by Traefik.
 	Overhead = "Overhead"
+
+	// RequestRefererHeader is the log field used for the Referer request header (common log format only).
+	RequestRefererHeader = "request_Referer"
+	// RequestUserAgentHeader is the log field used for the User-Agent request header (common log format only).
+	RequestUserAgentHeader = "request_User-Agent"
 )
 
 // These are written out in the default case when no config is provided to specify keys of interest.
@@ -94,6 +99,22 @@ var defaultCoreKeys = [...]string{
 	RequestCount,
 }
 
+// These are the keys needed by the common log format.
+var commonLogKeys = [...]string{
+	StartLocal,
+	Duration,
+	FrontendName,
+	BackendURL,
+	ClientHost,
+	ClientUsername,
+	RequestMethod,
+	RequestPath,
+	RequestProtocol,
+	DownstreamStatus,
+	DownstreamContentSize,
+	RequestCount,
+}
+
 // This contains the set of all keys, i.e. all the default keys plus all non-default keys.
 var allCoreKeys = make(map[string]struct{})
 
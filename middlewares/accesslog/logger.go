@@ -6,8 +6,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/containous/traefik/types"
 )
 
 type key string
@@ -16,18 +23,84 @@ const (
 	// DataTableKey is the key within the request context used to
 	// store the Log Data Table
 	DataTableKey key = "LogDataTable"
+
+	// CommonFormat is the common logging format (CLF)
+	CommonFormat = "common"
+
+	// JSONFormat is the JSON logging format
+	JSONFormat = "json"
 )
 
 // LogHandler will write each request and its response to the access log.
-// It gets some information from the logInfoResponseWriter set up by previous middleware.
-// Note: Current implementation collects log data but does not have the facility to
-// write anywhere.
+// It gets some information from the captureResponseWriter set up by previous middleware.
+// When no access log configuration is given, the log data is still collected for the
+// rest of the middleware chain but nothing is written.
 type LogHandler struct {
+	logger   *logrus.Logger
+	file     *os.File
+	filePath string
+	keys     []string
+	mu       sync.Mutex
 }
 
-// NewLogHandler creates a new LogHandler
-func NewLogHandler() *LogHandler {
-	return &LogHandler{}
+// NewLogHandler creates a new LogHandler writing to the file or stdout described by config.
+func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
+	if config == nil {
+		return &LogHandler{}, nil
+	}
+
+	var formatter logrus.Formatter
+	var keys []string
+	switch config.Format {
+	case CommonFormat, "":
+		formatter = new(CommonLogFormatter)
+		keys = commonLogKeys[:]
+	case JSONFormat:
+		formatter = new(logrus.JSONFormatter)
+		keys = defaultCoreKeys[:]
+		if len(config.Fields) > 0 {
+			for _, field := range config.Fields {
+				if _, ok := allCoreKeys[field]; !ok {
+					return nil, fmt.Errorf("unknown access log field %q", field)
+				}
+			}
+			keys = config.Fields
+		}
+	default:
+		return nil, fmt.Errorf("unsupported access log format %q", config.Format)
+	}
+
+	logHandler := &LogHandler{
+		logger: &logrus.Logger{
+			Out:       os.Stdout,
+			Formatter: formatter,
+			Hooks:     make(logrus.LevelHooks),
+			Level:     logrus.InfoLevel,
+		},
+		filePath: config.FilePath,
+		keys:     keys,
+	}
+	if len(config.FilePath) > 0 {
+		file, err := openAccessLogFile(config.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		logHandler.file = file
+		logHandler.logger.Out = file
+	}
+	return logHandler, nil
+}
+
+func openAccessLogFile(filePath string) (*os.File, error) {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log path %s: %s", dir, err)
+	}
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %s", filePath, err)
+	}
+	return file, nil
 }
 
 // GetLogDataTable gets the request context object that contains logging data. This accretes
@@ -36,6 +109,14 @@ func GetLogDataTable(req *http.Request) *LogData {
 	return req.Context().Value(DataTableKey).(*LogData)
 }
 
+// WithLogDataTable returns a copy of the request with a new logging data table, which
+// is not logged. The requests sent besides the logged one, like the error page and
+// the mirrored requests, get one so that they don't alter its logging data.
+func WithLogDataTable(req *http.Request) *http.Request {
+	logDataTable := &LogData{Core: make(CoreLogData), Request: req.Header}
+	return req.WithContext(context.WithValue(req.Context(), DataTableKey, logDataTable))
+}
+
 func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
 	now := time.Now().UTC()
 	core := make(CoreLogData)
@@ -72,7 +153,8 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 	core[RequestLine] = fmt.Sprintf("%s %s %s", req.Method, urlCopyString, req.Proto)
 
 	core[ClientAddr] = req.RemoteAddr
-	core[ClientHost], core[ClientPort] = silentSplitHostPort(req.RemoteAddr)
+	_, core[ClientPort] = silentSplitHostPort(req.RemoteAddr)
+	core[ClientHost] = forwardedheaders.ClientIP(req)
 	core[ClientUsername] = usernameIfPresent(req.URL)
 
 	crw := &captureResponseWriter{rw: rw}
@@ -85,6 +167,31 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 
 // Close closes the Logger (i.e. the file etc).
 func (l *LogHandler) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
+
+// Rotate closes and reopens the log file to allow for rotation
+// by an external source (e.g. logrotate sending SIGUSR1).
+func (l *LogHandler) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	file, err := openAccessLogFile(l.filePath)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.logger.Out = file
 	return nil
 }
 
@@ -133,6 +240,25 @@ func (l *LogHandler) logTheRoundTrip(logDataTable *LogData, crr *captureRequestR
 	} else {
 		core[Overhead] = total
 	}
+
+	if l.logger == nil {
+		return
+	}
+
+	fields := logrus.Fields{}
+	for _, k := range l.keys {
+		if v, ok := core[k]; ok {
+			fields[k] = v
+		}
+	}
+	if _, ok := l.logger.Formatter.(*CommonLogFormatter); ok {
+		fields[RequestRefererHeader] = logDataTable.Request.Get("Referer")
+		fields[RequestUserAgentHeader] = logDataTable.Request.Get("User-Agent")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logger.WithFields(fields).Println()
 }
 
 //-------------------------------------------------------------------------------------------------
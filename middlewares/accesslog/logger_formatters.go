@@ -0,0 +1,62 @@
+package accesslog
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// default format for time presentation
+const commonLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CommonLogFormatter provides formatting in the Traefik common log format
+type CommonLogFormatter struct{}
+
+// Format formats the log entry in the Traefik common log format
+func (f *CommonLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := &bytes.Buffer{}
+
+	timestamp := "-"
+	if start, ok := entry.Data[StartLocal].(time.Time); ok {
+		timestamp = start.Format(commonLogTimeFormat)
+	}
+
+	elapsedMillis := int64(0)
+	if duration, ok := entry.Data[Duration].(time.Duration); ok {
+		elapsedMillis = duration.Nanoseconds() / 1000000
+	}
+
+	_, err := fmt.Fprintf(b, "%s - %s [%s] \"%s %s %s\" %v %v %s %s %v %s %s %dms\n",
+		toLog(entry.Data[ClientHost]),
+		toLog(entry.Data[ClientUsername]),
+		timestamp,
+		toLog(entry.Data[RequestMethod]),
+		toLog(entry.Data[RequestPath]),
+		toLog(entry.Data[RequestProtocol]),
+		toLog(entry.Data[DownstreamStatus]),
+		toLog(entry.Data[DownstreamContentSize]),
+		toQuotedLog(entry.Data[RequestRefererHeader]),
+		toQuotedLog(entry.Data[RequestUserAgentHeader]),
+		toLog(entry.Data[RequestCount]),
+		toQuotedLog(entry.Data[FrontendName]),
+		toQuotedLog(entry.Data[BackendURL]),
+		elapsedMillis)
+
+	return b.Bytes(), err
+}
+
+func toLog(v interface{}) interface{} {
+	if v == nil {
+		return "-"
+	}
+	return v
+}
+
+func toQuotedLog(v interface{}) string {
+	if v == nil {
+		return `"-"`
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(v))
+}
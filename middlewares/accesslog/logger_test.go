@@ -0,0 +1,257 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/containous/traefik/types"
+	shellwords "github.com/mattn/go-shellwords"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	logFileNameSuffix = "/traefik/logger/test.log"
+	helloWorld        = "Hello, World"
+	testBackendName   = "http://127.0.0.1/testBackend"
+	testFrontendName  = "testFrontend"
+	testStatus        = 123
+	testHostname      = "TestHost"
+	testUsername      = "TestUser"
+	testPath          = "testpath"
+	testPort          = 8181
+	testProto         = "HTTP/0.0"
+	testMethod        = "POST"
+	testReferer       = "testReferer"
+	testUserAgent     = "testUserAgent"
+)
+
+func TestLoggerCLF(t *testing.T) {
+	tmpDir, logFilePath := createTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	config := &types.AccessLog{FilePath: logFilePath, Format: CommonFormat}
+	doLogging(t, config)
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := shellwords.Parse(string(logData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Equal(t, 14, len(tokens), string(logData)) {
+		assert.Equal(t, testHostname, tokens[0])
+		assert.Equal(t, testUsername, tokens[2])
+		assert.Equal(t, fmt.Sprintf("%s %s %s", testMethod, testPath, testProto), tokens[5])
+		assert.Equal(t, fmt.Sprintf("%d", testStatus), tokens[6])
+		assert.Equal(t, fmt.Sprintf("%d", len(helloWorld)), tokens[7])
+		assert.Equal(t, testReferer, tokens[8])
+		assert.Equal(t, testUserAgent, tokens[9])
+		assert.Regexp(t, `^\d+$`, tokens[10])
+		assert.Equal(t, testFrontendName, tokens[11])
+		assert.Equal(t, testBackendName, tokens[12])
+		assert.Regexp(t, `^\d+ms$`, tokens[13])
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		fields       types.AccessLogFields
+		expectedKeys []string
+		absentKeys   []string
+	}{
+		{
+			desc:         "default fields",
+			expectedKeys: []string{FrontendName, BackendName, ClientHost, RequestMethod, DownstreamStatus, OriginDuration},
+			absentKeys:   []string{Overhead, RequestLine, RequestRefererHeader},
+		},
+		{
+			desc:         "selected fields",
+			fields:       types.AccessLogFields{FrontendName, Overhead},
+			expectedKeys: []string{FrontendName, Overhead},
+			absentKeys:   []string{BackendName, ClientHost, RequestMethod},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			tmpDir, logFilePath := createTempDir(t)
+			defer os.RemoveAll(tmpDir)
+
+			config := &types.AccessLog{FilePath: logFilePath, Format: JSONFormat, Fields: test.fields}
+			doLogging(t, config)
+
+			logData, err := ioutil.ReadFile(logFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jsonData := make(map[string]interface{})
+			if err := json.Unmarshal(logData, &jsonData); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, k := range test.expectedKeys {
+				assert.Contains(t, jsonData, k)
+			}
+			for _, k := range test.absentKeys {
+				assert.NotContains(t, jsonData, k)
+			}
+			assert.Equal(t, testFrontendName, jsonData[FrontendName])
+		})
+	}
+}
+
+func TestNewLogHandlerErrors(t *testing.T) {
+	_, err := NewLogHandler(&types.AccessLog{Format: "xml"})
+	assert.Error(t, err)
+
+	_, err = NewLogHandler(&types.AccessLog{Format: JSONFormat, Fields: types.AccessLogFields{"Unknown"}})
+	assert.Error(t, err)
+}
+
+func TestLoggerRotate(t *testing.T) {
+	tmpDir, logFilePath := createTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: CommonFormat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	rotatedPath := logFilePath + ".1"
+	if err := os.Rename(logFilePath, rotatedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newTestRequest()
+	logger.ServeHTTP(&logtestResponseWriter{}, req, logWriterTestHandlerFunc)
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, logData)
+
+	rotatedData, err := ioutil.ReadFile(rotatedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, rotatedData)
+}
+
+func TestLoggerResolvedClientIP(t *testing.T) {
+	tmpDir, logFilePath := createTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: CommonFormat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xForwarded, err := forwardedheaders.NewXForwarded(false, []string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := newTestRequest()
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	xForwarded.ServeHTTP(&logtestResponseWriter{}, req, func(rw http.ResponseWriter, req *http.Request) {
+		logger.ServeHTTP(rw, req, logWriterTestHandlerFunc)
+	})
+	logger.Close()
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := shellwords.Parse(string(logData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotEmpty(t, tokens, string(logData)) {
+		assert.Equal(t, "1.2.3.4", tokens[0])
+	}
+}
+
+func createTempDir(t *testing.T) (string, string) {
+	tmpDir, err := ioutil.TempDir("", "traefik_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpDir, filepath.Join(tmpDir, logFileNameSuffix)
+}
+
+func doLogging(t *testing.T, config *types.AccessLog) {
+	logger, err := NewLogHandler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	if config.FilePath != "" {
+		_, err = os.Stat(config.FilePath)
+		if err != nil {
+			t.Fatalf("logger should create %s: %s", config.FilePath, err)
+		}
+	}
+
+	logger.ServeHTTP(&logtestResponseWriter{}, newTestRequest(), logWriterTestHandlerFunc)
+}
+
+func newTestRequest() *http.Request {
+	return &http.Request{
+		Header: map[string][]string{
+			"User-Agent": {testUserAgent},
+			"Referer":    {testReferer},
+		},
+		Proto:      testProto,
+		Host:       testHostname,
+		Method:     testMethod,
+		RemoteAddr: fmt.Sprintf("%s:%d", testHostname, testPort),
+		URL: &url.URL{
+			User: url.UserPassword(testUsername, ""),
+			Path: testPath,
+		},
+	}
+}
+
+func logWriterTestHandlerFunc(rw http.ResponseWriter, r *http.Request) {
+	rw.Write([]byte(helloWorld))
+	rw.WriteHeader(testStatus)
+
+	logDataTable := GetLogDataTable(r)
+	logDataTable.Core[FrontendName] = testFrontendName
+	logDataTable.Core[BackendName] = testBackendName
+	logDataTable.Core[BackendURL] = testBackendName
+	logDataTable.Core[OriginDuration] = time.Duration(0)
+}
+
+type logtestResponseWriter struct{}
+
+func (lrw *logtestResponseWriter) Header() http.Header {
+	return map[string][]string{}
+}
+
+func (lrw *logtestResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (lrw *logtestResponseWriter) WriteHeader(s int) {
+}
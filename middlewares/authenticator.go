@@ -12,7 +12,7 @@ import (
 	"github.com/containous/traefik/types"
 )
 
-// Authenticator is a middleware that provides HTTP basic and digest authentication
+// Authenticator is a middleware that provides HTTP basic, digest and forward authentication
 type Authenticator struct {
 	handler negroni.Handler
 	users   map[string]string
@@ -61,6 +61,14 @@ func NewAuthenticator(authConfig *types.Auth) (*Authenticator, error) {
 				next.ServeHTTP(w, r)
 			}
 		})
+	} else if authConfig.Forward != nil {
+		if authConfig.Forward.Address == "" {
+			return nil, fmt.Errorf("Error creating Authenticator: forward auth address is empty")
+		}
+		httpClient := newForwardAuthClient()
+		authenticator.handler = negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			forwardAuth(authConfig.Forward, httpClient, w, r, next)
+		})
 	}
 	return &authenticator, nil
 }
@@ -0,0 +1,231 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	defaultMemBodyBytes = 1 << 20
+	// maxBufferingAttempts caps the attempts of the retry expression
+	maxBufferingAttempts = 10
+)
+
+var errBodyTooLarge = errors.New("body too large")
+
+// Buffering reads the whole request body before sending the request to the
+// backend, so that slow clients do not tie up the backend connections, and
+// buffers the responses when their size is limited or the requests may be retried
+type Buffering struct {
+	next                 http.Handler
+	maxRequestBodyBytes  int64
+	memRequestBodyBytes  int64
+	maxResponseBodyBytes int64
+	memResponseBodyBytes int64
+	retry                bufferingPredicate
+}
+
+// NewBuffering builds a new Buffering, the bodies being stored in memory up to
+// 1MB and in temporary files above when the memory sizes are not set
+func NewBuffering(next http.Handler, config *types.Buffering) (*Buffering, error) {
+	buffering := &Buffering{
+		next:                 next,
+		maxRequestBodyBytes:  config.MaxRequestBodyBytes,
+		memRequestBodyBytes:  config.MemRequestBodyBytes,
+		maxResponseBodyBytes: config.MaxResponseBodyBytes,
+		memResponseBodyBytes: config.MemResponseBodyBytes,
+	}
+	if buffering.memRequestBodyBytes <= 0 {
+		buffering.memRequestBodyBytes = defaultMemBodyBytes
+	}
+	if buffering.memResponseBodyBytes <= 0 {
+		buffering.memResponseBodyBytes = defaultMemBodyBytes
+	}
+	if len(config.RetryExpression) > 0 {
+		retry, err := parseBufferingRetry(config.RetryExpression)
+		if err != nil {
+			return nil, err
+		}
+		buffering.retry = retry
+	}
+	return buffering, nil
+}
+
+func (b *Buffering) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// the upgraded connections, like the WebSockets, must hijack the connection
+	// of the client, which a buffered response cannot offer
+	if len(req.Header.Get("Upgrade")) > 0 {
+		b.next.ServeHTTP(w, req)
+		return
+	}
+
+	if b.maxRequestBodyBytes > 0 && req.ContentLength > b.maxRequestBodyBytes {
+		log.Debugf("Request body of %d bytes larger than %d bytes: %v", req.ContentLength, b.maxRequestBodyBytes, req.URL)
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := &spillBuffer{memLimit: b.memRequestBodyBytes, maxSize: b.maxRequestBodyBytes}
+	defer body.Close()
+	if req.Body != nil {
+		if _, err := io.Copy(body, req.Body); err == errBodyTooLarge {
+			log.Debugf("Request body larger than %d bytes: %v", b.maxRequestBodyBytes, req.URL)
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			log.Debugf("Error reading request body of %v: %v", req.URL, err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if b.maxResponseBodyBytes <= 0 && b.retry == nil {
+		b.next.ServeHTTP(w, bufferedRequest(req, body))
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		response := &bufferWriter{
+			header: make(http.Header),
+			body:   &spillBuffer{memLimit: b.memResponseBodyBytes, maxSize: b.maxResponseBodyBytes},
+		}
+		b.next.ServeHTTP(response, bufferedRequest(req, body))
+		if response.err != nil {
+			response.body.Close()
+			log.Errorf("Error buffering response of %v: %v", req.URL, response.err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if b.retry != nil && attempt < maxBufferingAttempts && b.retry(&bufferingContext{request: req, attempts: attempt, responseCode: response.code}) {
+			response.body.Close()
+			log.Debugf("New attempt %d for request: %v", attempt+1, req.URL)
+			continue
+		}
+		response.writeTo(w, req)
+		response.body.Close()
+		return
+	}
+}
+
+// bufferedRequest copies the request with a body reading the buffer
+func bufferedRequest(req *http.Request, body *spillBuffer) *http.Request {
+	outReq := *req
+	outReq.Body = ioutil.NopCloser(body.Reader())
+	outReq.ContentLength = body.size
+	outReq.TransferEncoding = nil
+	if body.size == 0 {
+		outReq.Body = http.NoBody
+	}
+	return &outReq
+}
+
+// spillBuffer stores a body in memory up to memLimit bytes and in a temporary
+// file above, failing when the body is larger than maxSize bytes if it is set
+type spillBuffer struct {
+	memLimit int64
+	maxSize  int64
+	size     int64
+	mem      bytes.Buffer
+	file     *os.File
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.maxSize > 0 && b.size+int64(len(p)) > b.maxSize {
+		return 0, errBodyTooLarge
+	}
+	var n int
+	var err error
+	if b.file == nil && int64(b.mem.Len()+len(p)) <= b.memLimit {
+		n, err = b.mem.Write(p)
+	} else {
+		if b.file == nil {
+			if b.file, err = ioutil.TempFile("", "traefik-buffer-"); err != nil {
+				return 0, err
+			}
+		}
+		n, err = b.file.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// Reader returns a new reader of the whole body
+func (b *spillBuffer) Reader() io.Reader {
+	mem := bytes.NewReader(b.mem.Bytes())
+	if b.file == nil {
+		return mem
+	}
+	return io.MultiReader(mem, io.NewSectionReader(b.file, 0, b.size-int64(b.mem.Len())))
+}
+
+// Close removes the temporary file
+func (b *spillBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
+
+// bufferWriter stores a response until it is written to the client
+type bufferWriter struct {
+	header http.Header
+	code   int
+	body   *spillBuffer
+	err    error
+}
+
+func (bw *bufferWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferWriter) WriteHeader(code int) {
+	if bw.code == 0 {
+		bw.code = code
+	}
+}
+
+func (bw *bufferWriter) Write(buf []byte) (int, error) {
+	bw.WriteHeader(http.StatusOK)
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	n, err := bw.body.Write(buf)
+	if err != nil {
+		bw.err = err
+	}
+	return n, err
+}
+
+// writeTo writes the response to the client, keeping the Content-Length of the
+// backend when the response has no body
+func (bw *bufferWriter) writeTo(w http.ResponseWriter, req *http.Request) {
+	if bw.code == 0 {
+		bw.code = http.StatusOK
+	}
+	utils.CopyHeaders(w.Header(), bw.header)
+	if expectBody(req, bw.code) {
+		w.Header().Set("Content-Length", strconv.FormatInt(bw.body.size, 10))
+	}
+	w.WriteHeader(bw.code)
+	if _, err := io.Copy(w, bw.body.Reader()); err != nil {
+		log.Debugf("Error writing buffered response: %v", err)
+	}
+}
+
+// expectBody tells whether a response with the status code to the request has a body
+func expectBody(req *http.Request, code int) bool {
+	if req.Method == http.MethodHead {
+		return false
+	}
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
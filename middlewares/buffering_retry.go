@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vulcand/predicate"
+)
+
+// bufferingContext holds the values of the functions of a retry expression
+type bufferingContext struct {
+	request      *http.Request
+	attempts     int
+	responseCode int
+}
+
+type bufferingPredicate func(*bufferingContext) bool
+
+type bufferingString func(*bufferingContext) string
+
+type bufferingInt func(*bufferingContext) int
+
+// parseBufferingRetry parses a retry expression such as
+// `IsNetworkError() && Attempts() <= 2`, the available functions being
+// Attempts(), ResponseCode(), IsNetworkError() and RequestMethod()
+func parseBufferingRetry(expression string) (bufferingPredicate, error) {
+	parser, err := predicate.NewParser(predicate.Def{
+		Operators: predicate.Operators{
+			AND: bufferingAnd,
+			OR:  bufferingOr,
+			EQ:  bufferingEQ,
+			NEQ: bufferingNEQ,
+			LT:  bufferingCompare(func(a, b int) bool { return a < b }),
+			GT:  bufferingCompare(func(a, b int) bool { return a > b }),
+			LE:  bufferingCompare(func(a, b int) bool { return a <= b }),
+			GE:  bufferingCompare(func(a, b int) bool { return a >= b }),
+		},
+		Functions: map[string]interface{}{
+			"Attempts": func() bufferingInt {
+				return func(c *bufferingContext) int { return c.attempts }
+			},
+			"ResponseCode": func() bufferingInt {
+				return func(c *bufferingContext) int { return c.responseCode }
+			},
+			"RequestMethod": func() bufferingString {
+				return func(c *bufferingContext) string { return c.request.Method }
+			},
+			"IsNetworkError": func() bufferingPredicate {
+				return func(c *bufferingContext) bool {
+					return c.responseCode == http.StatusBadGateway || c.responseCode == http.StatusGatewayTimeout
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := parser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry expression %q: %v", expression, err)
+	}
+	retry, ok := out.(bufferingPredicate)
+	if !ok {
+		return nil, fmt.Errorf("invalid retry expression %q: expected a predicate, got %T", expression, out)
+	}
+	return retry, nil
+}
+
+func bufferingAnd(predicates ...bufferingPredicate) bufferingPredicate {
+	return func(c *bufferingContext) bool {
+		for _, p := range predicates {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func bufferingOr(predicates ...bufferingPredicate) bufferingPredicate {
+	return func(c *bufferingContext) bool {
+		for _, p := range predicates {
+			if p(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func bufferingEQ(m interface{}, value interface{}) (bufferingPredicate, error) {
+	switch mapper := m.(type) {
+	case bufferingString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return func(c *bufferingContext) bool { return mapper(c) == s }, nil
+	case bufferingInt:
+		return bufferingCompare(func(a, b int) bool { return a == b })(m, value)
+	}
+	return nil, fmt.Errorf("unsupported argument: %T", m)
+}
+
+func bufferingNEQ(m interface{}, value interface{}) (bufferingPredicate, error) {
+	eq, err := bufferingEQ(m, value)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *bufferingContext) bool { return !eq(c) }, nil
+}
+
+// bufferingCompare returns an operator comparing the value of an int function
+// to a constant
+func bufferingCompare(compare func(a, b int) bool) func(m interface{}, value interface{}) (bufferingPredicate, error) {
+	return func(m interface{}, value interface{}) (bufferingPredicate, error) {
+		mapper, ok := m.(bufferingInt)
+		if !ok {
+			return nil, fmt.Errorf("unsupported argument: %T", m)
+		}
+		i, ok := value.(int)
+		if !ok {
+			return nil, fmt.Errorf("expected int, got %T", value)
+		}
+		return func(c *bufferingContext) bool { return compare(mapper(c), i) }, nil
+	}
+}
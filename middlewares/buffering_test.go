@@ -0,0 +1,202 @@
+package middlewares
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferingRequest(t *testing.T) {
+	tests := []struct {
+		desc          string
+		body          string
+		chunked       bool
+		config        types.Buffering
+		wantCode      int
+		wantForwarded bool
+	}{
+		{
+			desc:          "body in memory",
+			body:          "hello",
+			config:        types.Buffering{MaxRequestBodyBytes: 10},
+			wantCode:      http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			desc:          "body spilled to disk",
+			body:          "hello world",
+			config:        types.Buffering{MemRequestBodyBytes: 4},
+			wantCode:      http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			desc:     "content length too large",
+			body:     "hello world",
+			config:   types.Buffering{MaxRequestBodyBytes: 5},
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:     "chunked body too large",
+			body:     "hello world",
+			chunked:  true,
+			config:   types.Buffering{MaxRequestBodyBytes: 5, MemRequestBodyBytes: 2},
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			forwarded := false
+			buffering, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = true
+				body, _ := ioutil.ReadAll(r.Body)
+				assert.Equal(t, int64(len(test.body)), r.ContentLength)
+				w.Write(body)
+			}), &test.config)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest("POST", "http://localhost/", strings.NewReader(test.body))
+			if test.chunked {
+				req.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+			buffering.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.wantCode, recorder.Code)
+			assert.Equal(t, test.wantForwarded, forwarded)
+			if test.wantForwarded {
+				assert.Equal(t, test.body, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestBufferingResponseTooLarge(t *testing.T) {
+	buffering, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}), &types.Buffering{MaxResponseBodyBytes: 5})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	buffering.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestBufferingRetry(t *testing.T) {
+	attempts := 0
+	buffering, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if attempts < 3 {
+			w.Header().Set("X-Failed", "true")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write(body)
+	}), &types.Buffering{RetryExpression: "IsNetworkError() && Attempts() <= 2 && RequestMethod() == `POST`"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	buffering.ServeHTTP(recorder, httptest.NewRequest("POST", "http://localhost/", strings.NewReader("replayed")))
+
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "replayed", recorder.Body.String(), "the body must be replayed on each attempt")
+	assert.Empty(t, recorder.Header().Get("X-Failed"), "the failed responses must be discarded")
+}
+
+func TestBufferingResponseWithoutBody(t *testing.T) {
+	tests := []struct {
+		desc   string
+		method string
+		code   int
+	}{
+		{
+			desc:   "HEAD request",
+			method: http.MethodHead,
+			code:   http.StatusOK,
+		},
+		{
+			desc:   "no content",
+			method: http.MethodGet,
+			code:   http.StatusNoContent,
+		},
+		{
+			desc:   "not modified",
+			method: http.MethodGet,
+			code:   http.StatusNotModified,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			buffering, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "42")
+				w.WriteHeader(test.code)
+			}), &types.Buffering{MaxResponseBodyBytes: 100})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			recorder := httptest.NewRecorder()
+			buffering.ServeHTTP(recorder, httptest.NewRequest(test.method, "http://localhost/", nil))
+
+			assert.Equal(t, test.code, recorder.Code)
+			assert.Equal(t, "42", recorder.Header().Get("Content-Length"), "the backend Content-Length must be kept")
+		})
+	}
+}
+
+// hijackRecorder is a response recorder that can be hijacked
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestBufferingUpgrade(t *testing.T) {
+	hijackable := false
+	buffering, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hijackable = w.(http.Hijacker)
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}), &types.Buffering{MaxResponseBodyBytes: 100, RetryExpression: "IsNetworkError() && Attempts() <= 2"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost/", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	recorder := hijackRecorder{httptest.NewRecorder()}
+	buffering.ServeHTTP(recorder, req)
+
+	assert.True(t, hijackable, "the upgraded connection must be hijackable")
+	assert.Equal(t, http.StatusSwitchingProtocols, recorder.Code)
+}
+
+func TestBufferingInvalidRetryExpression(t *testing.T) {
+	for _, expression := range []string{"Attempts(", "Attempts()", "ResponseCode() == `502`"} {
+		_, err := NewBuffering(http.NotFoundHandler(), &types.Buffering{RetryExpression: expression})
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// ErrorPagesHandler is a middleware that replaces the responses whose status code
+// matches one of the configured ranges by a page requested on an error backend
+type ErrorPagesHandler struct {
+	HTTPCodeRanges [][2]int
+	Query          string
+	BackendHandler http.Handler
+}
+
+// NewErrorPagesHandler builds a new ErrorPagesHandler given an error page configuration
+// and the handler of its backend
+func NewErrorPagesHandler(errorPage *types.ErrorPage, backendHandler http.Handler) (*ErrorPagesHandler, error) {
+	if len(errorPage.Status) == 0 {
+		return nil, fmt.Errorf("no status provided")
+	}
+	if backendHandler == nil {
+		return nil, fmt.Errorf("no backend handler provided")
+	}
+
+	var httpCodeRanges [][2]int
+	for _, status := range errorPage.Status {
+		httpCodeRange, err := types.ParseHTTPCodeRange(status)
+		if err != nil {
+			return nil, err
+		}
+		httpCodeRanges = append(httpCodeRanges, httpCodeRange)
+	}
+
+	query := errorPage.Query
+	if !strings.HasPrefix(query, "/") {
+		query = "/" + query
+	}
+
+	return &ErrorPagesHandler{
+		HTTPCodeRanges: httpCodeRanges,
+		Query:          query,
+		BackendHandler: backendHandler,
+	}, nil
+}
+
+func (ep *ErrorPagesHandler) matches(code int) bool {
+	for _, httpCodeRange := range ep.HTTPCodeRanges {
+		if code >= httpCodeRange[0] && code <= httpCodeRange[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (ep *ErrorPagesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	epw := &errorPagesResponseWriter{rw: w, header: make(http.Header), matches: ep.matches}
+	next.ServeHTTP(epw, r)
+
+	if !epw.intercepted {
+		return
+	}
+
+	page, err := ep.getErrorPage(r, epw.code)
+	if err != nil {
+		log.Errorf("Error getting error page for status %d: %v", epw.code, err)
+		utils.CopyHeaders(w.Header(), epw.header)
+		w.WriteHeader(epw.code)
+		w.Write(epw.body.Bytes())
+		return
+	}
+
+	if contentType := page.Header().Get("Content-Type"); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(epw.code)
+	w.Write(page.Body.Bytes())
+}
+
+// getErrorPage requests the error page for the status code on the error backend
+func (ep *ErrorPagesHandler) getErrorPage(r *http.Request, code int) (*ResponseRecorder, error) {
+	query := strings.Replace(ep.Query, "{status}", strconv.Itoa(code), -1)
+	pageReq, err := http.NewRequest(http.MethodGet, "http://"+r.Host+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the forwarder builds the outgoing URL from the request URI
+	pageReq.RequestURI = query
+	pageReq.RemoteAddr = r.RemoteAddr
+	pageReq = accesslog.WithLogDataTable(pageReq.WithContext(r.Context()))
+
+	recorder := NewRecorder()
+	recorder.responseWriter = nopResponseWriter{}
+	ep.BackendHandler.ServeHTTP(recorder, pageReq)
+	if recorder.Code < http.StatusOK || recorder.Code >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("error backend returned status %d for %s", recorder.Code, query)
+	}
+	return recorder, nil
+}
+
+// nopResponseWriter backs the recorder of error page requests and discards the
+// responses to mirrored requests, neither of which must reach the client connection
+type nopResponseWriter struct{}
+
+func (nopResponseWriter) Header() http.Header {
+	return make(http.Header)
+}
+
+func (nopResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (nopResponseWriter) WriteHeader(int) {}
+
+// errorPagesResponseWriter passes the response through to the client unless its
+// status code matches, in which case the response is kept aside
+type errorPagesResponseWriter struct {
+	rw          http.ResponseWriter
+	header      http.Header
+	matches     func(int) bool
+	code        int
+	wroteHeader bool
+	intercepted bool
+	body        bytes.Buffer
+}
+
+func (epw *errorPagesResponseWriter) Header() http.Header {
+	if epw.wroteHeader && !epw.intercepted {
+		return epw.rw.Header()
+	}
+	return epw.header
+}
+
+func (epw *errorPagesResponseWriter) Write(b []byte) (int, error) {
+	if !epw.wroteHeader {
+		epw.WriteHeader(http.StatusOK)
+	}
+	if epw.intercepted {
+		return epw.body.Write(b)
+	}
+	return epw.rw.Write(b)
+}
+
+func (epw *errorPagesResponseWriter) WriteHeader(code int) {
+	if epw.wroteHeader {
+		return
+	}
+	epw.wroteHeader = true
+	epw.code = code
+	if epw.matches(code) {
+		epw.intercepted = true
+		return
+	}
+	utils.CopyHeaders(epw.rw.Header(), epw.header)
+	epw.rw.WriteHeader(code)
+}
+
+func (epw *errorPagesResponseWriter) Flush() {
+	if !epw.wroteHeader {
+		epw.WriteHeader(http.StatusOK)
+	}
+	if epw.intercepted {
+		return
+	}
+	if f, ok := epw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (epw *errorPagesResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := epw.rw.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("not a hijacker: %T", epw.rw)
+}
+
+func (epw *errorPagesResponseWriter) CloseNotify() <-chan bool {
+	if c, ok := epw.rw.(http.CloseNotifier); ok {
+		return c.CloseNotify()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newErrorPagesTestBackend() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "custom error page %s", r.URL.Path)
+	})
+}
+
+func TestErrorPagesHandler(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		errorPage           *types.ErrorPage
+		backendCode         int
+		expectedCode        int
+		expectedBody        string
+		expectedContentType string
+	}{
+		{
+			desc:                "status not in range",
+			errorPage:           &types.ErrorPage{Status: []string{"500-599"}, Query: "/{status}.html"},
+			backendCode:         http.StatusOK,
+			expectedCode:        http.StatusOK,
+			expectedBody:        "backend",
+			expectedContentType: "text/plain",
+		},
+		{
+			desc:                "status in range",
+			errorPage:           &types.ErrorPage{Status: []string{"500-599"}, Query: "/{status}.html"},
+			backendCode:         http.StatusServiceUnavailable,
+			expectedCode:        http.StatusServiceUnavailable,
+			expectedBody:        "custom error page /503.html",
+			expectedContentType: "text/html",
+		},
+		{
+			desc:                "single status",
+			errorPage:           &types.ErrorPage{Status: []string{"500-599", "404"}, Query: "errors/{status}"},
+			backendCode:         http.StatusNotFound,
+			expectedCode:        http.StatusNotFound,
+			expectedBody:        "custom error page /errors/404",
+			expectedContentType: "text/html",
+		},
+		{
+			desc:                "error page not found on the error backend",
+			errorPage:           &types.ErrorPage{Status: []string{"500-599"}, Query: "/missing"},
+			backendCode:         http.StatusInternalServerError,
+			expectedCode:        http.StatusInternalServerError,
+			expectedBody:        "backend",
+			expectedContentType: "text/plain",
+		},
+	}
+
+	for _, test := range testCases {
+		errorPages, err := NewErrorPagesHandler(test.errorPage, newErrorPagesTestBackend())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := negroni.New(errorPages)
+		backendCode := test.backendCode
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(backendCode)
+			w.Write([]byte("backend"))
+		})
+
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, httptest.NewRequest("GET", "http://example.com/foo", nil))
+
+		assert.Equal(t, test.expectedCode, rw.Code, test.desc)
+		assert.Equal(t, test.expectedBody, rw.Body.String(), test.desc)
+		assert.Equal(t, test.expectedContentType, rw.Header().Get("Content-Type"), test.desc)
+	}
+}
+
+func TestErrorPagesHandlerAfterRetry(t *testing.T) {
+	errorPages, err := NewErrorPagesHandler(&types.ErrorPage{Status: []string{"502"}, Query: "/{status}.html"}, newErrorPagesTestBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	retry := NewRetry(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		NetErrorHandler.ServeHTTP(w, r, &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	}), types.Retry{Attempts: 3, InitialInterval: flaeg.Duration(time.Millisecond)})
+
+	n := negroni.New(errorPages)
+	n.UseHandler(retry)
+
+	rw := httptest.NewRecorder()
+	n.ServeHTTP(rw, httptest.NewRequest("GET", "http://example.com/foo", nil))
+
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, http.StatusBadGateway, rw.Code)
+	assert.Equal(t, "custom error page /502.html", rw.Body.String())
+}
+
+func TestNewErrorPagesHandlerErrors(t *testing.T) {
+	_, err := NewErrorPagesHandler(&types.ErrorPage{Query: "/"}, newErrorPagesTestBackend())
+	assert.Error(t, err)
+
+	_, err = NewErrorPagesHandler(&types.ErrorPage{Status: []string{"5xx"}, Query: "/"}, newErrorPagesTestBackend())
+	assert.Error(t, err)
+
+	_, err = NewErrorPagesHandler(&types.ErrorPage{Status: []string{"500"}, Query: "/"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/vulcand/oxy/utils"
+)
+
+// NewExtractor builds a source extractor as utils.NewExtractor does, the
+// client.ip one returning the client IP resolved from the forwarding headers
+func NewExtractor(variable string) (utils.SourceExtractor, error) {
+	if variable == "client.ip" {
+		return utils.ExtractorFunc(func(req *http.Request) (string, int64, error) {
+			return forwardedheaders.ClientIP(req), 1, nil
+		}), nil
+	}
+	return utils.NewExtractor(variable)
+}
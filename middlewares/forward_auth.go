@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	xForwardedURI    = "X-Forwarded-Uri"
+	xForwardedMethod = "X-Forwarded-Method"
+
+	forwardAuthTimeout = 30 * time.Second
+	// forwardAuthMaxBodySize caps the body of the authentication server responses
+	// returned to the client
+	forwardAuthMaxBodySize = 1 << 20
+)
+
+// newForwardAuthClient returns a client that doesn't follow the redirects of the
+// authentication server, they are returned to the client instead, and gives
+// up on a server not answering in time
+func newForwardAuthClient() *http.Client {
+	return &http.Client{
+		Timeout: forwardAuthTimeout,
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// forwardAuth sends a subrequest with the headers of the request to the authentication server.
+// The request is passed to next when the server answers with a 2XX status code,
+// otherwise the answer of the server is returned to the client.
+func forwardAuth(config *types.Forward, httpClient *http.Client, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	forwardReq, err := http.NewRequest(http.MethodGet, config.Address, nil)
+	if err != nil {
+		log.Debugf("Error creating forward auth request to %s: %s", config.Address, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// the subrequest is canceled with the request of the client
+	forwardReq = forwardReq.WithContext(r.Context())
+
+	writeForwardAuthHeaders(r, forwardReq, config.TrustForwardHeader)
+
+	forwardResponse, err := httpClient.Do(forwardReq)
+	if err != nil {
+		log.Debugf("Error calling forward auth server %s: %s", config.Address, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer forwardResponse.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(forwardResponse.Body, forwardAuthMaxBodySize))
+	if err != nil {
+		log.Debugf("Error reading forward auth response from %s: %s", config.Address, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Pass the forward response's body and selected headers if it didn't return a 2XX status code
+	if forwardResponse.StatusCode < http.StatusOK || forwardResponse.StatusCode >= http.StatusMultipleChoices {
+		log.Debugf("Forward auth server %s rejected the request with status %d", config.Address, forwardResponse.StatusCode)
+		utils.CopyHeaders(w.Header(), forwardResponse.Header)
+		utils.RemoveHeaders(w.Header(), forward.HopHeaders...)
+		w.Header().Del(forward.ContentLength)
+		w.WriteHeader(forwardResponse.StatusCode)
+		w.Write(body)
+		return
+	}
+
+	for _, headerName := range config.AuthResponseHeaders {
+		if value := forwardResponse.Header.Get(headerName); value != "" {
+			r.Header.Set(headerName, value)
+		} else {
+			r.Header.Del(headerName)
+		}
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func writeForwardAuthHeaders(req *http.Request, forwardReq *http.Request, trustForwardHeader bool) {
+	utils.CopyHeaders(forwardReq.Header, req.Header)
+	utils.RemoveHeaders(forwardReq.Header, forward.HopHeaders...)
+
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if trustForwardHeader {
+			if prior, ok := req.Header[forward.XForwardedFor]; ok {
+				clientIP = strings.Join(prior, ", ") + ", " + clientIP
+			}
+		}
+		forwardReq.Header.Set(forward.XForwardedFor, clientIP)
+	}
+
+	setForwardAuthHeader(forwardReq, req, forward.XForwardedProto, forwardedProto(req), trustForwardHeader)
+	setForwardAuthHeader(forwardReq, req, forward.XForwardedHost, req.Host, trustForwardHeader)
+	setForwardAuthHeader(forwardReq, req, xForwardedURI, req.URL.RequestURI(), trustForwardHeader)
+	setForwardAuthHeader(forwardReq, req, xForwardedMethod, req.Method, trustForwardHeader)
+}
+
+// setForwardAuthHeader sets the header to value unless it is already set on a trusted request
+func setForwardAuthHeader(forwardReq *http.Request, req *http.Request, header string, value string, trustForwardHeader bool) {
+	if trustForwardHeader && req.Header.Get(header) != "" {
+		forwardReq.Header.Set(header, req.Header.Get(header))
+		return
+	}
+	forwardReq.Header.Set(header, value)
+}
+
+func forwardedProto(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
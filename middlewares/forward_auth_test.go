@@ -0,0 +1,179 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newForwardAuthTestServer(t *testing.T, forward *types.Forward) *httptest.Server {
+	authMiddleware, err := NewAuthenticator(&types.Auth{Forward: forward})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := negroni.New(authMiddleware)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "traefik %s", r.Header.Get("X-Auth-User"))
+	})
+	return httptest.NewServer(n)
+}
+
+func TestForwardAuthFail(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer authServer.Close()
+
+	ts := newForwardAuthTestServer(t, &types.Forward{Address: authServer.URL})
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "they should be equal")
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(t, err, "there should be no error")
+	assert.Equal(t, "Forbidden\n", string(body), "they should be equal")
+}
+
+func TestForwardAuthFailLargeBody(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(make([]byte, 2*forwardAuthMaxBodySize))
+	}))
+	defer authServer.Close()
+
+	ts := newForwardAuthTestServer(t, &types.Forward{Address: authServer.URL})
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "they should be equal")
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(t, err, "there should be no error")
+	assert.Len(t, body, forwardAuthMaxBodySize, "the body should be truncated")
+}
+
+func TestForwardAuthSuccess(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Basic dGVzdDp0ZXN0", r.Header.Get("Authorization"))
+		assert.Equal(t, "/foo?bar=baz", r.Header.Get("X-Forwarded-Uri"))
+		assert.Equal(t, "GET", r.Header.Get("X-Forwarded-Method"))
+		assert.Equal(t, "http", r.Header.Get("X-Forwarded-Proto"))
+		w.Header().Set("X-Auth-User", "user@example.com")
+		w.Header().Set("X-Auth-Secret", "secret")
+		fmt.Fprintln(w, "Success")
+	}))
+	defer authServer.Close()
+
+	ts := newForwardAuthTestServer(t, &types.Forward{
+		Address:             authServer.URL,
+		AuthResponseHeaders: []string{"X-Auth-User"},
+	})
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/foo?bar=baz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("test", "test")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, res.StatusCode, "they should be equal")
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(t, err, "there should be no error")
+	assert.Equal(t, "traefik user@example.com", string(body), "they should be equal")
+}
+
+func TestForwardAuthRedirect(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/redirect-test", http.StatusFound)
+	}))
+	defer authServer.Close()
+
+	ts := newForwardAuthTestServer(t, &types.Forward{Address: authServer.URL})
+	defer ts.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusFound, res.StatusCode, "they should be equal")
+	assert.Equal(t, "http://example.com/redirect-test", res.Header.Get("Location"), "they should be equal")
+}
+
+func TestForwardAuthTrustForwardHeader(t *testing.T) {
+	testCases := []struct {
+		trustForwardHeader bool
+		expectedProto      string
+		expectedFor        string
+	}{
+		{trustForwardHeader: false, expectedProto: "http", expectedFor: "10.0.1.2"},
+		{trustForwardHeader: true, expectedProto: "https", expectedFor: "10.0.0.1, 10.0.1.2"},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/foo", nil)
+		req.RemoteAddr = "10.0.1.2:1234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		forwardReq := httptest.NewRequest(http.MethodGet, "http://auth.example.com", nil)
+
+		writeForwardAuthHeaders(req, forwardReq, test.trustForwardHeader)
+
+		assert.Equal(t, test.expectedProto, forwardReq.Header.Get("X-Forwarded-Proto"))
+		assert.Equal(t, test.expectedFor, forwardReq.Header.Get("X-Forwarded-For"))
+		assert.Equal(t, "example.com", forwardReq.Header.Get("X-Forwarded-Host"))
+	}
+}
+
+func TestForwardAuthEmptyAddress(t *testing.T) {
+	_, err := NewAuthenticator(&types.Auth{Forward: &types.Forward{}})
+	assert.Error(t, err)
+}
+
+func TestForwardAuthCanceled(t *testing.T) {
+	unblock := make(chan struct{})
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer authServer.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/foo", nil).WithContext(ctx)
+	rw := httptest.NewRecorder()
+	next := func(w http.ResponseWriter, r *http.Request) {
+		t.Error("next should not be called")
+	}
+
+	forwardAuth(&types.Forward{Address: authServer.URL}, newForwardAuthClient(), rw, req, next)
+
+	assert.Equal(t, http.StatusInternalServerError, rw.Code, "they should be equal")
+}
@@ -0,0 +1,170 @@
+package forwardedheaders
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/whitelist"
+)
+
+const (
+	forwarded       = "Forwarded"
+	xForwardedFor   = "X-Forwarded-For"
+	xForwardedProto = "X-Forwarded-Proto"
+	xForwardedHost  = "X-Forwarded-Host"
+	xForwardedPort  = "X-Forwarded-Port"
+	xRealIP         = "X-Real-Ip"
+)
+
+// forwardingHeaders are the headers describing the previous hops of a request
+var forwardingHeaders = []string{forwarded, xForwardedFor, xForwardedProto, xForwardedHost, xForwardedPort, xRealIP}
+
+type clientIPKey struct{}
+
+// XForwarded is a middleware removing the forwarding headers (X-Forwarded-* and
+// Forwarded) of the requests not coming from a trusted IP, and resolving the
+// client IP of the requests from their trusted hops
+type XForwarded struct {
+	insecure   bool
+	trustedIPs *whitelist.IP
+}
+
+// NewXForwarded builds a new XForwarded, trusting the forwarding headers sent
+// by the given CIDRs or IPs, or by any source when insecure
+func NewXForwarded(insecure bool, trustedIPs []string) (*XForwarded, error) {
+	xForwarded := &XForwarded{insecure: insecure}
+	if len(trustedIPs) > 0 {
+		ips, err := whitelist.NewIP(trustedIPs)
+		if err != nil {
+			return nil, err
+		}
+		xForwarded.trustedIPs = ips
+	}
+	return xForwarded, nil
+}
+
+func (x *XForwarded) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	remoteIP := net.ParseIP(hostFromAddr(req.RemoteAddr))
+	if !x.trusted(remoteIP) {
+		for _, header := range forwardingHeaders {
+			req.Header.Del(header)
+		}
+	}
+
+	if clientIP := x.clientIP(req, remoteIP); clientIP != nil {
+		req = req.WithContext(context.WithValue(req.Context(), clientIPKey{}, clientIP.String()))
+	}
+	if remoteIP != nil {
+		appendForwarded(req, remoteIP)
+	}
+	next(rw, req)
+}
+
+func (x *XForwarded) trusted(ip net.IP) bool {
+	if x.insecure {
+		return true
+	}
+	return ip != nil && x.trustedIPs != nil && x.trustedIPs.ContainsIP(ip)
+}
+
+// clientIP walks the hops of the request from the nearest one, and returns the
+// first one not trusted, or the farthest one when they all are
+func (x *XForwarded) clientIP(req *http.Request, remoteIP net.IP) net.IP {
+	if remoteIP == nil {
+		return nil
+	}
+	hops := forwardedForHops(req.Header)
+	if len(hops) == 0 {
+		hops = xForwardedForHops(req.Header)
+	}
+
+	clientIP := remoteIP
+	for i := len(hops) - 1; i >= 0 && x.trusted(clientIP); i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// unknown or obfuscated hop, the nearest known one is used
+			break
+		}
+		clientIP = ip
+	}
+	return clientIP
+}
+
+// xForwardedForHops returns the IPs of the X-Forwarded-For headers, the farthest first
+func xForwardedForHops(header http.Header) []string {
+	var hops []string
+	for _, value := range header[xForwardedFor] {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedForHops returns the "for" parameters of the RFC 7239 Forwarded headers,
+// the farthest first, without their port
+func forwardedForHops(header http.Header) []string {
+	var hops []string
+	for _, value := range header[forwarded] {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				node := strings.Trim(kv[1], `"`)
+				if strings.HasPrefix(node, "[") {
+					if end := strings.Index(node, "]"); end > 0 {
+						node = node[1:end]
+					}
+				} else if host, _, err := net.SplitHostPort(node); err == nil {
+					node = host
+				}
+				hops = append(hops, node)
+			}
+		}
+	}
+	return hops
+}
+
+// appendForwarded adds the element of this hop to the Forwarded header,
+// X-Forwarded-* ones being set by the forwarder
+func appendForwarded(req *http.Request, remoteIP net.IP) {
+	node := remoteIP.String()
+	if remoteIP.To4() == nil {
+		node = fmt.Sprintf(`"[%s]"`, node)
+	}
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	element := fmt.Sprintf("for=%s;proto=%s", node, proto)
+	if len(req.Host) > 0 {
+		element += fmt.Sprintf(";host=%q", req.Host)
+	}
+
+	if prior := req.Header[forwarded]; len(prior) > 0 {
+		element = strings.Join(prior, ", ") + ", " + element
+	}
+	req.Header.Set(forwarded, element)
+}
+
+// ClientIP returns the client IP of the request resolved by XForwarded, or the
+// host of its remote address
+func ClientIP(req *http.Request) string {
+	if clientIP, ok := req.Context().Value(clientIPKey{}).(string); ok {
+		return clientIP
+	}
+	return hostFromAddr(req.RemoteAddr)
+}
+
+func hostFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
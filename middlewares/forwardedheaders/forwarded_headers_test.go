@@ -0,0 +1,160 @@
+package forwardedheaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXForwarded(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		insecure          bool
+		trustedIPs        []string
+		remoteAddr        string
+		incomingHeaders   map[string]string
+		expectedClientIP  string
+		expectedHeaders   map[string]string
+		expectedForwarded string
+	}{
+		{
+			desc:       "untrusted source",
+			trustedIPs: []string{"10.0.0.1"},
+			remoteAddr: "1.2.3.4:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For":   "6.6.6.6",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "evil.com",
+				"X-Forwarded-Port":  "443",
+				"X-Real-Ip":         "6.6.6.6",
+				"Forwarded":         "for=6.6.6.6",
+			},
+			expectedClientIP: "1.2.3.4",
+			expectedHeaders: map[string]string{
+				"X-Forwarded-For":   "",
+				"X-Forwarded-Proto": "",
+				"X-Forwarded-Host":  "",
+				"X-Forwarded-Port":  "",
+				"X-Real-Ip":         "",
+			},
+			expectedForwarded: `for=1.2.3.4;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "no trusted IP",
+			remoteAddr: "10.0.0.1:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For": "6.6.6.6",
+			},
+			expectedClientIP: "10.0.0.1",
+			expectedHeaders: map[string]string{
+				"X-Forwarded-For": "",
+			},
+			expectedForwarded: `for=10.0.0.1;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "trusted source",
+			trustedIPs: []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For":   "6.6.6.6, 1.2.3.4, 10.0.0.2",
+				"X-Forwarded-Proto": "https",
+			},
+			expectedClientIP: "1.2.3.4",
+			expectedHeaders: map[string]string{
+				"X-Forwarded-For":   "6.6.6.6, 1.2.3.4, 10.0.0.2",
+				"X-Forwarded-Proto": "https",
+			},
+			expectedForwarded: `for=10.0.0.1;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "trusted source with only trusted hops",
+			trustedIPs: []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For": "10.0.0.3, 10.0.0.2",
+			},
+			expectedClientIP:  "10.0.0.3",
+			expectedForwarded: `for=10.0.0.1;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "trusted source with unknown hop",
+			trustedIPs: []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For": "1.2.3.4, unknown, 10.0.0.2",
+			},
+			expectedClientIP:  "10.0.0.2",
+			expectedForwarded: `for=10.0.0.1;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "trusted source with Forwarded header",
+			trustedIPs: []string{"10.0.0.0/8", "2001:db8::/32"},
+			remoteAddr: "10.0.0.1:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For": "6.6.6.6",
+				"Forwarded":       `for=1.2.3.4:4711;proto=https, for="[2001:db8::1]:4711"`,
+			},
+			expectedClientIP:  "1.2.3.4",
+			expectedForwarded: `for=1.2.3.4:4711;proto=https, for="[2001:db8::1]:4711", for=10.0.0.1;proto=http;host="example.com"`,
+		},
+		{
+			desc:       "insecure",
+			insecure:   true,
+			remoteAddr: "[2001:db8::2]:1234",
+			incomingHeaders: map[string]string{
+				"X-Forwarded-For": "1.2.3.4, 6.6.6.6",
+			},
+			expectedClientIP: "1.2.3.4",
+			expectedHeaders: map[string]string{
+				"X-Forwarded-For": "1.2.3.4, 6.6.6.6",
+			},
+			expectedForwarded: `for="[2001:db8::2]";proto=http;host="example.com"`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			xForwarded, err := NewXForwarded(test.insecure, test.trustedIPs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for name, value := range test.incomingHeaders {
+				req.Header.Set(name, value)
+			}
+
+			var clientIP string
+			var headers http.Header
+			xForwarded.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, req *http.Request) {
+				clientIP = ClientIP(req)
+				headers = req.Header
+			})
+
+			assert.Equal(t, test.expectedClientIP, clientIP)
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, headers.Get(name), name)
+			}
+			assert.Equal(t, test.expectedForwarded, headers.Get("Forwarded"))
+		})
+	}
+}
+
+func TestNewXForwardedInvalidTrustedIPs(t *testing.T) {
+	_, err := NewXForwarded(false, []string{"foo"})
+	assert.Error(t, err)
+}
+
+func TestClientIPWithoutXForwarded(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	assert.Equal(t, "1.2.3.4", ClientIP(req))
+
+	req.RemoteAddr = "1.2.3.4"
+	assert.Equal(t, "1.2.3.4", ClientIP(req))
+}
@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/containous/traefik/types"
+)
+
+// HeaderStruct is a middleware that adds, overrides and removes
+// request and response headers of a frontend
+type HeaderStruct struct {
+	// If Custom request headers are set, these will be added to the request
+	customRequestHeaders map[string]string
+	// If Custom response headers are set, these will be added to the ResponseWriter
+	customResponseHeaders map[string]string
+	// Request headers removed before the request is forwarded
+	removeRequestHeaders []string
+	// Response headers removed before the response is written
+	removeResponseHeaders []string
+}
+
+// NewHeaderFromStruct constructs a new header instance from supplied frontend header struct.
+// It returns nil when no custom header is defined.
+func NewHeaderFromStruct(headers *types.Headers) *HeaderStruct {
+	if !headers.HasCustomHeadersDefined() {
+		return nil
+	}
+
+	return &HeaderStruct{
+		customRequestHeaders:  headers.CustomRequestHeaders,
+		customResponseHeaders: headers.CustomResponseHeaders,
+		removeRequestHeaders:  headers.RemoveRequestHeaders,
+		removeResponseHeaders: headers.RemoveResponseHeaders,
+	}
+}
+
+func (s *HeaderStruct) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	s.ModifyRequestHeaders(r)
+	if len(s.customResponseHeaders) > 0 || len(s.removeResponseHeaders) > 0 {
+		w = &headerResponseWriter{rw: w, modify: s.ModifyResponseHeaders}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// ModifyRequestHeaders set or delete request headers
+func (s *HeaderStruct) ModifyRequestHeaders(r *http.Request) {
+	for _, header := range s.removeRequestHeaders {
+		r.Header.Del(header)
+	}
+	// Loop through Custom request headers
+	for header, value := range s.customRequestHeaders {
+		if value == "" {
+			r.Header.Del(header)
+		} else if http.CanonicalHeaderKey(header) == "Host" {
+			r.Host = value
+		} else {
+			r.Header.Set(header, value)
+		}
+	}
+}
+
+// ModifyResponseHeaders set or delete response headers
+func (s *HeaderStruct) ModifyResponseHeaders(header http.Header) {
+	for _, name := range s.removeResponseHeaders {
+		header.Del(name)
+	}
+	// Loop through Custom response headers
+	for name, value := range s.customResponseHeaders {
+		if value == "" {
+			header.Del(name)
+		} else {
+			header.Set(name, value)
+		}
+	}
+}
+
+// headerResponseWriter applies the response header modifications
+// right before the headers are sent to the client
+type headerResponseWriter struct {
+	rw          http.ResponseWriter
+	modify      func(http.Header)
+	wroteHeader bool
+}
+
+func (hrw *headerResponseWriter) Header() http.Header {
+	return hrw.rw.Header()
+}
+
+func (hrw *headerResponseWriter) Write(b []byte) (int, error) {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	return hrw.rw.Write(b)
+}
+
+func (hrw *headerResponseWriter) WriteHeader(code int) {
+	if !hrw.wroteHeader {
+		hrw.wroteHeader = true
+		hrw.modify(hrw.rw.Header())
+	}
+	hrw.rw.WriteHeader(code)
+}
+
+func (hrw *headerResponseWriter) Flush() {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := hrw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (hrw *headerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := hrw.rw.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("not a hijacker: %T", hrw.rw)
+}
+
+func (hrw *headerResponseWriter) CloseNotify() <-chan bool {
+	if c, ok := hrw.rw.(http.CloseNotifier); ok {
+		return c.CloseNotify()
+	}
+	return nil
+}
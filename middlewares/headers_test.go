@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHeaderFromStructWithoutHeaders(t *testing.T) {
+	assert.Nil(t, NewHeaderFromStruct(nil))
+	assert.Nil(t, NewHeaderFromStruct(&types.Headers{}))
+}
+
+func TestCustomRequestHeaders(t *testing.T) {
+	header := NewHeaderFromStruct(&types.Headers{
+		CustomRequestHeaders: map[string]string{
+			"X-Custom-Request-Header": "test_request",
+			"X-Forwarded-Proto":       "https",
+			"X-Empty":                 "",
+		},
+		RemoveRequestHeaders: []string{"X-Secret"},
+	})
+
+	var received http.Header
+	n := negroni.New(header)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	})
+
+	req := httptest.NewRequest("GET", "http://localhost/foo", nil)
+	req.Header.Set("X-Secret", "password")
+	req.Header.Set("X-Empty", "value")
+	req.Header.Set("X-Forwarded-Proto", "http")
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "test_request", received.Get("X-Custom-Request-Header"))
+	assert.Equal(t, "https", received.Get("X-Forwarded-Proto"))
+	assert.NotContains(t, received, "X-Secret")
+	assert.NotContains(t, received, "X-Empty")
+}
+
+func TestCustomResponseHeaders(t *testing.T) {
+	header := NewHeaderFromStruct(&types.Headers{
+		CustomResponseHeaders: map[string]string{
+			"X-Custom-Response-Header": "test_response",
+		},
+		RemoveResponseHeaders: []string{"Server", "X-Powered-By"},
+	})
+
+	n := negroni.New(header)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Apache")
+		w.Header().Set("X-Powered-By", "PHP")
+		w.Header().Set("X-Custom-Response-Header", "backend")
+		w.Write([]byte("bar"))
+	})
+
+	rw := httptest.NewRecorder()
+	n.ServeHTTP(rw, httptest.NewRequest("GET", "http://localhost/foo", nil))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "bar", rw.Body.String())
+	assert.Equal(t, "test_response", rw.Header().Get("X-Custom-Response-Header"))
+	assert.NotContains(t, rw.Header(), "Server")
+	assert.NotContains(t, rw.Header(), "X-Powered-By")
+}
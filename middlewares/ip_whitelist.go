@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/containous/traefik/whitelist"
+)
+
+const forwardedForHeader = "X-Forwarded-For"
+
+// IPWhitelister is a middleware that provides checks for incoming requests' remote address
+// against a list of allowed CIDRs
+type IPWhitelister struct {
+	whitelister    *whitelist.IP
+	trustedProxies *whitelist.IP
+}
+
+// NewIPWhitelister builds a new IPWhitelister given a list of CIDR-Strings to whitelist.
+// The X-Forwarded-For header is only used when the request comes from one of the trusted proxies.
+func NewIPWhitelister(whitelistStrings []string, trustedProxies []string) (*IPWhitelister, error) {
+	if len(whitelistStrings) == 0 {
+		return nil, fmt.Errorf("no whitelists provided")
+	}
+
+	whitelister, err := whitelist.NewIP(whitelistStrings)
+	if err != nil {
+		return nil, err
+	}
+
+	ipWhitelister := &IPWhitelister{whitelister: whitelister}
+	if len(trustedProxies) > 0 {
+		ipWhitelister.trustedProxies, err = whitelist.NewIP(trustedProxies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debugf("configured %d IP whitelists: %s", len(whitelistStrings), whitelistStrings)
+
+	return ipWhitelister, nil
+}
+
+func (wl *IPWhitelister) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ip, err := wl.clientIP(r)
+	if err != nil {
+		log.Debugf("unable to parse remote-address from header: %s - rejecting", r.RemoteAddr)
+		reject(w)
+		return
+	}
+
+	if !wl.whitelister.ContainsIP(ip) {
+		log.Debugf("source-IP %s matched none of the whitelists - rejecting", ip)
+		reject(w)
+		return
+	}
+
+	log.Debugf("source-IP %s matched whitelist - passing", ip)
+	next.ServeHTTP(w, r)
+}
+
+// clientIP returns the client IP of the request, or the first untrusted address
+// of the X-Forwarded-For header when the request comes from a trusted proxy
+func (wl *IPWhitelister) clientIP(r *http.Request) (net.IP, error) {
+	ip := net.ParseIP(forwardedheaders.ClientIP(r))
+	if ip == nil {
+		return nil, fmt.Errorf("can't parse IP from address %s", r.RemoteAddr)
+	}
+	if wl.trustedProxies == nil || !wl.trustedProxies.ContainsIP(ip) {
+		return ip, nil
+	}
+
+	forwardedFor := r.Header[forwardedForHeader]
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		addrs := strings.Split(forwardedFor[i], ",")
+		for j := len(addrs) - 1; j >= 0; j-- {
+			ip = net.ParseIP(strings.TrimSpace(addrs[j]))
+			if ip == nil {
+				return nil, fmt.Errorf("can't parse IP from %s header %s", forwardedForHeader, forwardedFor[i])
+			}
+			if !wl.trustedProxies.ContainsIP(ip) {
+				return ip, nil
+			}
+		}
+	}
+	return ip, nil
+}
+
+func reject(w http.ResponseWriter) {
+	statusCode := http.StatusForbidden
+
+	w.WriteHeader(statusCode)
+	w.Write([]byte(http.StatusText(statusCode)))
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIPWhitelister(t *testing.T) {
+	_, err := NewIPWhitelister(nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewIPWhitelister([]string{"foo"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewIPWhitelister([]string{"10.0.0.0/8"}, []string{"bar"})
+	assert.Error(t, err)
+
+	_, err = NewIPWhitelister([]string{"10.0.0.0/8"}, []string{"192.168.0.1"})
+	assert.NoError(t, err)
+}
+
+func TestIPWhitelisterHandle(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		whitelist      []string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   []string
+		expected       int
+	}{
+		{
+			desc:       "allowed remote address",
+			whitelist:  []string{"10.0.0.0/8"},
+			remoteAddr: "10.1.2.3:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			desc:       "rejected remote address",
+			whitelist:  []string{"10.0.0.0/8"},
+			remoteAddr: "192.168.1.1:1234",
+			expected:   http.StatusForbidden,
+		},
+		{
+			desc:       "invalid remote address",
+			whitelist:  []string{"10.0.0.0/8"},
+			remoteAddr: "foo",
+			expected:   http.StatusForbidden,
+		},
+		{
+			desc:         "X-Forwarded-For ignored without trusted proxies",
+			whitelist:    []string{"10.0.0.0/8"},
+			remoteAddr:   "192.168.1.1:1234",
+			forwardedFor: []string{"10.1.2.3"},
+			expected:     http.StatusForbidden,
+		},
+		{
+			desc:           "X-Forwarded-For ignored from untrusted peer",
+			whitelist:      []string{"10.0.0.0/8"},
+			trustedProxies: []string{"172.16.0.1"},
+			remoteAddr:     "192.168.1.1:1234",
+			forwardedFor:   []string{"10.1.2.3"},
+			expected:       http.StatusForbidden,
+		},
+		{
+			desc:           "X-Forwarded-For used from trusted proxy",
+			whitelist:      []string{"10.0.0.0/8"},
+			trustedProxies: []string{"172.16.0.0/16"},
+			remoteAddr:     "172.16.0.1:1234",
+			forwardedFor:   []string{"10.1.2.3, 172.16.0.2"},
+			expected:       http.StatusOK,
+		},
+		{
+			desc:           "spoofed X-Forwarded-For entry before the real client",
+			whitelist:      []string{"10.0.0.0/8"},
+			trustedProxies: []string{"172.16.0.0/16"},
+			remoteAddr:     "172.16.0.1:1234",
+			forwardedFor:   []string{"10.1.2.3", "192.168.1.1"},
+			expected:       http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		whitelister, err := NewIPWhitelister(test.whitelist, test.trustedProxies)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := negroni.New(whitelister)
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("traefik"))
+		})
+
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.RemoteAddr = test.remoteAddr
+		for _, value := range test.forwardedFor {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, req)
+
+		assert.Equal(t, test.expected, rw.Code, test.desc)
+	}
+}
+
+func TestIPWhitelisterResolvedClientIP(t *testing.T) {
+	whitelister, err := NewIPWhitelister([]string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xForwarded, err := forwardedheaders.NewXForwarded(false, []string{"172.16.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := negroni.New(xForwarded, whitelister)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("traefik"))
+	})
+
+	serve := func(remoteAddr string, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("172.16.0.1:1234", "10.1.2.3"), "client resolved from a trusted proxy")
+	assert.Equal(t, http.StatusForbidden, serve("172.16.0.1:1234", "192.168.1.1"), "client resolved from a trusted proxy")
+	assert.Equal(t, http.StatusForbidden, serve("192.168.1.1:1234", "10.1.2.3"), "X-Forwarded-For from an untrusted peer")
+}
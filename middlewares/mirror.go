@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	defaultMirrorMaxBodySize = 1 << 20
+	mirrorMaxInFlight        = 100
+	mirrorTimeout            = 30 * time.Second
+)
+
+// Mirror sends a copy of a sample of the requests to a secondary handler, in
+// the background, discarding its responses
+type Mirror struct {
+	handler     http.Handler
+	percent     int
+	maxBodySize int64
+	inFlight    chan struct{}
+	timeout     time.Duration
+}
+
+// NewMirror builds a new Mirror sending percent of the requests to the handler,
+// none when it is zero. Requests with a body larger than maxBodySize bytes are
+// not mirrored.
+func NewMirror(handler http.Handler, percent int, maxBodySize int64) *Mirror {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMirrorMaxBodySize
+	}
+	return &Mirror{
+		handler:     handler,
+		percent:     percent,
+		maxBodySize: maxBodySize,
+		inFlight:    make(chan struct{}, mirrorMaxInFlight),
+		timeout:     mirrorTimeout,
+	}
+}
+
+func (m *Mirror) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m.percent == 100 || rand.Intn(100) < m.percent {
+		m.mirror(r)
+	}
+	next.ServeHTTP(rw, r)
+}
+
+// mirror sends a copy of the request to the handler in the background, unless
+// too many mirrored requests are already in flight, in which case it is dropped
+func (m *Mirror) mirror(r *http.Request) {
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		log.Debugf("Not mirroring request %s: %d mirrored requests in flight", r.URL, cap(m.inFlight))
+		return
+	}
+
+	mirrorReq, err := m.copyRequest(r)
+	if err != nil {
+		log.Debugf("Not mirroring request %s: %v", r.URL, err)
+	}
+	if mirrorReq == nil {
+		<-m.inFlight
+		return
+	}
+
+	go func() {
+		defer func() { <-m.inFlight }()
+		ctx, cancel := context.WithTimeout(mirrorReq.Context(), m.timeout)
+		defer cancel()
+		m.handler.ServeHTTP(nopResponseWriter{}, mirrorReq.WithContext(ctx))
+	}()
+}
+
+// copyRequest copies the request for the mirror, reading at most maxBodySize
+// bytes of its body, which are given back to the original request
+func (m *Mirror) copyRequest(r *http.Request) (*http.Request, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, m.maxBodySize+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > m.maxBodySize {
+			log.Debugf("Not mirroring request %s: body larger than %d bytes", r.URL, m.maxBodySize)
+			return nil, nil
+		}
+	}
+
+	// the mirrored request must outlive the original one
+	mirrorReq := accesslog.WithLogDataTable(r.WithContext(context.Background()))
+	mirrorReq.URL = utils.CopyURL(r.URL)
+	mirrorReq.Header = make(http.Header)
+	utils.CopyHeaders(mirrorReq.Header, r.Header)
+	mirrorReq.ContentLength = int64(len(body))
+	mirrorReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		mirrorReq.Body = http.NoBody
+	}
+	return mirrorReq, nil
+}
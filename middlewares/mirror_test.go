@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+type mirroredRequest struct {
+	path   string
+	header string
+	body   string
+}
+
+// mirrorRecorder records the requests it receives on a channel
+func mirrorRecorder(requests chan<- mirroredRequest) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- mirroredRequest{path: r.URL.Path, header: r.Header.Get("X-Test"), body: string(body)}
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("mirror response"))
+	})
+}
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		desc         string
+		body         string
+		maxBodySize  int64
+		wantMirrored bool
+	}{
+		{
+			desc:         "no body",
+			wantMirrored: true,
+		},
+		{
+			desc:         "small body",
+			body:         "hello",
+			maxBodySize:  5,
+			wantMirrored: true,
+		},
+		{
+			desc:         "body larger than the limit",
+			body:         "hello world",
+			maxBodySize:  5,
+			wantMirrored: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			requests := make(chan mirroredRequest, 1)
+			mirror := NewMirror(mirrorRecorder(requests), 100, test.maxBodySize)
+
+			handler := negroni.New(mirror)
+			handler.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				w.Write(append([]byte("live "), body...))
+			}))
+
+			req := httptest.NewRequest("POST", "http://localhost/foo", strings.NewReader(test.body))
+			req.Header.Set("X-Test", "mirrored")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code, "the mirror response must be discarded")
+			assert.Equal(t, "live "+test.body, recorder.Body.String(), "the live backend must get the whole body")
+
+			select {
+			case mirrored := <-requests:
+				assert.True(t, test.wantMirrored, "unexpected mirrored request")
+				assert.Equal(t, mirroredRequest{path: "/foo", header: "mirrored", body: test.body}, mirrored)
+			case <-time.After(100 * time.Millisecond):
+				assert.False(t, test.wantMirrored, "request not mirrored")
+			}
+		})
+	}
+}
+
+func TestMirrorPercent(t *testing.T) {
+	var mutex sync.Mutex
+	mirrored := 0
+	mirror := NewMirror(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		mirrored++
+	}), 10, 0)
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	for i := 0; i < 1000; i++ {
+		mirror.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil), next)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.InDelta(t, 100, mirrored, 50, "about 10%% of the requests should be mirrored")
+}
+
+func TestMirrorZeroPercent(t *testing.T) {
+	requests := make(chan mirroredRequest, 1)
+	mirror := NewMirror(mirrorRecorder(requests), 0, 0)
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	for i := 0; i < 100; i++ {
+		mirror.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil), next)
+	}
+
+	select {
+	case <-requests:
+		t.Error("no request should be mirrored")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMirrorDropWhenBusy(t *testing.T) {
+	unblock := make(chan struct{})
+	var mutex sync.Mutex
+	mirrored := 0
+	mirror := NewMirror(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		mirrored++
+		mutex.Unlock()
+		<-unblock
+	}), 100, 0)
+	mirror.inFlight = make(chan struct{}, 2)
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	for i := 0; i < 5; i++ {
+		mirror.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil), next)
+	}
+	close(unblock)
+	time.Sleep(50 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 2, mirrored, "the requests beyond the in flight limit should be dropped")
+	assert.Len(t, mirror.inFlight, 0, "the in flight slots should be released")
+}
+
+func TestMirrorTimeout(t *testing.T) {
+	canceled := make(chan error, 1)
+	mirror := NewMirror(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		canceled <- r.Context().Err()
+	}), 100, 0)
+	mirror.timeout = 10 * time.Millisecond
+
+	mirror.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil), func(w http.ResponseWriter, r *http.Request) {})
+
+	select {
+	case err := <-canceled:
+		assert.Equal(t, context.DeadlineExceeded, err, "the mirrored request should time out")
+	case <-time.After(time.Second):
+		t.Error("mirrored request not canceled")
+	}
+}
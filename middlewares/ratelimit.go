@@ -0,0 +1,136 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/juju/ratelimit"
+	"github.com/vulcand/oxy/utils"
+)
+
+const defaultRateLimitExtractorFunc = "client.ip"
+
+// RateLimiter is a middleware that limits the rate of the requests sharing the
+// same source, as returned by the extractor, with one token bucket per rate
+type RateLimiter struct {
+	extractor utils.SourceExtractor
+	rates     []*types.Rate
+	// sources unused for longer than expiry have refilled all their buckets and can be dropped
+	expiry      time.Duration
+	nextCleanup time.Time
+	buckets     map[string]*sourceBuckets
+	mutex       sync.Mutex
+}
+
+type sourceBuckets struct {
+	buckets  []*ratelimit.Bucket
+	lastSeen time.Time
+}
+
+// NewRateLimiter builds a new RateLimiter given a frontend rate limit configuration
+func NewRateLimiter(config *types.RateLimit) (*RateLimiter, error) {
+	if config == nil || len(config.RateSet) == 0 {
+		return nil, fmt.Errorf("no rate set provided")
+	}
+
+	extractorFunc := config.ExtractorFunc
+	if len(extractorFunc) == 0 {
+		extractorFunc = defaultRateLimitExtractorFunc
+	}
+	extractor, err := NewExtractor(extractorFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimiter := &RateLimiter{
+		extractor: extractor,
+		buckets:   make(map[string]*sourceBuckets),
+	}
+
+	// sort rate names to get a predictable order
+	var names []string
+	for name := range config.RateSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rate := *config.RateSet[name]
+		if rate.Average <= 0 {
+			return nil, fmt.Errorf("rate %s: average must be greater than 0, got %d", name, rate.Average)
+		}
+		if rate.Period <= 0 {
+			rate.Period = flaeg.Duration(time.Second)
+		}
+		if rate.Burst <= 0 {
+			rate.Burst = rate.Average
+		}
+		rateLimiter.rates = append(rateLimiter.rates, &rate)
+
+		refill := time.Duration(rate.Period) * time.Duration((rate.Burst+rate.Average-1)/rate.Average)
+		if refill > rateLimiter.expiry {
+			rateLimiter.expiry = refill
+		}
+	}
+
+	return rateLimiter, nil
+}
+
+func (rl *RateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	source, _, err := rl.extractor.Extract(r)
+	if err != nil {
+		log.Errorf("Error extracting rate limit source: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if !rl.take(source, time.Now()) {
+		log.Debugf("Rate limit exceeded for %s", source)
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// take consumes one token in every bucket of the source, it returns false
+// and consumes nothing when one of the buckets is empty
+func (rl *RateLimiter) take(source string, now time.Time) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if now.After(rl.nextCleanup) {
+		for key, sb := range rl.buckets {
+			if now.Sub(sb.lastSeen) > rl.expiry {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.nextCleanup = now.Add(rl.expiry)
+	}
+
+	sb, ok := rl.buckets[source]
+	if !ok {
+		sb = &sourceBuckets{}
+		for _, rate := range rl.rates {
+			sb.buckets = append(sb.buckets, ratelimit.NewBucketWithQuantum(time.Duration(rate.Period), rate.Burst, rate.Average))
+		}
+		rl.buckets[source] = sb
+	}
+	sb.lastSeen = now
+
+	for _, bucket := range sb.buckets {
+		if bucket.Available() < 1 {
+			return false
+		}
+	}
+	for _, bucket := range sb.buckets {
+		bucket.TakeAvailable(1)
+	}
+	return true
+}
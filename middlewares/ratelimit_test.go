@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/middlewares/forwardedheaders"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRateLimiterErrors(t *testing.T) {
+	_, err := NewRateLimiter(nil)
+	assert.Error(t, err)
+
+	_, err = NewRateLimiter(&types.RateLimit{RateSet: map[string]*types.Rate{"foo": {Period: flaeg.Duration(time.Second)}}})
+	assert.Error(t, err)
+
+	_, err = NewRateLimiter(&types.RateLimit{
+		ExtractorFunc: "foo",
+		RateSet:       map[string]*types.Rate{"foo": {Average: 1}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	rateLimiter, err := NewRateLimiter(&types.RateLimit{
+		ExtractorFunc: "request.header.X-Client",
+		RateSet: map[string]*types.Rate{
+			"short": {Period: flaeg.Duration(time.Hour), Average: 2, Burst: 3},
+			"long":  {Period: flaeg.Duration(24 * time.Hour), Average: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := negroni.New(rateLimiter)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("traefik"))
+	})
+
+	serve := func(client string) int {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.Header.Set("X-Client", client)
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("foo"), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve("foo"))
+	assert.Equal(t, http.StatusOK, serve("bar"))
+}
+
+func TestRateLimiterExpiry(t *testing.T) {
+	rateLimiter, err := NewRateLimiter(&types.RateLimit{
+		RateSet: map[string]*types.Rate{
+			"foo": {Period: flaeg.Duration(time.Second), Average: 5, Burst: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2*time.Second, rateLimiter.expiry)
+
+	now := time.Now()
+	assert.True(t, rateLimiter.take("foo", now))
+	assert.True(t, rateLimiter.take("bar", now.Add(time.Second)))
+	assert.Len(t, rateLimiter.buckets, 2)
+
+	assert.True(t, rateLimiter.take("bar", now.Add(3*time.Second)))
+	assert.Len(t, rateLimiter.buckets, 1)
+	assert.Contains(t, rateLimiter.buckets, "bar")
+}
+
+func TestRateLimiterResolvedClientIP(t *testing.T) {
+	rateLimiter, err := NewRateLimiter(&types.RateLimit{
+		RateSet: map[string]*types.Rate{
+			"short": {Period: flaeg.Duration(time.Hour), Average: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xForwarded, err := forwardedheaders.NewXForwarded(false, []string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := negroni.New(xForwarded, rateLimiter)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("traefik"))
+	})
+
+	serve := func(client string) int {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", client)
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("1.2.3.4"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("1.2.3.4"))
+	assert.Equal(t, http.StatusOK, serve("5.6.7.8"))
+}
@@ -2,129 +2,262 @@ package middlewares
 
 import (
 	"bufio"
-	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
 	"github.com/vulcand/oxy/utils"
 )
 
+const (
+	defaultRetryInitialInterval = 50 * time.Millisecond
+	defaultRetryMaxInterval     = time.Second
+	// retryBudgetBurst is the number of retries allowed by a budget before
+	// any request went through
+	retryBudgetBurst = 10
+)
+
 var (
-	_ http.ResponseWriter = &ResponseRecorder{}
-	_ http.Hijacker       = &ResponseRecorder{}
-	_ http.Flusher        = &ResponseRecorder{}
-	_ http.CloseNotifier  = &ResponseRecorder{}
+	_ http.ResponseWriter = &retryResponseWriter{}
+	_ http.Hijacker       = &retryResponseWriter{}
+	_ http.Flusher        = &retryResponseWriter{}
+	_ http.CloseNotifier  = &retryResponseWriter{}
 )
 
-// Retry is a middleware that retries requests
+// idempotentMethods are the methods retried by default
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+type netErrorKey struct{}
+
+// NetErrorHandler is the error handler of the forwarder, recording the
+// failures to reach a server for the Retry middleware before writing the
+// error response
+var NetErrorHandler utils.ErrorHandler = utils.ErrorHandlerFunc(func(w http.ResponseWriter, req *http.Request, err error) {
+	if netError, ok := req.Context().Value(netErrorKey{}).(*bool); ok && err != nil {
+		*netError = true
+	}
+	utils.DefaultHandler.ServeHTTP(w, req, err)
+})
+
+// Retry is a middleware that retries the requests which could not reach a
+// server, as long as nothing was sent to the client nor read from the
+// request body
 type Retry struct {
-	attempts int
-	next     http.Handler
+	attempts        int
+	methods         map[string]bool
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	budget          *retryBudget
+	next            http.Handler
 }
 
 // NewRetry returns a new Retry instance
-func NewRetry(attempts int, next http.Handler) *Retry {
-	return &Retry{
-		attempts: attempts,
-		next:     next,
+func NewRetry(next http.Handler, config types.Retry) *Retry {
+	retry := &Retry{
+		attempts:        config.Attempts,
+		methods:         make(map[string]bool),
+		initialInterval: time.Duration(config.InitialInterval),
+		maxInterval:     time.Duration(config.MaxInterval),
+		budget:          newRetryBudget(config.Budget),
+		next:            next,
+	}
+	methods := []string(config.Methods)
+	if len(methods) == 0 {
+		methods = idempotentMethods
+	}
+	for _, method := range methods {
+		retry.methods[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+	if retry.initialInterval <= 0 {
+		retry.initialInterval = defaultRetryInitialInterval
+	}
+	if retry.maxInterval <= 0 {
+		retry.maxInterval = defaultRetryMaxInterval
 	}
+	return retry
 }
 
 func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	// if we might make multiple attempts, swap the body for an ioutil.NopCloser
+	retry.budget.deposit()
+	if retry.attempts <= 1 || !retry.methods[r.Method] {
+		retry.next.ServeHTTP(rw, r)
+		return
+	}
+
+	// the body must not be closed between the attempts
 	// cf https://github.com/containous/traefik/issues/1008
-	if retry.attempts > 1 {
-		body := r.Body
-		defer body.Close()
+	var body *countingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		defer r.Body.Close()
+		body = &countingReader{Reader: r.Body}
 		r.Body = ioutil.NopCloser(body)
 	}
-	attempts := 1
-	for {
-		recorder := NewRecorder()
-		recorder.responseWriter = rw
-		retry.next.ServeHTTP(recorder, r)
-		if !isNetworkError(recorder.Code) || attempts >= retry.attempts {
-			utils.CopyHeaders(rw.Header(), recorder.Header())
-			rw.WriteHeader(recorder.Code)
-			rw.Write(recorder.Body.Bytes())
-			break
+
+	for attempt := 1; ; attempt++ {
+		netError := false
+		writer := newRetryResponseWriter(rw, func() bool {
+			return netError && attempt < retry.attempts && (body == nil || body.read == 0) &&
+				r.Context().Err() == nil && retry.budget.withdraw()
+		})
+		retry.next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), netErrorKey{}, &netError)))
+		if !writer.retrying {
+			return
 		}
-		attempts++
-		log.Debugf("New attempt %d for request: %v", attempts, r.URL)
+		if !retry.wait(r.Context(), attempt) {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		log.Debugf("New attempt %d for request: %v", attempt+1, r.URL)
 	}
 }
 
-func isNetworkError(status int) bool {
-	return status == http.StatusBadGateway || status == http.StatusGatewayTimeout
+// wait waits before the next attempt, with an exponential backoff and a full
+// jitter, returning false when the client went away
+func (retry *Retry) wait(ctx context.Context, attempt int) bool {
+	interval := retry.maxInterval
+	if attempt < 32 {
+		interval = time.Duration(math.Min(float64(retry.initialInterval<<uint(attempt-1)), float64(retry.maxInterval)))
+	}
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(interval) + 1)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
-// ResponseRecorder is an implementation of http.ResponseWriter that
-// records its mutations for later inspection in tests.
-type ResponseRecorder struct {
-	Code      int           // the HTTP response code from WriteHeader
-	HeaderMap http.Header   // the HTTP response headers
-	Body      *bytes.Buffer // if non-nil, the bytes.Buffer to append written data to
+// retryBudget is a token bucket limiting the retries to a percentage of the
+// requests, a nil budget allowing all the retries
+type retryBudget struct {
+	ratio  float64
+	tokens float64
+	mutex  sync.Mutex
+}
 
+func newRetryBudget(percent int) *retryBudget {
+	if percent <= 0 {
+		return nil
+	}
+	return &retryBudget{ratio: float64(percent) / 100, tokens: retryBudgetBurst}
+}
+
+func (b *retryBudget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens = math.Min(b.tokens+b.ratio, retryBudgetBurst)
+}
+
+func (b *retryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type countingReader struct {
+	io.Reader
+	read int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// retryResponseWriter holds back the headers of an attempt until its response
+// is written, discarding the response when the request is retried
+type retryResponseWriter struct {
 	responseWriter http.ResponseWriter
-	err            error
+	header         http.Header
+	mayRetry       func() bool
+	retrying       bool
+	written        bool
 }
 
-// NewRecorder returns an initialized ResponseRecorder.
-func NewRecorder() *ResponseRecorder {
-	return &ResponseRecorder{
-		HeaderMap: make(http.Header),
-		Body:      new(bytes.Buffer),
-		Code:      200,
+func newRetryResponseWriter(rw http.ResponseWriter, mayRetry func() bool) *retryResponseWriter {
+	return &retryResponseWriter{
+		responseWriter: rw,
+		header:         make(http.Header),
+		mayRetry:       mayRetry,
 	}
 }
 
-// Header returns the response headers.
-func (rw *ResponseRecorder) Header() http.Header {
-	m := rw.HeaderMap
-	if m == nil {
-		m = make(http.Header)
-		rw.HeaderMap = m
+func (rw *retryResponseWriter) Header() http.Header {
+	if rw.written {
+		return rw.responseWriter.Header()
 	}
-	return m
+	return rw.header
 }
 
-// Write always succeeds and writes to rw.Body, if not nil.
-func (rw *ResponseRecorder) Write(buf []byte) (int, error) {
-	if rw.err != nil {
-		return 0, rw.err
+func (rw *retryResponseWriter) WriteHeader(code int) {
+	if rw.written || rw.retrying {
+		return
 	}
-	return rw.Body.Write(buf)
+	if rw.mayRetry() {
+		rw.retrying = true
+		return
+	}
+	rw.commit()
+	rw.responseWriter.WriteHeader(code)
 }
 
-// WriteHeader sets rw.Code.
-func (rw *ResponseRecorder) WriteHeader(code int) {
-	rw.Code = code
+func (rw *retryResponseWriter) Write(buf []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	if rw.retrying {
+		return len(buf), nil
+	}
+	return rw.responseWriter.Write(buf)
 }
 
 // Hijack hijacks the connection
-func (rw *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+func (rw *retryResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.commit()
 	return rw.responseWriter.(http.Hijacker).Hijack()
 }
 
 // CloseNotify returns a channel that receives at most a
 // single value (true) when the client connection has gone
 // away.
-func (rw *ResponseRecorder) CloseNotify() <-chan bool {
+func (rw *retryResponseWriter) CloseNotify() <-chan bool {
 	return rw.responseWriter.(http.CloseNotifier).CloseNotify()
 }
 
 // Flush sends any buffered data to the client.
-func (rw *ResponseRecorder) Flush() {
-	_, err := rw.responseWriter.Write(rw.Body.Bytes())
-	if err != nil {
-		log.Errorf("Error writing response in ResponseRecorder: %s", err)
-		rw.err = err
-	}
-	rw.Body.Reset()
-	flusher, ok := rw.responseWriter.(http.Flusher)
-	if ok {
+func (rw *retryResponseWriter) Flush() {
+	if !rw.written {
+		return
+	}
+	if flusher, ok := rw.responseWriter.(http.Flusher); ok {
 		flusher.Flush()
 	}
 }
+
+// commit copies the headers of the attempt to the client response
+func (rw *retryResponseWriter) commit() {
+	if rw.written {
+		return
+	}
+	utils.CopyHeaders(rw.responseWriter.Header(), rw.header)
+	rw.written = true
+}
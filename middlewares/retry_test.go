@@ -0,0 +1,164 @@
+package middlewares
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingHandler fails to reach a server for the first failures requests
+func failingHandler(failures int, calls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("X-Attempt", "attempt")
+		if *calls <= failures {
+			NetErrorHandler.ServeHTTP(w, r, &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		desc         string
+		method       string
+		methods      types.HTTPMethods
+		attempts     int
+		failures     int
+		wantCode     int
+		wantAttempts int
+	}{
+		{
+			desc:         "network error retried",
+			method:       "GET",
+			attempts:     3,
+			failures:     2,
+			wantCode:     http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			desc:         "attempts exhausted",
+			method:       "GET",
+			attempts:     2,
+			failures:     2,
+			wantCode:     http.StatusBadGateway,
+			wantAttempts: 2,
+		},
+		{
+			desc:         "non idempotent method not retried",
+			method:       "POST",
+			attempts:     3,
+			failures:     1,
+			wantCode:     http.StatusBadGateway,
+			wantAttempts: 1,
+		},
+		{
+			desc:         "allowed method retried",
+			method:       "POST",
+			methods:      types.HTTPMethods{"post"},
+			attempts:     3,
+			failures:     1,
+			wantCode:     http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			desc:         "method not in the allow-list",
+			method:       "GET",
+			methods:      types.HTTPMethods{"POST"},
+			attempts:     3,
+			failures:     1,
+			wantCode:     http.StatusBadGateway,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			retry := NewRetry(failingHandler(test.failures, &calls), types.Retry{
+				Attempts:        test.attempts,
+				Methods:         test.methods,
+				InitialInterval: flaeg.Duration(time.Millisecond),
+			})
+
+			recorder := httptest.NewRecorder()
+			retry.ServeHTTP(recorder, httptest.NewRequest(test.method, "http://localhost/", nil))
+
+			assert.Equal(t, test.wantCode, recorder.Code)
+			assert.Equal(t, test.wantAttempts, calls)
+			assert.Equal(t, []string{"attempt"}, recorder.Header()["X-Attempt"], "only the headers of the last attempt must be sent")
+		})
+	}
+}
+
+func TestRetryBackendError(t *testing.T) {
+	calls := 0
+	retry := NewRetry(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}), types.Retry{Attempts: 3})
+
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, 1, calls, "a response of the server must not be retried")
+}
+
+func TestRetryBodyRead(t *testing.T) {
+	calls := 0
+	retry := NewRetry(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		ioutil.ReadAll(r.Body)
+		NetErrorHandler.ServeHTTP(w, r, &net.OpError{Op: "write", Err: errors.New("broken pipe")})
+	}), types.Retry{Attempts: 3, Methods: types.HTTPMethods{"PUT"}})
+
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, httptest.NewRequest("PUT", "http://localhost/", strings.NewReader("body")))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, 1, calls, "a request whose body was read must not be retried")
+}
+
+func TestRetryBudget(t *testing.T) {
+	calls := 0
+	retry := NewRetry(failingHandler(1000, &calls), types.Retry{
+		Attempts:        2,
+		Budget:          1,
+		InitialInterval: flaeg.Duration(time.Millisecond),
+	})
+
+	for i := 0; i < retryBudgetBurst+2; i++ {
+		retry.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil))
+	}
+
+	assert.Equal(t, 2*retryBudgetBurst+2, calls, "the retries must stop once the budget is spent")
+}
+
+func TestRetryStreaming(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	retry := NewRetry(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first chunk"))
+		w.(http.Flusher).Flush()
+		assert.Equal(t, "first chunk", recorder.Body.String(), "the response must reach the client while it is written")
+		assert.True(t, recorder.Flushed)
+		w.Write([]byte(", second chunk"))
+	}), types.Retry{Attempts: 3})
+
+	retry.ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "first chunk, second chunk", recorder.Body.String())
+}
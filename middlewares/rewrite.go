@@ -9,7 +9,8 @@ import (
 
 // Rewrite is a middleware that allows redirections
 type Rewrite struct {
-	rewriter *rewrite.Rewrite
+	rewriter  *rewrite.Rewrite
+	permanent bool
 }
 
 // NewRewrite creates a Rewrite middleware
@@ -21,12 +22,57 @@ func NewRewrite(regex, replacement string, redirect bool) (*Rewrite, error) {
 	return &Rewrite{rewriter: rewriter}, nil
 }
 
+// NewRedirect creates a Rewrite middleware redirecting with a 301 when permanent, a 302 otherwise
+func NewRedirect(regex, replacement string, permanent bool) (*Rewrite, error) {
+	rewrite, err := NewRewrite(regex, replacement, true)
+	if err != nil {
+		return nil, err
+	}
+	rewrite.permanent = permanent
+	return rewrite, nil
+}
+
 //
 func (rewrite *Rewrite) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	handler, err := rewrite.rewriter.NewHandler(next)
+	if !rewrite.permanent {
+		handler, err := rewrite.rewriter.NewHandler(next)
+		if err != nil {
+			log.Error("Error in rewrite middleware ", err)
+			return
+		}
+		handler.ServeHTTP(rw, r)
+		return
+	}
+
+	// only the redirections written by the rewriter go through the permanent writer,
+	// the responses of the next handlers are left untouched
+	handler, err := rewrite.rewriter.NewHandler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(rw, r)
+	}))
 	if err != nil {
 		log.Error("Error in rewrite middleware ", err)
 		return
 	}
-	handler.ServeHTTP(rw, r)
+	handler.ServeHTTP(&permanentRedirectWriter{ResponseWriter: rw}, r)
+}
+
+// permanentRedirectWriter turns the temporary redirections into permanent ones
+type permanentRedirectWriter struct {
+	http.ResponseWriter
+	redirected bool
+}
+
+func (prw *permanentRedirectWriter) WriteHeader(code int) {
+	if code == http.StatusFound {
+		prw.redirected = true
+		code = http.StatusMovedPermanently
+	}
+	prw.ResponseWriter.WriteHeader(code)
+}
+
+func (prw *permanentRedirectWriter) Write(b []byte) (int, error) {
+	if prw.redirected {
+		return prw.ResponseWriter.Write([]byte(http.StatusText(http.StatusMovedPermanently)))
+	}
+	return prw.ResponseWriter.Write(b)
 }
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedirect(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		url              string
+		permanent        bool
+		backendCode      int
+		expectedCode     int
+		expectedLocation string
+	}{
+		{
+			desc:             "temporary redirect",
+			url:              "http://www.example.com/foo",
+			expectedCode:     http.StatusFound,
+			expectedLocation: "http://example.com/foo",
+		},
+		{
+			desc:             "permanent redirect",
+			url:              "http://www.example.com/foo",
+			permanent:        true,
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "http://example.com/foo",
+		},
+		{
+			desc:         "no match",
+			url:          "http://example.com/foo",
+			permanent:    true,
+			backendCode:  http.StatusOK,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "backend redirect left untouched",
+			url:          "http://example.com/foo",
+			permanent:    true,
+			backendCode:  http.StatusFound,
+			expectedCode: http.StatusFound,
+		},
+	}
+
+	for _, test := range testCases {
+		redirect, err := NewRedirect("^http://www\\.(.*)$", "http://$1", test.permanent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := negroni.New(redirect)
+		backendCode := test.backendCode
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(backendCode)
+		})
+
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.RequestURI = req.URL.RequestURI()
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, req)
+
+		assert.Equal(t, test.expectedCode, rw.Code, test.desc)
+		assert.Equal(t, test.expectedLocation, rw.Header().Get("Location"), test.desc)
+	}
+}
@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+const (
+	stsHeader            = "Strict-Transport-Security"
+	frameOptionsHeader   = "X-Frame-Options"
+	contentTypeHeader    = "X-Content-Type-Options"
+	xssProtectionHeader  = "X-XSS-Protection"
+	cspHeader            = "Content-Security-Policy"
+	referrerPolicyHeader = "Referrer-Policy"
+)
+
+// Secure is a middleware that enforces security related headers,
+// allowed hosts and SSL redirection for a frontend
+type Secure struct {
+	headers *types.Headers
+}
+
+// NewSecure constructs a new Secure instance from the supplied frontend headers.
+// It returns nil when no secure header is defined.
+func NewSecure(headers *types.Headers) *Secure {
+	if !headers.HasSecureHeadersDefined() {
+		return nil
+	}
+	return &Secure{headers: headers}
+}
+
+func (s *Secure) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !s.headers.IsDevelopment {
+		if !s.isAllowedHost(r) {
+			log.Debugf("Host %s is not allowed", r.Host)
+			http.Error(w, "Bad Host", http.StatusBadRequest)
+			return
+		}
+
+		if (s.headers.SSLRedirect || s.headers.SSLTemporaryRedirect) && !s.isSSL(r) {
+			s.redirectToSSL(w, r)
+			return
+		}
+	}
+
+	isSSL := s.isSSL(r)
+	w = &headerResponseWriter{rw: w, modify: func(header http.Header) {
+		s.ModifyResponseHeaders(header, isSSL)
+	}}
+	next.ServeHTTP(w, r)
+}
+
+// ModifyResponseHeaders sets the security headers on the response
+func (s *Secure) ModifyResponseHeaders(header http.Header, isSSL bool) {
+	if s.headers.STSSeconds != 0 && (isSSL || s.headers.ForceSTSHeader) && !s.headers.IsDevelopment {
+		stsSub := ""
+		if s.headers.STSIncludeSubdomains {
+			stsSub = "; includeSubdomains"
+		}
+		if s.headers.STSPreload {
+			stsSub += "; preload"
+		}
+		header.Set(stsHeader, fmt.Sprintf("max-age=%d%s", s.headers.STSSeconds, stsSub))
+	}
+
+	if len(s.headers.CustomFrameOptionsValue) > 0 {
+		header.Set(frameOptionsHeader, s.headers.CustomFrameOptionsValue)
+	} else if s.headers.FrameDeny {
+		header.Set(frameOptionsHeader, "DENY")
+	}
+
+	if s.headers.ContentTypeNosniff {
+		header.Set(contentTypeHeader, "nosniff")
+	}
+
+	if s.headers.BrowserXSSFilter {
+		header.Set(xssProtectionHeader, "1; mode=block")
+	}
+
+	if len(s.headers.ContentSecurityPolicy) > 0 {
+		header.Set(cspHeader, s.headers.ContentSecurityPolicy)
+	}
+
+	if len(s.headers.ReferrerPolicy) > 0 {
+		header.Set(referrerPolicyHeader, s.headers.ReferrerPolicy)
+	}
+}
+
+func (s *Secure) requestHost(r *http.Request) string {
+	for _, header := range s.headers.HostsProxyHeaders {
+		if host := r.Header.Get(header); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+func (s *Secure) isAllowedHost(r *http.Request) bool {
+	if len(s.headers.AllowedHosts) == 0 {
+		return true
+	}
+	host := s.requestHost(r)
+	for _, allowedHost := range s.headers.AllowedHosts {
+		if strings.EqualFold(allowedHost, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Secure) isSSL(r *http.Request) bool {
+	if r.TLS != nil || strings.EqualFold(r.URL.Scheme, "https") {
+		return true
+	}
+	for header, value := range s.headers.SSLProxyHeaders {
+		if r.Header.Get(header) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Secure) redirectToSSL(w http.ResponseWriter, r *http.Request) {
+	host := s.requestHost(r)
+	if len(s.headers.SSLHost) > 0 {
+		host = s.headers.SSLHost
+	}
+
+	status := http.StatusMovedPermanently
+	if s.headers.SSLTemporaryRedirect {
+		status = http.StatusFound
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+}
@@ -0,0 +1,184 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSecureTestHandler(headers *types.Headers) http.Handler {
+	n := negroni.New(NewSecure(headers))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bar"))
+	})
+	return n
+}
+
+func TestNewSecureWithoutHeaders(t *testing.T) {
+	assert.Nil(t, NewSecure(nil))
+	assert.Nil(t, NewSecure(&types.Headers{CustomRequestHeaders: map[string]string{"X-Foo": "bar"}}))
+}
+
+func TestSecureResponseHeaders(t *testing.T) {
+	handler := newSecureTestHandler(&types.Headers{
+		STSSeconds:            31536000,
+		STSIncludeSubdomains:  true,
+		STSPreload:            true,
+		FrameDeny:             true,
+		ContentTypeNosniff:    true,
+		BrowserXSSFilter:      true,
+		ContentSecurityPolicy: "default-src 'self'",
+		ReferrerPolicy:        "same-origin",
+	})
+
+	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
+	req.TLS = &tls.ConnectionState{}
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "max-age=31536000; includeSubdomains; preload", rw.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", rw.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", rw.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "1; mode=block", rw.Header().Get("X-XSS-Protection"))
+	assert.Equal(t, "default-src 'self'", rw.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "same-origin", rw.Header().Get("Referrer-Policy"))
+}
+
+func TestSecureSTSHeader(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		headers  *types.Headers
+		tls      bool
+		expected string
+	}{
+		{
+			desc:     "no STS header over plain HTTP",
+			headers:  &types.Headers{STSSeconds: 60},
+			expected: "",
+		},
+		{
+			desc:     "STS header over TLS",
+			headers:  &types.Headers{STSSeconds: 60},
+			tls:      true,
+			expected: "max-age=60",
+		},
+		{
+			desc:     "forced STS header over plain HTTP",
+			headers:  &types.Headers{STSSeconds: 60, ForceSTSHeader: true},
+			expected: "max-age=60",
+		},
+		{
+			desc:     "no STS header in development mode",
+			headers:  &types.Headers{STSSeconds: 60, IsDevelopment: true},
+			tls:      true,
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rw := httptest.NewRecorder()
+		newSecureTestHandler(test.headers).ServeHTTP(rw, req)
+
+		assert.Equal(t, test.expected, rw.Header().Get("Strict-Transport-Security"), test.desc)
+	}
+}
+
+func TestSecureCustomFrameOptions(t *testing.T) {
+	handler := newSecureTestHandler(&types.Headers{
+		FrameDeny:               true,
+		CustomFrameOptionsValue: "SAMEORIGIN",
+	})
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest("GET", "http://example.com/foo", nil))
+
+	assert.Equal(t, "SAMEORIGIN", rw.Header().Get("X-Frame-Options"))
+}
+
+func TestSecureAllowedHosts(t *testing.T) {
+	handler := newSecureTestHandler(&types.Headers{
+		AllowedHosts:      []string{"example.com"},
+		HostsProxyHeaders: []string{"X-Forwarded-Host"},
+	})
+
+	testCases := []struct {
+		desc          string
+		host          string
+		forwardedHost string
+		expected      int
+	}{
+		{desc: "allowed host", host: "example.com", expected: http.StatusOK},
+		{desc: "allowed host with different case", host: "EXAMPLE.com", expected: http.StatusOK},
+		{desc: "unknown host", host: "evil.com", expected: http.StatusBadRequest},
+		{desc: "allowed forwarded host", host: "internal", forwardedHost: "example.com", expected: http.StatusOK},
+		{desc: "unknown forwarded host", host: "example.com", forwardedHost: "evil.com", expected: http.StatusBadRequest},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.Host = test.host
+		if test.forwardedHost != "" {
+			req.Header.Set("X-Forwarded-Host", test.forwardedHost)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+
+		assert.Equal(t, test.expected, rw.Code, test.desc)
+	}
+}
+
+func TestSecureSSLRedirect(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		headers          *types.Headers
+		requestHeaders   map[string]string
+		expectedCode     int
+		expectedLocation string
+	}{
+		{
+			desc:             "permanent redirect",
+			headers:          &types.Headers{SSLRedirect: true},
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "https://example.com/foo?bar=baz",
+		},
+		{
+			desc:             "temporary redirect to SSL host",
+			headers:          &types.Headers{SSLTemporaryRedirect: true, SSLHost: "secure.example.com"},
+			expectedCode:     http.StatusFound,
+			expectedLocation: "https://secure.example.com/foo?bar=baz",
+		},
+		{
+			desc:           "no redirect when SSL is terminated upstream",
+			headers:        &types.Headers{SSLRedirect: true, SSLProxyHeaders: map[string]string{"X-Forwarded-Proto": "https"}},
+			requestHeaders: map[string]string{"X-Forwarded-Proto": "https"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			desc:         "no redirect in development mode",
+			headers:      &types.Headers{SSLRedirect: true, IsDevelopment: true},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest("GET", "http://example.com/foo?bar=baz", nil)
+		for name, value := range test.requestHeaders {
+			req.Header.Set(name, value)
+		}
+		rw := httptest.NewRecorder()
+		newSecureTestHandler(test.headers).ServeHTTP(rw, req)
+
+		assert.Equal(t, test.expectedCode, rw.Code, test.desc)
+		assert.Equal(t, test.expectedLocation, rw.Header().Get("Location"), test.desc)
+	}
+}
@@ -22,6 +22,8 @@ import (
 const (
 	// DefaultWatchWaitTime is the duration to wait when polling consul
 	DefaultWatchWaitTime = 15 * time.Second
+
+	frontendHeadersAttribute = "frontend.headers."
 )
 
 var _ provider.Provider = (*CatalogProvider)(nil)
@@ -234,6 +236,12 @@ func (p *CatalogProvider) buildConfig(catalog []catalogUpdate) *types.Configurat
 		"getAttribute":         p.getAttribute,
 		"getEntryPoints":       p.getEntryPoints,
 		"hasMaxconnAttributes": p.hasMaxconnAttributes,
+		"hasHeaders":           p.hasHeaders,
+		"getHeaderString":      p.getHeaderString,
+		"getHeaderBool":        p.getHeaderBool,
+		"getHeaderInt64":       p.getHeaderInt64,
+		"getHeaderList":        p.getHeaderList,
+		"getHeaderMap":         p.getHeaderMap,
 	}
 
 	allNodes := []*api.ServiceEntry{}
@@ -277,6 +285,44 @@ func (p *CatalogProvider) hasMaxconnAttributes(attributes []string) bool {
 	return false
 }
 
+func (p *CatalogProvider) hasHeaders(attributes []string) bool {
+	for _, tag := range attributes {
+		if strings.HasPrefix(strings.ToLower(tag), strings.ToLower(p.Prefix+"."+frontendHeadersAttribute)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CatalogProvider) getHeaderString(name string, attributes []string) string {
+	return p.getAttribute(frontendHeadersAttribute+name, attributes, "")
+}
+
+func (p *CatalogProvider) getHeaderBool(name string, attributes []string) bool {
+	return p.getAttribute(frontendHeadersAttribute+name, attributes, "false") == "true"
+}
+
+func (p *CatalogProvider) getHeaderInt64(name string, attributes []string) int64 {
+	value := p.getAttribute(frontendHeadersAttribute+name, attributes, "")
+	if value == "" {
+		return 0
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Errorf("Unable to parse %s%s %s: %s", frontendHeadersAttribute, name, value, err)
+		return 0
+	}
+	return i
+}
+
+func (p *CatalogProvider) getHeaderList(name string, attributes []string) []string {
+	return provider.SplitAndTrim(p.getAttribute(frontendHeadersAttribute+name, attributes, ""))
+}
+
+func (p *CatalogProvider) getHeaderMap(name string, attributes []string) map[string]string {
+	return provider.ParseHeaders(p.getAttribute(frontendHeadersAttribute+name, attributes, ""))
+}
+
 func (p *CatalogProvider) getNodes(index map[string][]string) ([]catalogUpdate, error) {
 	visited := make(map[string]bool)
 
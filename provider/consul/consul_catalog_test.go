@@ -274,6 +274,72 @@ func TestConsulCatalogBuildConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			nodes: []catalogUpdate{
+				{
+					Service: &serviceUpdate{
+						ServiceName: "test",
+						Attributes: []string{
+							"traefik.frontend.headers.customRequestHeaders=X-Forwarded-Proto:https",
+							"traefik.frontend.headers.customResponseHeaders=X-Custom-Response-Header:foo||X-Frame-Options:DENY",
+							"traefik.frontend.headers.removeResponseHeaders=Server",
+							"traefik.frontend.headers.SSLTemporaryRedirect=true",
+							"traefik.frontend.headers.SSLHost=secure.example.com",
+							"traefik.frontend.headers.contentTypeNosniff=true",
+							"traefik.frontend.headers.browserXSSFilter=true",
+						},
+					},
+					Nodes: []*api.ServiceEntry{
+						{
+							Service: &api.AgentService{
+								Service: "test",
+								Address: "127.0.0.1",
+								Port:    80,
+							},
+							Node: &api.Node{
+								Node:    "localhost",
+								Address: "127.0.0.1",
+							},
+						},
+					},
+				},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-test": {
+					Backend:        "backend-test",
+					PassHostHeader: true,
+					Routes: map[string]types.Route{
+						"route-host-test": {
+							Rule: "Host:test.localhost",
+						},
+					},
+					Headers: &types.Headers{
+						CustomRequestHeaders: map[string]string{
+							"X-Forwarded-Proto": "https",
+						},
+						CustomResponseHeaders: map[string]string{
+							"X-Custom-Response-Header": "foo",
+							"X-Frame-Options":          "DENY",
+						},
+						RemoveResponseHeaders: []string{"Server"},
+						SSLTemporaryRedirect:  true,
+						SSLHost:               "secure.example.com",
+						ContentTypeNosniff:    true,
+						BrowserXSSFilter:      true,
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"test--127-0-0-1--80--0": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
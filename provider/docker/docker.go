@@ -37,6 +37,24 @@ const (
 	SwarmAPIVersion string = "1.24"
 	// SwarmDefaultWatchTime is the duration of the interval when polling docker
 	SwarmDefaultWatchTime = 15 * time.Second
+
+	labelFrontendHeaders = "traefik.frontend.headers."
+
+	labelFrontendRateLimitExtractorFunc = "traefik.frontend.rateLimit.extractorFunc"
+	labelFrontendRateLimitRateSet       = "traefik.frontend.rateLimit.rateSet."
+
+	labelFrontendRedirectEntryPoint  = "traefik.frontend.redirect.entryPoint"
+	labelFrontendRedirectRegex       = "traefik.frontend.redirect.regex"
+	labelFrontendRedirectReplacement = "traefik.frontend.redirect.replacement"
+	labelFrontendRedirectPermanent   = "traefik.frontend.redirect.permanent"
+
+	labelFrontendBufferingMaxRequestBodyBytes  = "traefik.frontend.buffering.maxRequestBodyBytes"
+	labelFrontendBufferingMemRequestBodyBytes  = "traefik.frontend.buffering.memRequestBodyBytes"
+	labelFrontendBufferingMaxResponseBodyBytes = "traefik.frontend.buffering.maxResponseBodyBytes"
+	labelFrontendBufferingMemResponseBodyBytes = "traefik.frontend.buffering.memResponseBodyBytes"
+	labelFrontendBufferingRetryExpression      = "traefik.frontend.buffering.retryExpression"
+
+	labelBackendLoadBalancerStickiness = "traefik.backend.loadbalancer.stickiness."
 )
 
 var _ provider.Provider = (*Provider)(nil)
@@ -241,37 +259,52 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 
 func (p *Provider) loadDockerConfig(containersInspected []dockerData) *types.Configuration {
 	var DockerFuncMap = template.FuncMap{
-		"getBackend":                  p.getBackend,
-		"getIPAddress":                p.getIPAddress,
-		"getPort":                     p.getPort,
-		"getWeight":                   p.getWeight,
-		"getDomain":                   p.getDomain,
-		"getProtocol":                 p.getProtocol,
-		"getPassHostHeader":           p.getPassHostHeader,
-		"getPriority":                 p.getPriority,
-		"getEntryPoints":              p.getEntryPoints,
-		"getBasicAuth":                p.getBasicAuth,
-		"getFrontendRule":             p.getFrontendRule,
-		"hasCircuitBreakerLabel":      p.hasCircuitBreakerLabel,
-		"getCircuitBreakerExpression": p.getCircuitBreakerExpression,
-		"hasLoadBalancerLabel":        p.hasLoadBalancerLabel,
-		"getLoadBalancerMethod":       p.getLoadBalancerMethod,
-		"hasMaxConnLabels":            p.hasMaxConnLabels,
-		"getMaxConnAmount":            p.getMaxConnAmount,
-		"getMaxConnExtractorFunc":     p.getMaxConnExtractorFunc,
-		"getSticky":                   p.getSticky,
-		"getIsBackendLBSwarm":         p.getIsBackendLBSwarm,
-		"hasServices":                 p.hasServices,
-		"getServiceNames":             p.getServiceNames,
-		"getServicePort":              p.getServicePort,
-		"getServiceWeight":            p.getServiceWeight,
-		"getServiceProtocol":          p.getServiceProtocol,
-		"getServiceEntryPoints":       p.getServiceEntryPoints,
-		"getServiceBasicAuth":         p.getServiceBasicAuth,
-		"getServiceFrontendRule":      p.getServiceFrontendRule,
-		"getServicePassHostHeader":    p.getServicePassHostHeader,
-		"getServicePriority":          p.getServicePriority,
-		"getServiceBackend":           p.getServiceBackend,
+		"getBackend":                     p.getBackend,
+		"getIPAddress":                   p.getIPAddress,
+		"getPort":                        p.getPort,
+		"getWeight":                      p.getWeight,
+		"getDomain":                      p.getDomain,
+		"getProtocol":                    p.getProtocol,
+		"getPassHostHeader":              p.getPassHostHeader,
+		"getPriority":                    p.getPriority,
+		"getEntryPoints":                 p.getEntryPoints,
+		"getBasicAuth":                   p.getBasicAuth,
+		"getWhitelistSourceRange":        p.getWhitelistSourceRange,
+		"getFrontendRule":                p.getFrontendRule,
+		"hasHeaders":                     p.hasHeaders,
+		"getHeaderString":                p.getHeaderString,
+		"getHeaderBool":                  p.getHeaderBool,
+		"getHeaderInt64":                 p.getHeaderInt64,
+		"getHeaderList":                  p.getHeaderList,
+		"getHeaderMap":                   p.getHeaderMap,
+		"hasCircuitBreakerLabel":         p.hasCircuitBreakerLabel,
+		"getCircuitBreakerExpression":    p.getCircuitBreakerExpression,
+		"hasLoadBalancerLabel":           p.hasLoadBalancerLabel,
+		"getLoadBalancerMethod":          p.getLoadBalancerMethod,
+		"getLoadBalancerHashKey":         p.getLoadBalancerHashKey,
+		"getStickiness":                  p.getStickiness,
+		"hasMaxConnLabels":               p.hasMaxConnLabels,
+		"hasRateLimitLabels":             p.hasRateLimitLabels,
+		"getRateLimitsExtractorFunc":     p.getRateLimitsExtractorFunc,
+		"getRateLimits":                  p.getRateLimits,
+		"getRedirect":                    p.getRedirect,
+		"getBuffering":                   p.getBuffering,
+		"getMaxConnAmount":               p.getMaxConnAmount,
+		"getMaxConnExtractorFunc":        p.getMaxConnExtractorFunc,
+		"getSticky":                      p.getSticky,
+		"getIsBackendLBSwarm":            p.getIsBackendLBSwarm,
+		"hasServices":                    p.hasServices,
+		"getServiceNames":                p.getServiceNames,
+		"getServicePort":                 p.getServicePort,
+		"getServiceWeight":               p.getServiceWeight,
+		"getServiceProtocol":             p.getServiceProtocol,
+		"getServiceEntryPoints":          p.getServiceEntryPoints,
+		"getServiceBasicAuth":            p.getServiceBasicAuth,
+		"getServiceWhitelistSourceRange": p.getServiceWhitelistSourceRange,
+		"getServiceFrontendRule":         p.getServiceFrontendRule,
+		"getServicePassHostHeader":       p.getServicePassHostHeader,
+		"getServicePriority":             p.getServicePriority,
+		"getServiceBackend":              p.getServiceBackend,
 	}
 	// filter containers
 	filteredContainers := fun.Filter(func(container dockerData) bool {
@@ -388,6 +421,14 @@ func (p *Provider) getServiceBasicAuth(container dockerData, serviceName string)
 
 }
 
+// Extract whitelistSourceRange from labels for a given service and a given docker container
+func (p *Provider) getServiceWhitelistSourceRange(container dockerData, serviceName string) []string {
+	if whitelistSourceRange, ok := getContainerServiceLabel(container, serviceName, "frontend.whitelistSourceRange"); ok {
+		return provider.SplitAndTrim(whitelistSourceRange)
+	}
+	return p.getWhitelistSourceRange(container)
+}
+
 // Extract passHostHeader from labels for a given service and a given docker container
 func (p *Provider) getServicePassHostHeader(container dockerData, serviceName string) string {
 	if servicePassHostHeader, ok := getContainerServiceLabel(container, serviceName, "frontend.passHostHeader"); ok {
@@ -449,7 +490,7 @@ func (p *Provider) getServiceProtocol(container dockerData, serviceName string)
 func (p *Provider) hasLoadBalancerLabel(container dockerData) bool {
 	_, errMethod := getLabel(container, "traefik.backend.loadbalancer.method")
 	_, errSticky := getLabel(container, "traefik.backend.loadbalancer.sticky")
-	if errMethod != nil && errSticky != nil {
+	if errMethod != nil && errSticky != nil && p.getStickiness(container) == nil {
 		return false
 	}
 	return true
@@ -465,6 +506,99 @@ func (p *Provider) hasMaxConnLabels(container dockerData) bool {
 	return true
 }
 
+func (p *Provider) hasRateLimitLabels(container dockerData) bool {
+	for key := range container.Labels {
+		if strings.HasPrefix(key, labelFrontendRateLimitRateSet) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) getRateLimitsExtractorFunc(container dockerData) string {
+	if label, err := getLabel(container, labelFrontendRateLimitExtractorFunc); err == nil {
+		return label
+	}
+	return "client.ip"
+}
+
+// getRateLimits parses the traefik.frontend.rateLimit.rateSet.<name>.{period,average,burst} labels
+func (p *Provider) getRateLimits(container dockerData) map[string]*types.Rate {
+	rateSet := make(map[string]*types.Rate)
+	for key, value := range container.Labels {
+		if !strings.HasPrefix(key, labelFrontendRateLimitRateSet) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(key, labelFrontendRateLimitRateSet), ".", 2)
+		if len(parts) != 2 {
+			log.Errorf("Invalid rate limit label %s", key)
+			continue
+		}
+		rate, ok := rateSet[parts[0]]
+		if !ok {
+			rate = &types.Rate{}
+			rateSet[parts[0]] = rate
+		}
+		var err error
+		switch parts[1] {
+		case "period":
+			err = rate.Period.Set(value)
+		case "average":
+			rate.Average, err = strconv.ParseInt(value, 10, 64)
+		case "burst":
+			rate.Burst, err = strconv.ParseInt(value, 10, 64)
+		default:
+			err = errors.New("unknown rate limit property")
+		}
+		if err != nil {
+			log.Errorf("Unable to parse %s %s: %s", key, value, err)
+		}
+	}
+	return rateSet
+}
+
+// getRedirect parses the traefik.frontend.redirect.{entryPoint,regex,replacement,permanent} labels
+func (p *Provider) getRedirect(container dockerData) *types.Redirect {
+	redirect := &types.Redirect{}
+	redirect.EntryPoint, _ = getLabel(container, labelFrontendRedirectEntryPoint)
+	redirect.Regex, _ = getLabel(container, labelFrontendRedirectRegex)
+	redirect.Replacement, _ = getLabel(container, labelFrontendRedirectReplacement)
+	if len(redirect.EntryPoint) == 0 && (len(redirect.Regex) == 0 || len(redirect.Replacement) == 0) {
+		return nil
+	}
+	if label, err := getLabel(container, labelFrontendRedirectPermanent); err == nil {
+		redirect.Permanent, err = strconv.ParseBool(label)
+		if err != nil {
+			log.Errorf("Unable to parse %s %s: %s", labelFrontendRedirectPermanent, label, err)
+		}
+	}
+	return redirect
+}
+
+// getBuffering parses the traefik.frontend.buffering.* labels
+func (p *Provider) getBuffering(container dockerData) *types.Buffering {
+	buffering := &types.Buffering{}
+	sizes := map[string]*int64{
+		labelFrontendBufferingMaxRequestBodyBytes:  &buffering.MaxRequestBodyBytes,
+		labelFrontendBufferingMemRequestBodyBytes:  &buffering.MemRequestBodyBytes,
+		labelFrontendBufferingMaxResponseBodyBytes: &buffering.MaxResponseBodyBytes,
+		labelFrontendBufferingMemResponseBodyBytes: &buffering.MemResponseBodyBytes,
+	}
+	for label, size := range sizes {
+		if value, err := getLabel(container, label); err == nil {
+			*size, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				log.Errorf("Unable to parse %s %s: %s", label, value, err)
+			}
+		}
+	}
+	buffering.RetryExpression, _ = getLabel(container, labelFrontendBufferingRetryExpression)
+	if *buffering == (types.Buffering{}) {
+		return nil
+	}
+	return buffering
+}
+
 func (p *Provider) getCircuitBreakerExpression(container dockerData) string {
 	if label, err := getLabel(container, "traefik.backend.circuitbreaker.expression"); err == nil {
 		return label
@@ -479,6 +613,48 @@ func (p *Provider) getLoadBalancerMethod(container dockerData) string {
 	return "wrr"
 }
 
+func (p *Provider) getStickiness(container dockerData) *types.Stickiness {
+	hasStickiness := false
+	for key := range container.Labels {
+		if strings.HasPrefix(key, labelBackendLoadBalancerStickiness) {
+			hasStickiness = true
+		}
+	}
+	if !hasStickiness {
+		return nil
+	}
+
+	stickiness := &types.Stickiness{}
+	stickiness.CookieName, _ = getLabel(container, labelBackendLoadBalancerStickiness+"cookieName")
+	stickiness.SameSite, _ = getLabel(container, labelBackendLoadBalancerStickiness+"sameSite")
+	for name, value := range map[string]*bool{
+		"secure":     &stickiness.Secure,
+		"httpOnly":   &stickiness.HTTPOnly,
+		"hashServer": &stickiness.HashServer,
+	} {
+		if label, err := getLabel(container, labelBackendLoadBalancerStickiness+name); err == nil {
+			*value, err = strconv.ParseBool(label)
+			if err != nil {
+				log.Errorf("Unable to parse %s%s %s: %s", labelBackendLoadBalancerStickiness, name, label, err)
+			}
+		}
+	}
+	if label, err := getLabel(container, labelBackendLoadBalancerStickiness+"maxAge"); err == nil {
+		stickiness.MaxAge, err = strconv.Atoi(label)
+		if err != nil {
+			log.Errorf("Unable to parse %smaxAge %s: %s", labelBackendLoadBalancerStickiness, label, err)
+		}
+	}
+	return stickiness
+}
+
+func (p *Provider) getLoadBalancerHashKey(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.hashkey"); err == nil {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) getMaxConnAmount(container dockerData) int64 {
 	if label, err := getLabel(container, "traefik.backend.maxconn.amount"); err == nil {
 		i, errConv := strconv.ParseInt(label, 10, 64)
@@ -685,6 +861,62 @@ func (p *Provider) getBasicAuth(container dockerData) []string {
 	return []string{}
 }
 
+func (p *Provider) getWhitelistSourceRange(container dockerData) []string {
+	if whitelistSourceRange, err := getLabel(container, "traefik.frontend.whitelistSourceRange"); err == nil {
+		return provider.SplitAndTrim(whitelistSourceRange)
+	}
+	return []string{}
+}
+
+func (p *Provider) hasHeaders(container dockerData) bool {
+	for key := range container.Labels {
+		if strings.HasPrefix(key, labelFrontendHeaders) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) getHeaderString(container dockerData, name string) string {
+	if value, err := getLabel(container, labelFrontendHeaders+name); err == nil {
+		return value
+	}
+	return ""
+}
+
+func (p *Provider) getHeaderBool(container dockerData, name string) bool {
+	if value, err := getLabel(container, labelFrontendHeaders+name); err == nil {
+		return value == "true"
+	}
+	return false
+}
+
+func (p *Provider) getHeaderInt64(container dockerData, name string) int64 {
+	if value, err := getLabel(container, labelFrontendHeaders+name); err == nil {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Errorf("Unable to parse %s%s %s: %s", labelFrontendHeaders, name, value, err)
+			return 0
+		}
+		return i
+	}
+	return 0
+}
+
+func (p *Provider) getHeaderList(container dockerData, name string) []string {
+	if value, err := getLabel(container, labelFrontendHeaders+name); err == nil {
+		return provider.SplitAndTrim(value)
+	}
+	return []string{}
+}
+
+func (p *Provider) getHeaderMap(container dockerData, name string) map[string]string {
+	if value, err := getLabel(container, labelFrontendHeaders+name); err == nil {
+		return provider.ParseHeaders(value)
+	}
+	return map[string]string{}
+}
+
 func isContainerEnabled(container dockerData, exposedByDefault bool) bool {
 	return exposedByDefault && container.Labels["traefik.enable"] != "false" || container.Labels["traefik.enable"] == "true"
 }
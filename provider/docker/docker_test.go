@@ -5,7 +5,9 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/containous/flaeg"
 	"github.com/containous/traefik/types"
 	docker "github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/container"
@@ -962,6 +964,199 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.backend":                      "foobar",
+						"traefik.backend.loadbalancer.method":  "consistenthash",
+						"traefik.backend.loadbalancer.hashkey": "request.cookie.session",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:        "backend-foobar",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					BasicAuth:      []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method:  "consistenthash",
+						HashKey: "request.cookie.session",
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.backend": "foobar",
+						"traefik.backend.loadbalancer.stickiness.cookieName": "app",
+						"traefik.backend.loadbalancer.stickiness.secure":     "true",
+						"traefik.backend.loadbalancer.stickiness.httpOnly":   "true",
+						"traefik.backend.loadbalancer.stickiness.sameSite":   "strict",
+						"traefik.backend.loadbalancer.stickiness.maxAge":     "3600",
+						"traefik.backend.loadbalancer.stickiness.hashServer": "true",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:        "backend-foobar",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					BasicAuth:      []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+						Stickiness: &types.Stickiness{
+							CookieName: "app",
+							Secure:     true,
+							HTTPOnly:   true,
+							SameSite:   "strict",
+							MaxAge:     3600,
+							HashServer: true,
+						},
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.frontend.headers.customRequestHeaders":  "X-Forwarded-Proto:https||X-Script-Name:/app",
+						"traefik.frontend.headers.customResponseHeaders": "X-Custom-Response-Header:foo",
+						"traefik.frontend.headers.removeResponseHeaders": "Server, X-Powered-By",
+						"traefik.frontend.headers.allowedHosts":          "foo.com,bar.com",
+						"traefik.frontend.headers.SSLRedirect":           "true",
+						"traefik.frontend.headers.SSLProxyHeaders":       "X-Forwarded-Proto:https",
+						"traefik.frontend.headers.STSSeconds":            "31536000",
+						"traefik.frontend.headers.STSIncludeSubdomains":  "true",
+						"traefik.frontend.headers.frameDeny":             "true",
+						"traefik.frontend.headers.contentSecurityPolicy": "default-src 'self'",
+						"traefik.frontend.whitelistSourceRange":          "10.0.0.0/8, 192.168.0.1",
+						"traefik.frontend.rateLimit.extractorFunc":       "request.header.X-Client",
+						"traefik.frontend.rateLimit.rateSet.foo.period":  "6s",
+						"traefik.frontend.rateLimit.rateSet.foo.average": "12",
+						"traefik.frontend.rateLimit.rateSet.foo.burst":   "18",
+						"traefik.frontend.redirect.regex":                `^https?://www\.(.*)$`,
+						"traefik.frontend.redirect.replacement":          "https://$1",
+						"traefik.frontend.redirect.permanent":            "true",
+						"traefik.frontend.buffering.maxRequestBodyBytes": "10485760",
+						"traefik.frontend.buffering.retryExpression":     "IsNetworkError() && Attempts() <= 2",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:        "backend-test1",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					BasicAuth:      []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+					WhitelistSourceRange: []string{"10.0.0.0/8", "192.168.0.1"},
+					RateLimit: &types.RateLimit{
+						ExtractorFunc: "request.header.X-Client",
+						RateSet: map[string]*types.Rate{
+							"foo": {
+								Period:  flaeg.Duration(6 * time.Second),
+								Average: 12,
+								Burst:   18,
+							},
+						},
+					},
+					Redirect: &types.Redirect{
+						Regex:       `^https?://www\.(.*)$`,
+						Replacement: "https://$1",
+						Permanent:   true,
+					},
+					Buffering: &types.Buffering{
+						MaxRequestBodyBytes: 10485760,
+						RetryExpression:     "IsNetworkError() && Attempts() <= 2",
+					},
+					Headers: &types.Headers{
+						CustomRequestHeaders: map[string]string{
+							"X-Forwarded-Proto": "https",
+							"X-Script-Name":     "/app",
+						},
+						CustomResponseHeaders: map[string]string{
+							"X-Custom-Response-Header": "foo",
+						},
+						RemoveResponseHeaders: []string{"Server", "X-Powered-By"},
+						AllowedHosts:          []string{"foo.com", "bar.com"},
+						SSLRedirect:           true,
+						SSLProxyHeaders: map[string]string{
+							"X-Forwarded-Proto": "https",
+						},
+						STSSeconds:            31536000,
+						STSIncludeSubdomains:  true,
+						FrameDeny:             true,
+						ContentSecurityPolicy: "default-src 'self'",
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test1": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
 	}
 
 	for caseID, c := range cases {
@@ -314,6 +314,45 @@ func TestDockerGetServiceEntryPoints(t *testing.T) {
 	}
 }
 
+func TestDockerGetServiceWhitelistSourceRange(t *testing.T) {
+	provider := &Provider{}
+
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
+		{
+			container: containerJSON(),
+			expected:  []string{},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.whitelistSourceRange": "10.0.0.0/8, 192.168.0.1",
+			})),
+			expected: []string{"10.0.0.0/8", "192.168.0.1"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.whitelistSourceRange":           "10.0.0.0/8",
+				"traefik.myservice.frontend.whitelistSourceRange": "172.16.0.0/16",
+			})),
+			expected: []string{"172.16.0.0/16"},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			actual := provider.getServiceWhitelistSourceRange(dockerData, "myservice")
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Fatalf("expected %q, got %q for container %q", e.expected, actual, dockerData.Name)
+			}
+		})
+	}
+}
+
 func TestDockerLoadDockerServiceConfig(t *testing.T) {
 	cases := []struct {
 		containers        []docker.ContainerJSON
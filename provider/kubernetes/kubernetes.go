@@ -3,10 +3,12 @@ package kubernetes
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -18,6 +20,7 @@ import (
 	"github.com/containous/traefik/provider"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
+	"github.com/ghodss/yaml"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 	"k8s.io/client-go/pkg/util/intstr"
@@ -31,6 +34,47 @@ const (
 	ruleTypePathStrip          = "PathStrip"
 	ruleTypePath               = "Path"
 	ruleTypePathPrefix         = "PathPrefix"
+
+	annotationBackendStickinessCookieName = "traefik.backend.loadbalancer.stickiness.cookieName"
+	annotationBackendStickinessSecure     = "traefik.backend.loadbalancer.stickiness.secure"
+	annotationBackendStickinessHTTPOnly   = "traefik.backend.loadbalancer.stickiness.httpOnly"
+	annotationBackendStickinessSameSite   = "traefik.backend.loadbalancer.stickiness.sameSite"
+	annotationBackendStickinessMaxAge     = "traefik.backend.loadbalancer.stickiness.maxAge"
+	annotationBackendStickinessHashServer = "traefik.backend.loadbalancer.stickiness.hashServer"
+)
+
+const (
+	annotationKubernetesCustomRequestHeaders  = "ingress.kubernetes.io/custom-request-headers"
+	annotationKubernetesCustomResponseHeaders = "ingress.kubernetes.io/custom-response-headers"
+	annotationKubernetesRemoveRequestHeaders  = "ingress.kubernetes.io/remove-request-headers"
+	annotationKubernetesRemoveResponseHeaders = "ingress.kubernetes.io/remove-response-headers"
+	annotationKubernetesAllowedHosts          = "ingress.kubernetes.io/allowed-hosts"
+	annotationKubernetesProxyHeaders          = "ingress.kubernetes.io/proxy-headers"
+	annotationKubernetesSSLRedirect           = "ingress.kubernetes.io/ssl-redirect"
+	annotationKubernetesSSLTemporaryRedirect  = "ingress.kubernetes.io/ssl-temporary-redirect"
+	annotationKubernetesSSLHost               = "ingress.kubernetes.io/ssl-host"
+	annotationKubernetesSSLProxyHeaders       = "ingress.kubernetes.io/ssl-proxy-headers"
+	annotationKubernetesHSTSMaxAge            = "ingress.kubernetes.io/hsts-max-age"
+	annotationKubernetesHSTSIncludeSubdomains = "ingress.kubernetes.io/hsts-include-subdomains"
+	annotationKubernetesHSTSPreload           = "ingress.kubernetes.io/hsts-preload"
+	annotationKubernetesForceHSTSHeader       = "ingress.kubernetes.io/force-hsts"
+	annotationKubernetesFrameDeny             = "ingress.kubernetes.io/frame-deny"
+	annotationKubernetesCustomFrameOptions    = "ingress.kubernetes.io/custom-frame-options-value"
+	annotationKubernetesContentTypeNosniff    = "ingress.kubernetes.io/content-type-nosniff"
+	annotationKubernetesBrowserXSSFilter      = "ingress.kubernetes.io/browser-xss-filter"
+	annotationKubernetesContentSecurityPolicy = "ingress.kubernetes.io/content-security-policy"
+	annotationKubernetesReferrerPolicy        = "ingress.kubernetes.io/referrer-policy"
+	annotationKubernetesIsDevelopment         = "ingress.kubernetes.io/is-development"
+	annotationKubernetesWhitelistSourceRange  = "ingress.kubernetes.io/whitelist-source-range"
+	annotationKubernetesRateLimit             = "ingress.kubernetes.io/rate-limit"
+	annotationKubernetesBuffering             = "ingress.kubernetes.io/buffering"
+	annotationKubernetesRedirectEntryPoint    = "ingress.kubernetes.io/redirect-entry-point"
+	annotationKubernetesRedirectRegex         = "ingress.kubernetes.io/redirect-regex"
+	annotationKubernetesRedirectReplacement   = "ingress.kubernetes.io/redirect-replacement"
+	annotationKubernetesRedirectPermanent     = "ingress.kubernetes.io/redirect-permanent"
+	annotationKubernetesServiceWeights        = "ingress.kubernetes.io/service-weights"
+	annotationKubernetesServiceWeightsSticky  = "ingress.kubernetes.io/service-weights-sticky"
+	annotationKubernetesServiceWeightsCookie  = "ingress.kubernetes.io/service-weights-cookie-name"
 )
 
 const traefikDefaultRealm = "traefik"
@@ -128,9 +172,10 @@ func (p *Provider) loadIngresses(k8sClient Client) (*types.Configuration, error)
 	ingresses := k8sClient.GetIngresses(p.Namespaces)
 
 	templateObjects := types.Configuration{
-		map[string]*types.Backend{},
-		map[string]*types.Frontend{},
+		Backends:  map[string]*types.Backend{},
+		Frontends: map[string]*types.Frontend{},
 	}
+	tlsConfigurations := map[string]*types.TLSConfiguration{}
 	for _, i := range ingresses {
 		ingressClass := i.Annotations["kubernetes.io/ingress.class"]
 
@@ -138,19 +183,42 @@ func (p *Provider) loadIngresses(k8sClient Client) (*types.Configuration, error)
 			continue
 		}
 
+		if err := loadTLSConfigurations(i, k8sClient, tlsConfigurations); err != nil {
+			return nil, err
+		}
+
+		serviceWeights, err := getServiceWeights(i)
+		if err != nil {
+			return nil, err
+		}
+		rateLimit, err := getRateLimit(i)
+		if err != nil {
+			log.Errorf("Skipping ingress %s/%s: %v", i.Namespace, i.Name, err)
+			continue
+		}
+
 		for _, r := range i.Spec.Rules {
 			if r.HTTP == nil {
 				log.Warnf("Error in ingress: HTTP is nil")
 				continue
 			}
 			for _, pa := range r.HTTP.Paths {
-				if _, exists := templateObjects.Backends[r.Host+pa.Path]; !exists {
-					templateObjects.Backends[r.Host+pa.Path] = &types.Backend{
-						Servers: make(map[string]types.Server),
-						LoadBalancer: &types.LoadBalancer{
-							Sticky: false,
-							Method: "wrr",
-						},
+				backendServices := map[string]string{r.Host + pa.Path: pa.Backend.ServiceName}
+				if len(serviceWeights) > 0 {
+					backendServices = make(map[string]string)
+					for serviceName := range serviceWeights {
+						backendServices[weightedBackendName(r.Host+pa.Path, serviceName)] = serviceName
+					}
+				}
+				for backendName := range backendServices {
+					if _, exists := templateObjects.Backends[backendName]; !exists {
+						templateObjects.Backends[backendName] = &types.Backend{
+							Servers: make(map[string]types.Server),
+							LoadBalancer: &types.LoadBalancer{
+								Sticky: false,
+								Method: "wrr",
+							},
+						}
 					}
 				}
 
@@ -176,12 +244,25 @@ func (p *Provider) loadIngresses(k8sClient Client) (*types.Configuration, error)
 					if err != nil {
 						return nil, err
 					}
+					buffering, err := getBuffering(i)
+					if err != nil {
+						return nil, err
+					}
 					templateObjects.Frontends[r.Host+pa.Path] = &types.Frontend{
-						Backend:        r.Host + pa.Path,
-						PassHostHeader: PassHostHeader,
-						Routes:         make(map[string]types.Route),
-						Priority:       len(pa.Path),
-						BasicAuth:      basicAuthCreds,
+						Backend:              r.Host + pa.Path,
+						PassHostHeader:       PassHostHeader,
+						Routes:               make(map[string]types.Route),
+						Priority:             len(pa.Path),
+						BasicAuth:            basicAuthCreds,
+						WhitelistSourceRange: getWhitelistSourceRange(i),
+						Headers:              getHeaders(i),
+						RateLimit:            rateLimit,
+						Redirect:             getRedirect(i),
+						Buffering:            buffering,
+					}
+					if len(serviceWeights) > 0 {
+						templateObjects.Frontends[r.Host+pa.Path].Backend = ""
+						templateObjects.Frontends[r.Host+pa.Path].WeightedBackends = getWeightedBackends(i, r.Host+pa.Path, serviceWeights)
 					}
 				}
 				if len(r.Host) > 0 {
@@ -213,85 +294,318 @@ func (p *Provider) loadIngresses(k8sClient Client) (*types.Configuration, error)
 					}
 				}
 
-				service, exists, err := k8sClient.GetService(i.ObjectMeta.Namespace, pa.Backend.ServiceName)
+				for backendName, serviceName := range backendServices {
+					service, exists, err := k8sClient.GetService(i.ObjectMeta.Namespace, serviceName)
+					if err != nil {
+						log.Errorf("Error while retrieving service information from k8s API %s/%s: %v", i.ObjectMeta.Namespace, serviceName, err)
+						return nil, err
+					}
+
+					if !exists {
+						log.Errorf("Service not found for %s/%s", i.ObjectMeta.Namespace, serviceName)
+						delete(templateObjects.Frontends, r.Host+pa.Path)
+						break
+					}
+
+					if err := loadBackend(k8sClient, service, pa.Backend.ServicePort, templateObjects.Backends[backendName]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	templateObjects.TLS = sortedTLSConfigurations(tlsConfigurations)
+	return &templateObjects, nil
+}
+
+// loadTLSConfigurations adds the certificates of the secrets referenced by the
+// TLS section of the ingress, keyed by secret. The sections whose secret is
+// missing or invalid are skipped.
+func loadTLSConfigurations(i *v1beta1.Ingress, k8sClient Client, tlsConfigurations map[string]*types.TLSConfiguration) error {
+	for _, t := range i.Spec.TLS {
+		if t.SecretName == "" {
+			log.Debugf("Skipping TLS section without secret of ingress %s/%s", i.Namespace, i.Name)
+			continue
+		}
+		secretKey := i.Namespace + "/" + t.SecretName
+		if _, exists := tlsConfigurations[secretKey]; exists {
+			continue
+		}
+
+		secret, exists, err := k8sClient.GetSecret(i.Namespace, t.SecretName)
+		if err != nil {
+			return fmt.Errorf("failed to fetch secret %q: %s", secretKey, err)
+		}
+		if !exists {
+			log.Errorf("Secret %q not found, skipping TLS section of ingress %s/%s", secretKey, i.Namespace, i.Name)
+			continue
+		}
+		cert, key := secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey]
+		if len(cert) == 0 || len(key) == 0 {
+			log.Errorf("Secret %q must contain the %s and %s entries, skipping TLS section of ingress %s/%s", secretKey, v1.TLSCertKey, v1.TLSPrivateKeyKey, i.Namespace, i.Name)
+			continue
+		}
+		tlsConfigurations[secretKey] = &types.TLSConfiguration{
+			Certificate: &types.Certificate{
+				CertFile: string(cert),
+				KeyFile:  string(key),
+			},
+		}
+	}
+	return nil
+}
+
+// sortedTLSConfigurations returns the TLS configurations sorted by secret, so
+// that the configuration does not depend on the order of the ingresses
+func sortedTLSConfigurations(tlsConfigurations map[string]*types.TLSConfiguration) []*types.TLSConfiguration {
+	var keys []string
+	for key := range tlsConfigurations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sorted []*types.TLSConfiguration
+	for _, key := range keys {
+		sorted = append(sorted, tlsConfigurations[key])
+	}
+	return sorted
+}
+
+// loadBackend fills the backend with the annotations and the endpoints of the
+// service
+func loadBackend(k8sClient Client, service *v1.Service, servicePort intstr.IntOrString, backend *types.Backend) error {
+	if expression := service.Annotations["traefik.backend.circuitbreaker"]; expression != "" {
+		backend.CircuitBreaker = &types.CircuitBreaker{
+			Expression: expression,
+		}
+	}
+	if method := service.Annotations["traefik.backend.loadbalancer.method"]; method != "" {
+		if _, err := types.NewLoadBalancerMethod(&types.LoadBalancer{Method: method}); err == nil {
+			backend.LoadBalancer.Method = method
+		} else {
+			log.Errorf("Invalid load-balancer method %q on service %s/%s, using wrr", method, service.ObjectMeta.Namespace, service.ObjectMeta.Name)
+		}
+	}
+	if hashKey := service.Annotations["traefik.backend.loadbalancer.hashkey"]; hashKey != "" {
+		backend.LoadBalancer.HashKey = hashKey
+	}
+	backend.LoadBalancer.Stickiness = getStickiness(service)
+	if service.Annotations["traefik.backend.loadbalancer.sticky"] == "true" {
+		backend.LoadBalancer.Sticky = true
+	}
+
+	protocol := "http"
+	for _, port := range service.Spec.Ports {
+		if equalPorts(port, servicePort) {
+			if port.Port == 443 {
+				protocol = "https"
+			}
+			if service.Spec.Type == "ExternalName" {
+				url := protocol + "://" + service.Spec.ExternalName
+				name := url
+
+				backend.Servers[name] = types.Server{
+					URL:    url,
+					Weight: 1,
+				}
+			} else {
+				endpoints, exists, err := k8sClient.GetEndpoints(service.ObjectMeta.Namespace, service.ObjectMeta.Name)
 				if err != nil {
-					log.Errorf("Error while retrieving service information from k8s API %s/%s: %v", i.ObjectMeta.Namespace, pa.Backend.ServiceName, err)
-					return nil, err
+					log.Errorf("Error retrieving endpoints %s/%s: %v", service.ObjectMeta.Namespace, service.ObjectMeta.Name, err)
+					return err
 				}
 
 				if !exists {
-					log.Errorf("Service not found for %s/%s", i.ObjectMeta.Namespace, pa.Backend.ServiceName)
-					delete(templateObjects.Frontends, r.Host+pa.Path)
+					log.Errorf("Endpoints not found for %s/%s", service.ObjectMeta.Namespace, service.ObjectMeta.Name)
 					continue
 				}
 
-				if expression := service.Annotations["traefik.backend.circuitbreaker"]; expression != "" {
-					templateObjects.Backends[r.Host+pa.Path].CircuitBreaker = &types.CircuitBreaker{
-						Expression: expression,
+				if len(endpoints.Subsets) == 0 {
+					log.Warnf("Service endpoints not found for %s/%s, falling back to Service ClusterIP", service.ObjectMeta.Namespace, service.ObjectMeta.Name)
+					backend.Servers[string(service.UID)] = types.Server{
+						URL:    protocol + "://" + service.Spec.ClusterIP + ":" + strconv.Itoa(int(port.Port)),
+						Weight: 1,
 					}
-				}
-				if service.Annotations["traefik.backend.loadbalancer.method"] == "drr" {
-					templateObjects.Backends[r.Host+pa.Path].LoadBalancer.Method = "drr"
-				}
-				if service.Annotations["traefik.backend.loadbalancer.sticky"] == "true" {
-					templateObjects.Backends[r.Host+pa.Path].LoadBalancer.Sticky = true
-				}
-
-				protocol := "http"
-				for _, port := range service.Spec.Ports {
-					if equalPorts(port, pa.Backend.ServicePort) {
-						if port.Port == 443 {
-							protocol = "https"
-						}
-						if service.Spec.Type == "ExternalName" {
-							url := protocol + "://" + service.Spec.ExternalName
+				} else {
+					for _, subset := range endpoints.Subsets {
+						for _, address := range subset.Addresses {
+							url := protocol + "://" + address.IP + ":" + strconv.Itoa(endpointPortNumber(port, subset.Ports))
 							name := url
-
-							templateObjects.Backends[r.Host+pa.Path].Servers[name] = types.Server{
+							if address.TargetRef != nil && address.TargetRef.Name != "" {
+								name = address.TargetRef.Name
+							}
+							backend.Servers[name] = types.Server{
 								URL:    url,
 								Weight: 1,
 							}
-						} else {
-							endpoints, exists, err := k8sClient.GetEndpoints(service.ObjectMeta.Namespace, service.ObjectMeta.Name)
-							if err != nil {
-								log.Errorf("Error retrieving endpoints %s/%s: %v", service.ObjectMeta.Namespace, service.ObjectMeta.Name, err)
-								return nil, err
-							}
-
-							if !exists {
-								log.Errorf("Endpoints not found for %s/%s", service.ObjectMeta.Namespace, service.ObjectMeta.Name)
-								continue
-							}
-
-							if len(endpoints.Subsets) == 0 {
-								log.Warnf("Service endpoints not found for %s/%s, falling back to Service ClusterIP", service.ObjectMeta.Namespace, service.ObjectMeta.Name)
-								templateObjects.Backends[r.Host+pa.Path].Servers[string(service.UID)] = types.Server{
-									URL:    protocol + "://" + service.Spec.ClusterIP + ":" + strconv.Itoa(int(port.Port)),
-									Weight: 1,
-								}
-							} else {
-								for _, subset := range endpoints.Subsets {
-									for _, address := range subset.Addresses {
-										url := protocol + "://" + address.IP + ":" + strconv.Itoa(endpointPortNumber(port, subset.Ports))
-										name := url
-										if address.TargetRef != nil && address.TargetRef.Name != "" {
-											name = address.TargetRef.Name
-										}
-										templateObjects.Backends[r.Host+pa.Path].Servers[name] = types.Server{
-											URL:    url,
-											Weight: 1,
-										}
-									}
-								}
-							}
 						}
-						break
 					}
 				}
 			}
+			break
 		}
 	}
-	return &templateObjects, nil
+	return nil
+}
+
+func getHeaders(i *v1beta1.Ingress) *types.Headers {
+	headers := &types.Headers{}
+	if value, ok := i.Annotations[annotationKubernetesCustomRequestHeaders]; ok {
+		headers.CustomRequestHeaders = provider.ParseHeaders(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesCustomResponseHeaders]; ok {
+		headers.CustomResponseHeaders = provider.ParseHeaders(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesRemoveRequestHeaders]; ok {
+		headers.RemoveRequestHeaders = provider.SplitAndTrim(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesRemoveResponseHeaders]; ok {
+		headers.RemoveResponseHeaders = provider.SplitAndTrim(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesAllowedHosts]; ok {
+		headers.AllowedHosts = provider.SplitAndTrim(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesProxyHeaders]; ok {
+		headers.HostsProxyHeaders = provider.SplitAndTrim(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesSSLProxyHeaders]; ok {
+		headers.SSLProxyHeaders = provider.ParseHeaders(value)
+	}
+	if value, ok := i.Annotations[annotationKubernetesHSTSMaxAge]; ok {
+		maxAge, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Errorf("Unable to parse %s annotation %q: %s", annotationKubernetesHSTSMaxAge, value, err)
+		}
+		headers.STSSeconds = maxAge
+	}
+	headers.SSLHost = i.Annotations[annotationKubernetesSSLHost]
+	headers.CustomFrameOptionsValue = i.Annotations[annotationKubernetesCustomFrameOptions]
+	headers.ContentSecurityPolicy = i.Annotations[annotationKubernetesContentSecurityPolicy]
+	headers.ReferrerPolicy = i.Annotations[annotationKubernetesReferrerPolicy]
+	headers.SSLRedirect = getBoolAnnotation(i, annotationKubernetesSSLRedirect)
+	headers.SSLTemporaryRedirect = getBoolAnnotation(i, annotationKubernetesSSLTemporaryRedirect)
+	headers.STSIncludeSubdomains = getBoolAnnotation(i, annotationKubernetesHSTSIncludeSubdomains)
+	headers.STSPreload = getBoolAnnotation(i, annotationKubernetesHSTSPreload)
+	headers.ForceSTSHeader = getBoolAnnotation(i, annotationKubernetesForceHSTSHeader)
+	headers.FrameDeny = getBoolAnnotation(i, annotationKubernetesFrameDeny)
+	headers.ContentTypeNosniff = getBoolAnnotation(i, annotationKubernetesContentTypeNosniff)
+	headers.BrowserXSSFilter = getBoolAnnotation(i, annotationKubernetesBrowserXSSFilter)
+	headers.IsDevelopment = getBoolAnnotation(i, annotationKubernetesIsDevelopment)
+
+	if !headers.HasCustomHeadersDefined() && !headers.HasSecureHeadersDefined() {
+		return nil
+	}
+	return headers
+}
+
+func getWhitelistSourceRange(i *v1beta1.Ingress) []string {
+	if value, ok := i.Annotations[annotationKubernetesWhitelistSourceRange]; ok {
+		return provider.SplitAndTrim(value)
+	}
+	return nil
+}
+
+func getRateLimit(i *v1beta1.Ingress) (*types.RateLimit, error) {
+	value, ok := i.Annotations[annotationKubernetesRateLimit]
+	if !ok {
+		return nil, nil
+	}
+	rateLimitJSON, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesRateLimit, err)
+	}
+	rateLimit := &types.RateLimit{}
+	if err := json.Unmarshal(rateLimitJSON, rateLimit); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesRateLimit, err)
+	}
+	return rateLimit, nil
+}
+
+func getBuffering(i *v1beta1.Ingress) (*types.Buffering, error) {
+	value, ok := i.Annotations[annotationKubernetesBuffering]
+	if !ok {
+		return nil, nil
+	}
+	bufferingJSON, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesBuffering, err)
+	}
+	buffering := &types.Buffering{}
+	if err := json.Unmarshal(bufferingJSON, buffering); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesBuffering, err)
+	}
+	return buffering, nil
+}
+
+func getRedirect(i *v1beta1.Ingress) *types.Redirect {
+	redirect := &types.Redirect{
+		EntryPoint:  i.Annotations[annotationKubernetesRedirectEntryPoint],
+		Regex:       i.Annotations[annotationKubernetesRedirectRegex],
+		Replacement: i.Annotations[annotationKubernetesRedirectReplacement],
+		Permanent:   getBoolAnnotation(i, annotationKubernetesRedirectPermanent),
+	}
+	if len(redirect.EntryPoint) == 0 && (len(redirect.Regex) == 0 || len(redirect.Replacement) == 0) {
+		return nil
+	}
+	return redirect
+}
+
+// getServiceWeights returns the weights of the services sharing the paths of
+// the ingress, nil when the requests go to the service of each path
+func getServiceWeights(i *v1beta1.Ingress) (map[string]int, error) {
+	value, ok := i.Annotations[annotationKubernetesServiceWeights]
+	if !ok {
+		return nil, nil
+	}
+	weightsJSON, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesServiceWeights, err)
+	}
+	weights := make(map[string]int)
+	if err := json.Unmarshal(weightsJSON, &weights); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", annotationKubernetesServiceWeights, err)
+	}
+	return weights, nil
+}
+
+func getWeightedBackends(i *v1beta1.Ingress, frontendName string, serviceWeights map[string]int) *types.WeightedBackends {
+	weightedBackends := &types.WeightedBackends{
+		Backends:   make(map[string]int),
+		Sticky:     getBoolAnnotation(i, annotationKubernetesServiceWeightsSticky),
+		CookieName: i.Annotations[annotationKubernetesServiceWeightsCookie],
+	}
+	for serviceName, weight := range serviceWeights {
+		weightedBackends.Backends[weightedBackendName(frontendName, serviceName)] = weight
+	}
+	return weightedBackends
+}
+
+func weightedBackendName(frontendName, serviceName string) string {
+	return frontendName + "@" + serviceName
+}
+
+func getStickiness(service *v1.Service) *types.Stickiness {
+	stickiness := &types.Stickiness{
+		CookieName: service.Annotations[annotationBackendStickinessCookieName],
+		Secure:     service.Annotations[annotationBackendStickinessSecure] == "true",
+		HTTPOnly:   service.Annotations[annotationBackendStickinessHTTPOnly] == "true",
+		SameSite:   service.Annotations[annotationBackendStickinessSameSite],
+		HashServer: service.Annotations[annotationBackendStickinessHashServer] == "true",
+	}
+	if maxAge, ok := service.Annotations[annotationBackendStickinessMaxAge]; ok {
+		var err error
+		stickiness.MaxAge, err = strconv.Atoi(maxAge)
+		if err != nil {
+			log.Errorf("Unable to parse %s %s on service %s/%s: %s", annotationBackendStickinessMaxAge, maxAge, service.ObjectMeta.Namespace, service.ObjectMeta.Name, err)
+		}
+	}
+	if *stickiness == (types.Stickiness{}) {
+		return nil
+	}
+	return stickiness
+}
+
+func getBoolAnnotation(i *v1beta1.Ingress, annotation string) bool {
+	return i.Annotations[annotation] == "true"
 }
 
 func handleBasicAuthConfig(i *v1beta1.Ingress, k8sClient Client) ([]string, error) {
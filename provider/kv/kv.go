@@ -142,8 +142,11 @@ func (p *Provider) loadConfig() *types.Configuration {
 	}
 
 	for key, frontend := range configuration.Frontends {
-		if _, ok := configuration.Backends[frontend.Backend]; ok == false {
-			delete(configuration.Frontends, key)
+		for _, backendName := range frontend.BackendNames() {
+			if _, ok := configuration.Backends[backendName]; ok == false {
+				delete(configuration.Frontends, key)
+				break
+			}
 		}
 	}
 
@@ -28,6 +28,8 @@ const (
 	labelPortIndex                  = "traefik.portIndex"
 	labelBackendHealthCheckPath     = "traefik.backend.healthcheck.path"
 	labelBackendHealthCheckInterval = "traefik.backend.healthcheck.interval"
+	labelFrontendHeaders            = "traefik.frontend.headers."
+	labelBackendStickiness          = "traefik.backend.loadbalancer.stickiness."
 )
 
 var _ provider.Provider = (*Provider)(nil)
@@ -151,12 +153,20 @@ func (p *Provider) loadMarathonConfig() *types.Configuration {
 		"getEntryPoints":              p.getEntryPoints,
 		"getFrontendRule":             p.getFrontendRule,
 		"getFrontendBackend":          p.getFrontendBackend,
+		"hasHeaders":                  p.hasHeaders,
+		"getHeaderString":             p.getHeaderString,
+		"getHeaderBool":               p.getHeaderBool,
+		"getHeaderInt64":              p.getHeaderInt64,
+		"getHeaderList":               p.getHeaderList,
+		"getHeaderMap":                p.getHeaderMap,
 		"hasCircuitBreakerLabels":     p.hasCircuitBreakerLabels,
 		"hasLoadBalancerLabels":       p.hasLoadBalancerLabels,
 		"hasMaxConnLabels":            p.hasMaxConnLabels,
 		"getMaxConnExtractorFunc":     p.getMaxConnExtractorFunc,
 		"getMaxConnAmount":            p.getMaxConnAmount,
 		"getLoadBalancerMethod":       p.getLoadBalancerMethod,
+		"getLoadBalancerHashKey":      p.getLoadBalancerHashKey,
+		"getStickiness":               p.getStickiness,
 		"getCircuitBreakerExpression": p.getCircuitBreakerExpression,
 		"getSticky":                   p.getSticky,
 		"hasHealthCheckLabels":        p.hasHealthCheckLabels,
@@ -374,6 +384,55 @@ func (p *Provider) getEntryPoints(application marathon.Application) []string {
 	return []string{}
 }
 
+func (p *Provider) hasHeaders(application marathon.Application) bool {
+	for key := range *application.Labels {
+		if strings.HasPrefix(key, labelFrontendHeaders) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) getHeaderString(application marathon.Application, name string) string {
+	if label, ok := p.getLabel(application, labelFrontendHeaders+name); ok {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getHeaderBool(application marathon.Application, name string) bool {
+	if label, ok := p.getLabel(application, labelFrontendHeaders+name); ok {
+		return label == "true"
+	}
+	return false
+}
+
+func (p *Provider) getHeaderInt64(application marathon.Application, name string) int64 {
+	if label, ok := p.getLabel(application, labelFrontendHeaders+name); ok {
+		i, errConv := strconv.ParseInt(label, 10, 64)
+		if errConv != nil {
+			log.Errorf("Unable to parse %s%s %s", labelFrontendHeaders, name, label)
+			return 0
+		}
+		return i
+	}
+	return 0
+}
+
+func (p *Provider) getHeaderList(application marathon.Application, name string) []string {
+	if label, ok := p.getLabel(application, labelFrontendHeaders+name); ok {
+		return provider.SplitAndTrim(label)
+	}
+	return []string{}
+}
+
+func (p *Provider) getHeaderMap(application marathon.Application, name string) map[string]string {
+	if label, ok := p.getLabel(application, labelFrontendHeaders+name); ok {
+		return provider.ParseHeaders(label)
+	}
+	return map[string]string{}
+}
+
 // getFrontendRule returns the frontend rule for the specified application, using
 // it's label. It returns a default one (Host) if the label is not present.
 func (p *Provider) getFrontendRule(application marathon.Application) string {
@@ -422,7 +481,7 @@ func (p *Provider) hasCircuitBreakerLabels(application marathon.Application) boo
 func (p *Provider) hasLoadBalancerLabels(application marathon.Application) bool {
 	_, errMethod := p.getLabel(application, "traefik.backend.loadbalancer.method")
 	_, errSticky := p.getLabel(application, "traefik.backend.loadbalancer.sticky")
-	return errMethod || errSticky
+	return errMethod || errSticky || p.getStickiness(application) != nil
 }
 
 func (p *Provider) hasMaxConnLabels(application marathon.Application) bool {
@@ -459,6 +518,50 @@ func (p *Provider) getLoadBalancerMethod(application marathon.Application) strin
 	return "wrr"
 }
 
+func (p *Provider) getStickiness(application marathon.Application) *types.Stickiness {
+	hasStickiness := false
+	for key := range *application.Labels {
+		if strings.HasPrefix(key, labelBackendStickiness) {
+			hasStickiness = true
+		}
+	}
+	if !hasStickiness {
+		return nil
+	}
+
+	stickiness := &types.Stickiness{}
+	stickiness.CookieName, _ = p.getLabel(application, labelBackendStickiness+"cookieName")
+	stickiness.SameSite, _ = p.getLabel(application, labelBackendStickiness+"sameSite")
+	for name, value := range map[string]*bool{
+		"secure":     &stickiness.Secure,
+		"httpOnly":   &stickiness.HTTPOnly,
+		"hashServer": &stickiness.HashServer,
+	} {
+		if label, ok := p.getLabel(application, labelBackendStickiness+name); ok {
+			var err error
+			*value, err = strconv.ParseBool(label)
+			if err != nil {
+				log.Errorf("Unable to parse %s%s %s", labelBackendStickiness, name, label)
+			}
+		}
+	}
+	if label, ok := p.getLabel(application, labelBackendStickiness+"maxAge"); ok {
+		var err error
+		stickiness.MaxAge, err = strconv.Atoi(label)
+		if err != nil {
+			log.Errorf("Unable to parse %smaxAge %s", labelBackendStickiness, label)
+		}
+	}
+	return stickiness
+}
+
+func (p *Provider) getLoadBalancerHashKey(application marathon.Application) string {
+	if label, ok := p.getLabel(application, "traefik.backend.loadbalancer.hashkey"); ok {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) getCircuitBreakerExpression(application marathon.Application) string {
 	if label, ok := p.getLabel(application, "traefik.backend.circuitbreaker.expression"); ok {
 		return label
@@ -177,6 +177,67 @@ func TestMarathonLoadConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			applications: &marathon.Applications{
+				Apps: []marathon.Application{
+					{
+						ID:    "/testStickiness",
+						Ports: []int{80},
+						Labels: &map[string]string{
+							"traefik.backend.loadbalancer.stickiness.cookieName": "app",
+							"traefik.backend.loadbalancer.stickiness.httpOnly":   "true",
+							"traefik.backend.loadbalancer.stickiness.maxAge":     "600",
+						},
+					},
+				},
+			},
+			tasks: &marathon.Tasks{
+				Tasks: []marathon.Task{
+					{
+						ID:    "testStickiness",
+						AppID: "/testStickiness",
+						Host:  "localhost",
+						Ports: []int{80},
+						IPAddresses: []*marathon.IPAddress{
+							{
+								IPAddress: "127.0.0.1",
+								Protocol:  "tcp",
+							},
+						},
+					},
+				},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				`frontend-testStickiness`: {
+					Backend:        "backend-testStickiness",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						`route-host-testStickiness`: {
+							Rule: "Host:testStickiness.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-testStickiness": {
+					Servers: map[string]types.Server{
+						"server-testStickiness": {
+							URL:    "http://localhost:80",
+							Weight: 0,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+						Stickiness: &types.Stickiness{
+							CookieName: "app",
+							HTTPOnly:   true,
+							MaxAge:     600,
+						},
+					},
+				},
+			},
+		},
 		{
 			applications: &marathon.Applications{
 				Apps: []marathon.Application{
@@ -393,6 +454,71 @@ func TestMarathonLoadConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			applications: &marathon.Applications{
+				Apps: []marathon.Application{
+					{
+						ID:    "/testHeaders",
+						Ports: []int{80},
+						Labels: &map[string]string{
+							"traefik.frontend.headers.customRequestHeaders": "X-Forwarded-Proto:https",
+							"traefik.frontend.headers.removeRequestHeaders": "X-Secret",
+							"traefik.frontend.headers.STSSeconds":           "31536000",
+							"traefik.frontend.headers.STSPreload":           "true",
+							"traefik.frontend.headers.frameDeny":            "false",
+							"traefik.frontend.headers.referrerPolicy":       "no-referrer",
+						},
+					},
+				},
+			},
+			tasks: &marathon.Tasks{
+				Tasks: []marathon.Task{
+					{
+						ID:    "testHeaders",
+						AppID: "/testHeaders",
+						Host:  "127.0.0.1",
+						Ports: []int{80},
+						IPAddresses: []*marathon.IPAddress{
+							{
+								IPAddress: "127.0.0.1",
+								Protocol:  "tcp",
+							},
+						},
+					},
+				},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				`frontend-testHeaders`: {
+					Backend:        "backend-testHeaders",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						`route-host-testHeaders`: {
+							Rule: "Host:testHeaders.docker.localhost",
+						},
+					},
+					Headers: &types.Headers{
+						CustomRequestHeaders: map[string]string{
+							"X-Forwarded-Proto": "https",
+						},
+						RemoveRequestHeaders: []string{"X-Secret"},
+						STSSeconds:           31536000,
+						STSPreload:           true,
+						ReferrerPolicy:       "no-referrer",
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-testHeaders": {
+					Servers: map[string]types.Server{
+						"server-testHeaders": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
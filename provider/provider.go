@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
 	"text/template"
@@ -123,6 +124,35 @@ func Normalize(name string) string {
 	return strings.Join(strings.FieldsFunc(name, fargs), "-")
 }
 
+// ParseHeaders parses a list of headers using the "Header1:value1||Header2:value2" format,
+// as found in labels and annotations.
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, part := range strings.Split(value, "||") {
+		if len(strings.TrimSpace(part)) == 0 {
+			continue
+		}
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) != 2 {
+			log.Warnf("Could not parse header %q, expected format Header:value", part)
+			continue
+		}
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(pair[0]))] = strings.TrimSpace(pair[1])
+	}
+	return headers
+}
+
+// SplitAndTrim splits a comma separated list and trims its elements, dropping the empty ones.
+func SplitAndTrim(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 // ReverseStringSlice invert the order of the given slice of string
 func ReverseStringSlice(slice *[]string) {
 	for i, j := 0, len(*slice)-1; i < j; i, j = i+1, j-1 {
@@ -3,6 +3,7 @@ package provider
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"text/template"
@@ -380,3 +381,30 @@ func TestDefaultFuncMap(t *testing.T) {
 		t.Fatalf("Frontend frontend-1 should exists, but it not")
 	}
 }
+
+func TestParseHeaders(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected map[string]string
+	}{
+		{
+			value:    "",
+			expected: map[string]string{},
+		},
+		{
+			value:    "X-Custom-Header:foo",
+			expected: map[string]string{"X-Custom-Header": "foo"},
+		},
+		{
+			value:    "x-forwarded-proto: https || X-Script-Name:/app||X-Empty:||invalid",
+			expected: map[string]string{"X-Forwarded-Proto": "https", "X-Script-Name": "/app", "X-Empty": ""},
+		},
+	}
+
+	for _, c := range cases {
+		actual := ParseHeaders(c.value)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("ParseHeaders(%q): expected %v, got %v", c.value, c.expected, actual)
+		}
+	}
+}
@@ -106,6 +106,13 @@ func (p *Provider) getLoadBalancerMethod(service rancherData) string {
 	return "wrr"
 }
 
+func (p *Provider) getLoadBalancerHashKey(service rancherData) string {
+	if label, err := getServiceLabel(service, "traefik.backend.loadbalancer.hashkey"); err == nil {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) hasLoadBalancerLabel(service rancherData) bool {
 	_, errMethod := getServiceLabel(service, "traefik.backend.loadbalancer.method")
 	_, errSticky := getServiceLabel(service, "traefik.backend.loadbalancer.sticky")
@@ -421,6 +428,7 @@ func (p *Provider) loadRancherConfig(services []rancherData) *types.Configuratio
 		"getCircuitBreakerExpression": p.getCircuitBreakerExpression,
 		"hasLoadBalancerLabel":        p.hasLoadBalancerLabel,
 		"getLoadBalancerMethod":       p.getLoadBalancerMethod,
+		"getLoadBalancerHashKey":      p.getLoadBalancerHashKey,
 		"hasMaxConnLabels":            p.hasMaxConnLabels,
 		"getMaxConnAmount":            p.getMaxConnAmount,
 		"getMaxConnExtractorFunc":     p.getMaxConnExtractorFunc,
@@ -0,0 +1,50 @@
+package proxyprotocol
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type sourceAddrKey struct{}
+
+// SourceAddrHandler stores the remote address of the requests in their
+// context, for the Dialer to send it to the servers
+func SourceAddrHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if addr, err := net.ResolveTCPAddr("tcp", req.RemoteAddr); err == nil {
+			req = req.WithContext(context.WithValue(req.Context(), sourceAddrKey{}, addr))
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// Dialer sends a PROXY protocol header on the connections it dials, with the
+// addresses of the request found in the dial context
+// The header carries no address for the connections not dialed for a request
+// that went through SourceAddrHandler, such as the health check ones
+type Dialer struct {
+	Version int
+	Dial    func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DialContext dials the address and sends the header
+func (dialer *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	c, err := dialer.Dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{Version: dialer.Version}
+	if src, ok := ctx.Value(sourceAddrKey{}).(*net.TCPAddr); ok {
+		if dst, ok := ctx.Value(http.LocalAddrContextKey).(*net.TCPAddr); ok {
+			header.SourceAddr = src
+			header.DestinationAddr = dst
+		}
+	}
+	if _, err := header.WriteTo(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
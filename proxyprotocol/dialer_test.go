@@ -0,0 +1,77 @@
+package proxyprotocol
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialer(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := NewListener(tcpListener, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, "%s %s", req.RemoteAddr, req.Context().Value(http.LocalAddrContextKey))
+	}))
+	backend.Listener.Close()
+	backend.Listener = listener
+	backend.Start()
+	defer backend.Close()
+
+	for _, version := range []int{1, 2} {
+		dialer := &Dialer{Version: version, Dial: (&net.Dialer{}).DialContext}
+		client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true}}
+
+		proxy := SourceAddrHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			outReq, err := http.NewRequest(http.MethodGet, backend.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(outReq.WithContext(req.Context()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rw.Write(body)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		req.RemoteAddr = "192.168.0.1:56324"
+		req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, tcpAddr("192.168.0.11:443")))
+		rw := httptest.NewRecorder()
+		proxy.ServeHTTP(rw, req)
+		assert.Equal(t, "192.168.0.1:56324 192.168.0.11:443", rw.Body.String(), "version %d", version)
+
+		// Without the addresses of a request, the connection addresses are kept
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, _, err := net.SplitHostPort(string(body[:len(body)-len(" "+backend.Listener.Addr().String())]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "127.0.0.1", host, "version %d", version)
+	}
+}
@@ -0,0 +1,209 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	v1Prefix = "PROXY "
+	// v1MaxLength is the maximum length of a v1 header, including the CRLF
+	v1MaxLength = 107
+
+	v2CommandLocal = 0x20
+	v2CommandProxy = 0x21
+	v2FamilyTCP4   = 0x11
+	v2FamilyTCP6   = 0x21
+	v2FamilyUnspec = 0x00
+	v2AddrLenTCP4  = 12
+	v2AddrLenTCP6  = 36
+)
+
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrNoHeader is returned by ReadHeader when the connection does not start with a PROXY protocol header
+var ErrNoHeader = errors.New("no PROXY protocol header")
+
+// Header is a PROXY protocol header, v1 or v2
+// The addresses are nil for the headers not carrying the addresses of the
+// client connection (v1 UNKNOWN, v2 LOCAL or non TCP families)
+type Header struct {
+	Version         int
+	SourceAddr      *net.TCPAddr
+	DestinationAddr *net.TCPAddr
+}
+
+// ReadHeader reads a PROXY protocol header of any version from the reader,
+// returning ErrNoHeader without consuming anything when there is none
+func ReadHeader(reader *bufio.Reader) (*Header, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case v1Prefix[0]:
+		prefix, err := reader.Peek(len(v1Prefix))
+		if err != nil || string(prefix) != v1Prefix {
+			return nil, ErrNoHeader
+		}
+		return readV1Header(reader)
+	case v2Signature[0]:
+		signature, err := reader.Peek(len(v2Signature))
+		if err != nil || !bytes.Equal(signature, v2Signature) {
+			return nil, ErrNoHeader
+		}
+		return readV2Header(reader)
+	}
+	return nil, ErrNoHeader
+}
+
+func readV1Header(reader *bufio.Reader) (*Header, error) {
+	var line []byte
+	for len(line) < v1MaxLength {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("invalid PROXY protocol v1 header: no CRLF found")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	header := &Header{Version: 1}
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return header, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+
+	var err error
+	if header.SourceAddr, err = parseV1Addr(fields[1], fields[2], fields[4]); err != nil {
+		return nil, err
+	}
+	if header.DestinationAddr, err = parseV1Addr(fields[1], fields[3], fields[5]); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func parseV1Addr(protocol string, ip string, port string) (*net.TCPAddr, error) {
+	// TCP6 addresses are in the IPv6 format, IPv4-mapped ones included
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil || strings.Contains(ip, ":") != (protocol == "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 %s address %q", protocol, ip)
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 port %q", port)
+	}
+	addr.Port = int(portNumber)
+	return addr, nil
+}
+
+func readV2Header(reader *bufio.Reader) (*Header, error) {
+	fixed := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(reader, fixed); err != nil {
+		return nil, err
+	}
+	command, family := fixed[12], fixed[13]
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	header := &Header{Version: 2}
+	switch command {
+	case v2CommandLocal:
+		return header, nil
+	case v2CommandProxy:
+	default:
+		return nil, fmt.Errorf("invalid PROXY protocol v2 command 0x%x", command)
+	}
+
+	// Only the TCP addresses are kept, the TLVs following them being ignored
+	switch family {
+	case v2FamilyTCP4:
+		if len(payload) < v2AddrLenTCP4 {
+			return nil, errors.New("invalid PROXY protocol v2 header: truncated TCP4 addresses")
+		}
+		header.SourceAddr = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:]))}
+		header.DestinationAddr = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:]))}
+	case v2FamilyTCP6:
+		if len(payload) < v2AddrLenTCP6 {
+			return nil, errors.New("invalid PROXY protocol v2 header: truncated TCP6 addresses")
+		}
+		header.SourceAddr = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:]))}
+		header.DestinationAddr = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:]))}
+	}
+	return header, nil
+}
+
+// WriteTo writes the header to the writer, as a v1 UNKNOWN or v2 LOCAL header
+// when it does not carry any address
+func (header *Header) WriteTo(writer io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	switch header.Version {
+	case 1:
+		header.writeV1(&buf)
+	case 2:
+		header.writeV2(&buf)
+	default:
+		return 0, fmt.Errorf("unsupported PROXY protocol version %d", header.Version)
+	}
+	return buf.WriteTo(writer)
+}
+
+func (header *Header) writeV1(buf *bytes.Buffer) {
+	if header.SourceAddr == nil || header.DestinationAddr == nil {
+		buf.WriteString(v1Prefix + "UNKNOWN\r\n")
+		return
+	}
+	protocol, src, dst := "TCP4", header.SourceAddr.IP.String(), header.DestinationAddr.IP.String()
+	if header.SourceAddr.IP.To4() == nil || header.DestinationAddr.IP.To4() == nil {
+		protocol, src, dst = "TCP6", formatIPv6(header.SourceAddr.IP), formatIPv6(header.DestinationAddr.IP)
+	}
+	fmt.Fprintf(buf, "%s%s %s %s %d %d\r\n", v1Prefix, protocol, src, dst, header.SourceAddr.Port, header.DestinationAddr.Port)
+}
+
+// formatIPv6 formats an IP in the IPv6 format, which net.IP.String does not
+// do for the IPv4-mapped addresses
+func formatIPv6(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+	return ip.String()
+}
+
+func (header *Header) writeV2(buf *bytes.Buffer) {
+	buf.Write(v2Signature)
+	if header.SourceAddr == nil || header.DestinationAddr == nil {
+		buf.Write([]byte{v2CommandLocal, v2FamilyUnspec, 0, 0})
+		return
+	}
+
+	src, dst := header.SourceAddr.IP.To4(), header.DestinationAddr.IP.To4()
+	family, length := byte(v2FamilyTCP4), v2AddrLenTCP4
+	if src == nil || dst == nil {
+		src, dst = header.SourceAddr.IP.To16(), header.DestinationAddr.IP.To16()
+		family, length = v2FamilyTCP6, v2AddrLenTCP6
+	}
+	buf.Write([]byte{v2CommandProxy, family})
+	binary.Write(buf, binary.BigEndian, uint16(length))
+	buf.Write(src)
+	buf.Write(dst)
+	binary.Write(buf, binary.BigEndian, uint16(header.SourceAddr.Port))
+	binary.Write(buf, binary.BigEndian, uint16(header.DestinationAddr.Port))
+}
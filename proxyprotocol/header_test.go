@@ -0,0 +1,193 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadHeader(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		input       string
+		expected    *Header
+		expectError error
+		anyError    bool
+	}{
+		{
+			desc:     "v1 TCP4",
+			input:    "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET / HTTP/1.1\r\n",
+			expected: &Header{Version: 1, SourceAddr: tcpAddr("192.168.0.1:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+		},
+		{
+			desc:     "v1 TCP6",
+			input:    "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nGET / HTTP/1.1\r\n",
+			expected: &Header{Version: 1, SourceAddr: tcpAddr("[2001:db8::1]:56324"), DestinationAddr: tcpAddr("[2001:db8::2]:443")},
+		},
+		{
+			desc:     "v1 UNKNOWN",
+			input:    "PROXY UNKNOWN\r\nGET / HTTP/1.1\r\n",
+			expected: &Header{Version: 1},
+		},
+		{
+			desc:     "v1 family mismatch",
+			input:    "PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n",
+			anyError: true,
+		},
+		{
+			desc:     "v1 invalid port",
+			input:    "PROXY TCP4 192.168.0.1 192.168.0.11 70000 443\r\n",
+			anyError: true,
+		},
+		{
+			desc:     "v1 without CRLF",
+			input:    "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443" + strings.Repeat(" ", 100),
+			anyError: true,
+		},
+		{
+			desc:        "plain HTTP request",
+			input:       "POST / HTTP/1.1\r\n",
+			expectError: ErrNoHeader,
+		},
+		{
+			desc:        "TLS handshake",
+			input:       "\x16\x03\x01\x00\xa5\x01",
+			expectError: ErrNoHeader,
+		},
+		{
+			desc:     "v2 TCP4",
+			input:    string(v2Signature) + "\x21\x11\x00\x0c\xc0\xa8\x00\x01\xc0\xa8\x00\x0b\xdc\x04\x01\xbb" + "GET / HTTP/1.1\r\n",
+			expected: &Header{Version: 2, SourceAddr: tcpAddr("192.168.0.1:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+		},
+		{
+			desc:     "v2 TCP4 with TLV",
+			input:    string(v2Signature) + "\x21\x11\x00\x10\xc0\xa8\x00\x01\xc0\xa8\x00\x0b\xdc\x04\x01\xbb\x04\x00\x01\x00" + "GET / HTTP/1.1\r\n",
+			expected: &Header{Version: 2, SourceAddr: tcpAddr("192.168.0.1:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+		},
+		{
+			desc:     "v2 LOCAL",
+			input:    string(v2Signature) + "\x20\x00\x00\x00" + "GET / HTTP/1.1\r\n",
+			expected: &Header{Version: 2},
+		},
+		{
+			desc:     "v2 truncated addresses",
+			input:    string(v2Signature) + "\x21\x11\x00\x04\xc0\xa8\x00\x01",
+			anyError: true,
+		},
+		{
+			desc:     "v2 invalid command",
+			input:    string(v2Signature) + "\x13\x11\x00\x00",
+			anyError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			reader := bufio.NewReader(strings.NewReader(test.input))
+			header, err := ReadHeader(reader)
+			switch {
+			case test.expectError != nil:
+				assert.Equal(t, test.expectError, err)
+				rest, _ := ioutil.ReadAll(reader)
+				assert.Equal(t, test.input, string(rest), "no byte should be consumed")
+			case test.anyError:
+				assert.Error(t, err)
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, test.expected.Version, header.Version)
+				assertAddr(t, test.expected.SourceAddr, header.SourceAddr)
+				assertAddr(t, test.expected.DestinationAddr, header.DestinationAddr)
+				rest, _ := ioutil.ReadAll(reader)
+				assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+			}
+		})
+	}
+}
+
+func TestHeaderWriteTo(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		header   *Header
+		expected string
+	}{
+		{
+			desc:     "v1 TCP4",
+			header:   &Header{Version: 1, SourceAddr: tcpAddr("192.168.0.1:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+			expected: "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+		},
+		{
+			desc:     "v1 mixed families",
+			header:   &Header{Version: 1, SourceAddr: tcpAddr("[2001:db8::1]:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+			expected: "PROXY TCP6 2001:db8::1 ::ffff:192.168.0.11 56324 443\r\n",
+		},
+		{
+			desc:     "v1 without addresses",
+			header:   &Header{Version: 1},
+			expected: "PROXY UNKNOWN\r\n",
+		},
+		{
+			desc:     "v2 TCP4",
+			header:   &Header{Version: 2, SourceAddr: tcpAddr("192.168.0.1:56324"), DestinationAddr: tcpAddr("192.168.0.11:443")},
+			expected: string(v2Signature) + "\x21\x11\x00\x0c\xc0\xa8\x00\x01\xc0\xa8\x00\x0b\xdc\x04\x01\xbb",
+		},
+		{
+			desc:     "v2 without addresses",
+			header:   &Header{Version: 2},
+			expected: string(v2Signature) + "\x20\x00\x00\x00",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			_, err := test.header.WriteTo(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.expected, buf.String())
+
+			// What is written must be read back
+			header, err := ReadHeader(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.header.Version, header.Version)
+			if test.header.SourceAddr != nil {
+				assert.True(t, test.header.SourceAddr.IP.Equal(header.SourceAddr.IP))
+				assert.Equal(t, test.header.SourceAddr.Port, header.SourceAddr.Port)
+			}
+		})
+	}
+
+	_, err := (&Header{Version: 3}).WriteTo(ioutil.Discard)
+	assert.Error(t, err)
+}
+
+func tcpAddr(addr string) *net.TCPAddr {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		panic(err)
+	}
+	return tcpAddr
+}
+
+func assertAddr(t *testing.T, expected *net.TCPAddr, actual *net.TCPAddr) {
+	if expected == nil {
+		assert.Nil(t, actual)
+		return
+	}
+	if assert.NotNil(t, actual) {
+		assert.Equal(t, expected.String(), actual.String())
+	}
+}
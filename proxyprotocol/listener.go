@@ -0,0 +1,99 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/whitelist"
+)
+
+// headerTimeout is the maximum duration to receive the header of a connection
+const headerTimeout = 10 * time.Second
+
+// Listener accepts connections starting with a PROXY protocol header when they
+// come from a trusted source, their remote and local addresses being those of
+// the header
+type Listener struct {
+	net.Listener
+	trustedIPs *whitelist.IP
+}
+
+// NewListener wraps a listener to read the PROXY protocol headers sent by the
+// trusted IPs, given as CIDRs or plain IPs
+func NewListener(listener net.Listener, trustedIPs []string) (*Listener, error) {
+	ips, err := whitelist.NewIP(trustedIPs)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: listener, trustedIPs: ips}, nil
+}
+
+// Accept waits for the next connection, its header being read on its first
+// use so that a slow client does not block the listener
+func (listener *Listener) Accept() (net.Conn, error) {
+	c, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	trusted, _, err := listener.trustedIPs.Contains(c.RemoteAddr().String())
+	if err != nil || !trusted {
+		return c, nil
+	}
+	return &conn{Conn: c, reader: bufio.NewReader(c)}, nil
+}
+
+// conn is a connection from a trusted source, which may start with a header
+type conn struct {
+	net.Conn
+	reader     *bufio.Reader
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *conn) readHeader() {
+	c.Conn.SetReadDeadline(time.Now().Add(headerTimeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	header, err := ReadHeader(c.reader)
+	if err == ErrNoHeader {
+		return
+	}
+	if err != nil {
+		log.Debugf("Error reading PROXY protocol header from %s: %v", c.Conn.RemoteAddr(), err)
+		c.err = err
+		return
+	}
+	if header.SourceAddr != nil {
+		c.remoteAddr = header.SourceAddr
+		c.localAddr = header.DestinationAddr
+	}
+}
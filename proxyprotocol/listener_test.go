@@ -0,0 +1,130 @@
+package proxyprotocol
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		trustedIPs         []string
+		header             string
+		expectedRemoteAddr string
+	}{
+		{
+			desc:               "trusted source with header",
+			trustedIPs:         []string{"127.0.0.0/8"},
+			header:             "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+			expectedRemoteAddr: "192.168.0.1:56324",
+		},
+		{
+			desc:       "trusted source without header",
+			trustedIPs: []string{"127.0.0.1"},
+		},
+		{
+			desc:       "trusted source with UNKNOWN header",
+			trustedIPs: []string{"127.0.0.1"},
+			header:     "PROXY UNKNOWN\r\n",
+		},
+		{
+			desc:       "untrusted source",
+			trustedIPs: []string{"10.0.0.0/8"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener, err := NewListener(tcpListener, test.trustedIPs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(rw, req.RemoteAddr)
+			}))
+			server.Listener.Close()
+			server.Listener = listener
+			server.Start()
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{}}
+			if len(test.header) > 0 {
+				client.Transport = &http.Transport{DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					c, err := (&net.Dialer{}).DialContext(ctx, network, address)
+					if err == nil {
+						_, err = c.Write([]byte(test.header))
+					}
+					return c, err
+				}}
+			}
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(test.expectedRemoteAddr) > 0 {
+				assert.Equal(t, test.expectedRemoteAddr, string(body))
+			} else {
+				host, _, err := net.SplitHostPort(string(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, "127.0.0.1", host)
+			}
+		})
+	}
+}
+
+func TestListenerInvalidHeader(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := NewListener(tcpListener, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.Write([]byte("PROXY TCP4 not.an.ip 192.168.0.11 56324 443\r\n"))
+	}()
+
+	c, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_, err = c.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestNewListenerInvalidTrustedIPs(t *testing.T) {
+	_, err := NewListener(nil, []string{"foo"})
+	assert.Error(t, err)
+}
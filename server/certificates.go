@@ -0,0 +1,113 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sort"
+	"strings"
+
+	"github.com/containous/traefik/log"
+)
+
+// entryPointCertificates are the certificates supplied by the providers for an
+// entrypoint, by the names they are valid for
+type entryPointCertificates map[string]*tls.Certificate
+
+// getCertificate returns the certificate of the server name, or a wildcard one
+// covering it, nil if there is none
+func (certs entryPointCertificates) getCertificate(serverName string) *tls.Certificate {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if len(name) == 0 {
+		return nil
+	}
+	if cert, ok := certs[name]; ok {
+		return cert
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		if cert, ok := certs["*"+name[i:]]; ok {
+			return cert
+		}
+	}
+	return nil
+}
+
+// loadEntryPointsCertificates loads the certificates of the TLS configurations
+// of the providers, for each TLS entrypoint
+func loadEntryPointsCertificates(configurations configs, globalConfiguration GlobalConfiguration) map[string]entryPointCertificates {
+	certificates := make(map[string]entryPointCertificates)
+	var tlsEntryPoints []string
+	for entryPointName, entryPoint := range globalConfiguration.EntryPoints {
+		if entryPoint.TLS != nil {
+			certificates[entryPointName] = entryPointCertificates{}
+			tlsEntryPoints = append(tlsEntryPoints, entryPointName)
+		}
+	}
+
+	// the providers are walked in order, so that the certificate kept for a name
+	// supplied by several of them does not change between the reloads
+	var providerNames []string
+	for providerName := range configurations {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+
+	for _, providerName := range providerNames {
+		for _, tlsConfiguration := range configurations[providerName].TLS {
+			if tlsConfiguration == nil || tlsConfiguration.Certificate == nil {
+				log.Errorf("Skipping TLS configuration without certificate of provider %s", providerName)
+				continue
+			}
+			cert, err := loadCertificate(tlsConfiguration.Certificate.CertFile, tlsConfiguration.Certificate.KeyFile)
+			if err != nil {
+				log.Errorf("Error loading certificate of provider %s: %v", providerName, err)
+				continue
+			}
+			names, err := certificateNames(&cert)
+			if err != nil {
+				log.Errorf("Error parsing certificate of provider %s: %v", providerName, err)
+				continue
+			}
+
+			entryPointNames := tlsConfiguration.EntryPoints
+			if len(entryPointNames) == 0 {
+				entryPointNames = tlsEntryPoints
+			}
+			for _, entryPointName := range entryPointNames {
+				entryPointCerts, ok := certificates[entryPointName]
+				if !ok {
+					log.Errorf("Undefined TLS entrypoint '%s' for certificate %s of provider %s", entryPointName, strings.Join(names, ","), providerName)
+					continue
+				}
+				for _, name := range names {
+					if _, exists := entryPointCerts[name]; exists {
+						log.Warnf("Several certificates for %s on entrypoint %s, keeping the first one", name, entryPointName)
+						continue
+					}
+					entryPointCerts[name] = &cert
+				}
+			}
+		}
+	}
+	return certificates
+}
+
+// certificateNames parses the leaf of the certificate and returns the names it
+// is valid for, from its common name and its subject alternative names
+func certificateNames(cert *tls.Certificate) ([]string, error) {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf = leaf
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{leaf.Subject.CommonName}, leaf.DNSNames...) {
+		name = strings.ToLower(name)
+		if len(name) > 0 && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
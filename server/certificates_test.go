@@ -0,0 +1,191 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+// generateTestCertificate returns a self-signed PEM cert/key pair valid for the names
+func generateTestCertificate(t *testing.T, commonName string, dnsNames ...string) *types.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &types.Certificate{
+		CertFile: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyFile:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+	}
+}
+
+func commonName(cert *tls.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestEntryPointCertificatesGetCertificate(t *testing.T) {
+	fooCert := &tls.Certificate{Leaf: &x509.Certificate{Subject: pkix.Name{CommonName: "foo.com"}}}
+	wildcardCert := &tls.Certificate{Leaf: &x509.Certificate{Subject: pkix.Name{CommonName: "*.foo.com"}}}
+	certs := entryPointCertificates{
+		"foo.com":   fooCert,
+		"*.foo.com": wildcardCert,
+	}
+
+	tests := []struct {
+		serverName string
+		expected   string
+	}{
+		{serverName: "foo.com", expected: "foo.com"},
+		{serverName: "FOO.com.", expected: "foo.com"},
+		{serverName: "www.foo.com", expected: "*.foo.com"},
+		{serverName: "a.www.foo.com"},
+		{serverName: "bar.com"},
+		{serverName: ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.serverName, func(t *testing.T) {
+			t.Parallel()
+			if actual := commonName(certs.getCertificate(test.serverName)); actual != test.expected {
+				t.Errorf("got certificate %q, want %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestLoadEntryPointsCertificates(t *testing.T) {
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http":   &EntryPoint{},
+			"https":  &EntryPoint{TLS: &TLS{}},
+			"https2": &EntryPoint{TLS: &TLS{}},
+		},
+	}
+	dynamicConfigs := configs{
+		"file": &types.Configuration{
+			TLS: []*types.TLSConfiguration{
+				{
+					EntryPoints: []string{"https2", "http", "unknown"},
+					Certificate: generateTestCertificate(t, "foo.com", "www.foo.com"),
+				},
+				{
+					Certificate: generateTestCertificate(t, "*.bar.com"),
+				},
+				{
+					Certificate: &types.Certificate{CertFile: "invalid", KeyFile: "invalid"},
+				},
+				{},
+			},
+		},
+		"kubernetes": &types.Configuration{
+			TLS: []*types.TLSConfiguration{
+				{
+					Certificate: generateTestCertificate(t, "other.foo.com", "foo.com"),
+				},
+			},
+		},
+	}
+
+	certificates := loadEntryPointsCertificates(dynamicConfigs, globalConfig)
+
+	if _, ok := certificates["http"]; ok {
+		t.Errorf("got certificates for the non TLS entrypoint http")
+	}
+	expected := map[string]map[string]string{
+		"https": {
+			"foo.com":       "other.foo.com",
+			"other.foo.com": "other.foo.com",
+			"*.bar.com":     "*.bar.com",
+		},
+		"https2": {
+			"foo.com":       "foo.com",
+			"www.foo.com":   "foo.com",
+			"other.foo.com": "other.foo.com",
+			"*.bar.com":     "*.bar.com",
+		},
+	}
+	for entryPointName, expectedCerts := range expected {
+		certs := certificates[entryPointName]
+		if len(certs) != len(expectedCerts) {
+			t.Errorf("got %d certificates on %s, want %d", len(certs), entryPointName, len(expectedCerts))
+		}
+		for name, expectedCommonName := range expectedCerts {
+			if actual := commonName(certs[name]); actual != expectedCommonName {
+				t.Errorf("got certificate %q for %s on %s, want %q", actual, name, entryPointName, expectedCommonName)
+			}
+		}
+	}
+}
+
+func TestServerCreateTLSConfigReloadsCertificates(t *testing.T) {
+	staticCert := generateTestCertificate(t, "static.com")
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"https": &EntryPoint{
+				TLS: &TLS{
+					Certificates: Certificates{{CertFile: staticCert.CertFile, KeyFile: staticCert.KeyFile}},
+				},
+			},
+		},
+	}
+	srv := NewServer(globalConfig)
+	srv.serverEntryPoints = srv.buildEntryPoints(globalConfig)
+	tlsConfig, err := srv.createTLSConfig("https", globalConfig.EntryPoints["https"].TLS, srv.serverEntryPoints["https"].httpRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getCertificate := func(serverName string) string {
+		cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commonName(cert)
+	}
+	if actual := getCertificate("foo.com"); actual != "" {
+		t.Errorf("got certificate %q for foo.com before the reload", actual)
+	}
+
+	dynamicConfigs := configs{
+		"file": &types.Configuration{
+			TLS: []*types.TLSConfiguration{
+				{Certificate: generateTestCertificate(t, "foo.com")},
+			},
+		},
+	}
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.serverEntryPoints["https"].certs.Set(entryPoints["https"].certs.Get())
+
+	if actual := getCertificate("foo.com"); actual != "foo.com" {
+		t.Errorf("got certificate %q for foo.com after the reload, want foo.com", actual)
+	}
+	// the static certificates are still served by the TLS config itself
+	if len(tlsConfig.Certificates) != 1 {
+		t.Errorf("got %d static certificates, want 1", len(tlsConfig.Certificates))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/containous/flaeg"
 	"github.com/containous/traefik/acme"
+	"github.com/containous/traefik/middlewares/accesslog"
 	"github.com/containous/traefik/provider/boltdb"
 	"github.com/containous/traefik/provider/consul"
 	"github.com/containous/traefik/provider/docker"
@@ -40,9 +42,11 @@ type TraefikConfiguration struct {
 // It's populated from the traefik configuration file passed as an argument to the binary.
 type GlobalConfiguration struct {
 	GraceTimeOut              flaeg.Duration          `short:"g" description:"Duration to give active requests a chance to finish during hot-reload"`
+	LifeCycle                 *LifeCycle              `description:"Timeouts influencing the server life cycle"`
 	Debug                     bool                    `short:"d" description:"Enable debug mode"`
 	CheckNewVersion           bool                    `description:"Periodically check if a new version has been released"`
-	AccessLogsFile            string                  `description:"Access logs file"`
+	AccessLogsFile            string                  `description:"(Deprecated) Access logs file"` // Deprecated
+	AccessLog                 *types.AccessLog        `description:"Access log settings"`
 	TraefikLogsFile           string                  `description:"Traefik logs file"`
 	LogLevel                  string                  `short:"l" description:"Log level"`
 	EntryPoints               EntryPoints             `description:"Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key;prod/traefik.crt,prod/traefik.key'"`
@@ -54,7 +58,7 @@ type GlobalConfiguration struct {
 	MaxIdleConnsPerHost       int                     `description:"If non-zero, controls the maximum idle (keep-alive) to keep per-host.  If zero, DefaultMaxIdleConnsPerHost is used"`
 	IdleTimeout               flaeg.Duration          `description:"maximum amount of time an idle (keep-alive) connection will remain idle before closing itself."`
 	InsecureSkipVerify        bool                    `description:"Disable SSL certificate verification"`
-	Retry                     *Retry                  `description:"Enable retry sending request if network error"`
+	Retry                     *types.Retry            `description:"Enable retry sending request if network error"`
 	HealthCheck               *HealthCheckConfig      `description:"Health check parameters"`
 	Docker                    *docker.Provider        `description:"Enable Docker backend"`
 	File                      *file.Provider          `description:"Enable File backend"`
@@ -124,7 +128,7 @@ func (ep *EntryPoints) String() string {
 // Set's argument is a string to be parsed to set the flag.
 // It's a comma-separated list, so we split it.
 func (ep *EntryPoints) Set(value string) error {
-	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*((?P<TLSACME>TLS))?\\s*(?:CA:(?P<CA>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?\\s*(?:Compress:(?P<Compress>\\S*))?")
+	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*((?P<TLSACME>TLS))?\\s*(?:CA:(?P<CA>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?\\s*(?:Compress:(?P<Compress>\\S*))?\\s*(?:WhitelistSourceRange:(?P<WhitelistSourceRange>\\S*))?\\s*(?:WhitelistTrustedProxies:(?P<WhitelistTrustedProxies>\\S*))?\\s*(?:ProxyProtocol.TrustedIPs:(?P<ProxyProtocolTrustedIPs>\\S*))?\\s*(?:ForwardedHeaders.Insecure:(?P<ForwardedHeadersInsecure>\\S*))?\\s*(?:ForwardedHeaders.TrustedIPs:(?P<ForwardedHeadersTrustedIPs>\\S*))?")
 	match := regex.FindAllStringSubmatch(value, -1)
 	if match == nil {
 		return errors.New("Bad EntryPoints format: " + value)
@@ -168,11 +172,42 @@ func (ep *EntryPoints) Set(value string) error {
 		compress = strings.EqualFold(result["Compress"], "enable") || strings.EqualFold(result["Compress"], "on")
 	}
 
+	var whitelistSourceRange []string
+	if len(result["WhitelistSourceRange"]) > 0 {
+		whitelistSourceRange = strings.Split(result["WhitelistSourceRange"], ",")
+	}
+
+	var whitelistTrustedProxies []string
+	if len(result["WhitelistTrustedProxies"]) > 0 {
+		whitelistTrustedProxies = strings.Split(result["WhitelistTrustedProxies"], ",")
+	}
+
+	var proxyProtocol *ProxyProtocol
+	if len(result["ProxyProtocolTrustedIPs"]) > 0 {
+		proxyProtocol = &ProxyProtocol{
+			TrustedIPs: strings.Split(result["ProxyProtocolTrustedIPs"], ","),
+		}
+	}
+
+	var forwardedHeaders *ForwardedHeaders
+	if len(result["ForwardedHeadersInsecure"]) > 0 || len(result["ForwardedHeadersTrustedIPs"]) > 0 {
+		forwardedHeaders = &ForwardedHeaders{
+			Insecure: strings.EqualFold(result["ForwardedHeadersInsecure"], "true"),
+		}
+		if len(result["ForwardedHeadersTrustedIPs"]) > 0 {
+			forwardedHeaders.TrustedIPs = strings.Split(result["ForwardedHeadersTrustedIPs"], ",")
+		}
+	}
+
 	(*ep)[result["Name"]] = &EntryPoint{
-		Address:  result["Address"],
-		TLS:      tls,
-		Redirect: redirect,
-		Compress: compress,
+		Address:                 result["Address"],
+		TLS:                     tls,
+		Redirect:                redirect,
+		Compress:                compress,
+		WhitelistSourceRange:    whitelistSourceRange,
+		WhitelistTrustedProxies: whitelistTrustedProxies,
+		ProxyProtocol:           proxyProtocol,
+		ForwardedHeaders:        forwardedHeaders,
 	}
 
 	return nil
@@ -195,12 +230,40 @@ func (ep *EntryPoints) Type() string {
 
 // EntryPoint holds an entry point configuration of the reverse proxy (ip, port, TLS...)
 type EntryPoint struct {
-	Network  string
-	Address  string
-	TLS      *TLS
-	Redirect *Redirect
-	Auth     *types.Auth
-	Compress bool
+	Network                 string
+	Address                 string
+	TLS                     *TLS
+	Redirect                *Redirect
+	Auth                    *types.Auth
+	Compress                bool
+	WhitelistSourceRange    []string
+	WhitelistTrustedProxies []string
+	Timeouts                *EntryPointTimeouts
+	ProxyProtocol           *ProxyProtocol
+	ForwardedHeaders        *ForwardedHeaders
+}
+
+// ForwardedHeaders configures the trust given to the X-Forwarded-* and Forwarded headers of the requests of an entry point
+type ForwardedHeaders struct {
+	// Insecure trusts the headers of any source
+	Insecure bool
+	// TrustedIPs are the CIDRs or IPs allowed to send the headers, which are removed from the requests of other sources
+	TrustedIPs []string
+}
+
+// ProxyProtocol configures the PROXY protocol headers accepted by an entry point
+type ProxyProtocol struct {
+	// TrustedIPs are the CIDRs or IPs allowed to send a header, the other sources being served as is
+	TrustedIPs []string
+}
+
+// EntryPointTimeouts configures the timeouts of the connections of an entry point, zero meaning no timeout
+type EntryPointTimeouts struct {
+	ReadTimeout       flaeg.Duration
+	ReadHeaderTimeout flaeg.Duration
+	WriteTimeout      flaeg.Duration
+	// IdleTimeout defaults to the global IdleTimeout
+	IdleTimeout flaeg.Duration
 }
 
 // Redirect configures a redirection of an entry point to another, or to an URL
@@ -262,37 +325,42 @@ func (certs *Certificates) CreateTLSConfig() (*tls.Config, error) {
 	config.Certificates = []tls.Certificate{}
 	certsSlice := []Certificate(*certs)
 	for _, v := range certsSlice {
-		isAPath := false
-		_, errCert := os.Stat(v.CertFile)
-		_, errKey := os.Stat(v.KeyFile)
-		if errCert == nil {
-			if errKey == nil {
-				isAPath = true
-			} else {
-				return nil, fmt.Errorf("bad TLS Certificate KeyFile format, expected a path")
-			}
-		} else if errKey == nil {
-			return nil, fmt.Errorf("bad TLS Certificate KeyFile format, expected a path")
-		}
-
-		cert := tls.Certificate{}
-		var err error
-		if isAPath {
-			cert, err = tls.LoadX509KeyPair(v.CertFile, v.KeyFile)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			cert, err = tls.X509KeyPair([]byte(v.CertFile), []byte(v.KeyFile))
-			if err != nil {
-				return nil, err
-			}
+		cert, err := loadCertificate(v.CertFile, v.KeyFile)
+		if err != nil {
+			return nil, err
 		}
 		config.Certificates = append(config.Certificates, cert)
 	}
 	return config, nil
 }
 
+// loadCertificate loads a cert/key pair given either as file paths, or as the
+// file contents themselves
+func loadCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	certPEM, certIsAPath, err := readFileOrContent(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, keyIsAPath, err := readFileOrContent(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if certIsAPath != keyIsAPath {
+		return tls.Certificate{}, fmt.Errorf("bad TLS Certificate KeyFile format, expected a path")
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// readFileOrContent returns the content of the file at the given path, or the
+// value itself when it is not the path of a file, telling which one it was
+func readFileOrContent(value string) ([]byte, bool, error) {
+	if _, err := os.Stat(value); err != nil {
+		return []byte(value), false, nil
+	}
+	content, err := ioutil.ReadFile(value)
+	return content, true, err
+}
+
 // String is the method to format the flag's value, part of the flag.Value interface.
 // The String method's output will be used in diagnostics.
 func (certs *Certificates) String() string {
@@ -336,9 +404,9 @@ type Certificate struct {
 	KeyFile  string
 }
 
-// Retry contains request retry config
-type Retry struct {
-	Attempts int `description:"Number of attempts"`
+// LifeCycle contains configurations relevant to the lifecycle (such as the shutdown phase) of Traefik.
+type LifeCycle struct {
+	RequestAcceptGraceTimeout flaeg.Duration `description:"Duration to keep accepting requests before Traefik initiates the graceful shutdown procedure"`
 }
 
 // HealthCheckConfig contains health check configuration parameters.
@@ -374,6 +442,12 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 		},
 	}
 
+	// default AccessLog
+	defaultAccessLog := types.AccessLog{
+		Format:   accesslog.CommonFormat,
+		FilePath: "",
+	}
+
 	// default Marathon
 	var defaultMarathon marathon.Provider
 	defaultMarathon.Watch = true
@@ -471,8 +545,10 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 		ECS:           &defaultECS,
 		Rancher:       &defaultRancher,
 		DynamoDB:      &defaultDynamoDB,
-		Retry:         &Retry{},
+		AccessLog:     &defaultAccessLog,
+		Retry:         &types.Retry{},
 		HealthCheck:   &HealthCheckConfig{},
+		LifeCycle:     &LifeCycle{},
 	}
 
 	//default Rancher
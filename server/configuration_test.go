@@ -0,0 +1,65 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntryPointsSetWhitelist(t *testing.T) {
+	entryPoints := EntryPoints{}
+	err := entryPoints.Set("Name:http Address::8000 WhitelistSourceRange:10.42.0.0/16,152.89.1.33/32 WhitelistTrustedProxies:10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &EntryPoint{
+		Address:                 ":8000",
+		WhitelistSourceRange:    []string{"10.42.0.0/16", "152.89.1.33/32"},
+		WhitelistTrustedProxies: []string{"10.0.0.1"},
+	}
+	if !reflect.DeepEqual(entryPoints["http"], expected) {
+		t.Fatalf("expected %+v, got %+v", expected, entryPoints["http"])
+	}
+}
+
+func TestEntryPointsSetProxyProtocol(t *testing.T) {
+	entryPoints := EntryPoints{}
+	err := entryPoints.Set("Name:http Address::8000 ProxyProtocol.TrustedIPs:10.0.0.0/8,192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &EntryPoint{
+		Address:       ":8000",
+		ProxyProtocol: &ProxyProtocol{TrustedIPs: []string{"10.0.0.0/8", "192.168.1.1"}},
+	}
+	if !reflect.DeepEqual(entryPoints["http"], expected) {
+		t.Fatalf("expected %+v, got %+v", expected, entryPoints["http"])
+	}
+}
+
+func TestEntryPointsSetForwardedHeaders(t *testing.T) {
+	entryPoints := EntryPoints{}
+	err := entryPoints.Set("Name:http Address::8000 ForwardedHeaders.TrustedIPs:10.0.0.0/8,192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = entryPoints.Set("Name:https Address::8443 ForwardedHeaders.Insecure:true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := EntryPoints{
+		"http": &EntryPoint{
+			Address:          ":8000",
+			ForwardedHeaders: &ForwardedHeaders{TrustedIPs: []string{"10.0.0.0/8", "192.168.1.1"}},
+		},
+		"https": &EntryPoint{
+			Address:          ":8443",
+			ForwardedHeaders: &ForwardedHeaders{Insecure: true},
+		},
+	}
+	if !reflect.DeepEqual(entryPoints, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, entryPoints)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,18 +16,22 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
 	"github.com/codegangsta/negroni"
 	"github.com/containous/mux"
 	"github.com/containous/traefik/cluster"
 	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/loadbalancer"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/containous/traefik/middlewares/forwardedheaders"
 	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/proxyprotocol"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/streamrail/concurrent-map"
@@ -33,7 +39,6 @@ import (
 	"github.com/vulcand/oxy/connlimit"
 	"github.com/vulcand/oxy/forward"
 	"github.com/vulcand/oxy/roundrobin"
-	"github.com/vulcand/oxy/utils"
 )
 
 var oxyLogger = &OxyLogger{}
@@ -48,10 +53,11 @@ type Server struct {
 	providers                  []provider.Provider
 	currentConfigurations      safe.Safe
 	globalConfiguration        GlobalConfiguration
-	loggerMiddleware           *middlewares.Logger
 	accessLoggerMiddleware     *accesslog.LogHandler
 	routinesPool               *safe.Pool
 	leadership                 *cluster.Leadership
+	backendTransports          backendTransports
+	terminating                int32
 }
 
 type serverEntryPoints map[string]*serverEntryPoint
@@ -59,6 +65,7 @@ type serverEntryPoints map[string]*serverEntryPoint
 type serverEntryPoint struct {
 	httpServer *http.Server
 	httpRouter *middlewares.HandlerSwitcher
+	certs      *safe.Safe
 }
 
 type serverRoute struct {
@@ -79,12 +86,23 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 	server.signals = make(chan os.Signal, 1)
 	server.stopChan = make(chan bool, 1)
 	server.providers = []provider.Provider{}
-	signal.Notify(server.signals, syscall.SIGINT, syscall.SIGTERM)
+	server.configureSignals()
 	currentConfigurations := make(configs)
 	server.currentConfigurations.Set(currentConfigurations)
 	server.globalConfiguration = globalConfiguration
-	server.loggerMiddleware = middlewares.NewLogger(globalConfiguration.AccessLogsFile)
-	server.accessLoggerMiddleware = accesslog.NewLogHandler()
+	if server.globalConfiguration.AccessLog == nil && len(server.globalConfiguration.AccessLogsFile) > 0 {
+		log.Warn("accessLogsFile is deprecated, use [accessLog] with filePath instead")
+		server.globalConfiguration.AccessLog = &types.AccessLog{
+			FilePath: server.globalConfiguration.AccessLogsFile,
+			Format:   accesslog.CommonFormat,
+		}
+	}
+	var err error
+	server.accessLoggerMiddleware, err = accesslog.NewLogHandler(server.globalConfiguration.AccessLog)
+	if err != nil {
+		log.Warnf("Unable to create access log handler, access logs disabled: %s", err)
+		server.accessLoggerMiddleware, _ = accesslog.NewLogHandler(nil)
+	}
 	server.routinesPool = safe.NewPool(context.Background())
 	if globalConfiguration.Cluster != nil {
 		// leadership creation if cluster mode
@@ -117,6 +135,12 @@ func (server *Server) Wait() {
 // Stop stops the server
 func (server *Server) Stop() {
 	defer log.Info("Server stopped")
+	atomic.StoreInt32(&server.terminating, 1)
+	if server.globalConfiguration.LifeCycle != nil && server.globalConfiguration.LifeCycle.RequestAcceptGraceTimeout > 0 {
+		requestAcceptGraceTimeout := time.Duration(server.globalConfiguration.LifeCycle.RequestAcceptGraceTimeout)
+		log.Infof("Waiting %s for incoming requests to cease", requestAcceptGraceTimeout)
+		time.Sleep(requestAcceptGraceTimeout)
+	}
 	var wg sync.WaitGroup
 	for sepn, sep := range server.serverEntryPoints {
 		wg.Add(1)
@@ -137,6 +161,12 @@ func (server *Server) Stop() {
 	server.stopChan <- true
 }
 
+// isTerminating returns true once the server has started to stop, the
+// entrypoints still accepting requests during the request accept grace timeout
+func (server *Server) isTerminating() bool {
+	return atomic.LoadInt32(&server.terminating) == 1
+}
+
 // Close destroys the server
 func (server *Server) Close() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(server.globalConfiguration.GraceTimeOut))
@@ -156,8 +186,9 @@ func (server *Server) Close() {
 	signal.Stop(server.signals)
 	close(server.signals)
 	close(server.stopChan)
-	server.loggerMiddleware.Close()
-	server.accessLoggerMiddleware.Close()
+	if err := server.accessLoggerMiddleware.Close(); err != nil {
+		log.Errorf("Error closing access log file: %s", err)
+	}
 	cancel()
 }
 
@@ -177,7 +208,15 @@ func (server *Server) startHTTPServers() {
 	server.serverEntryPoints = server.buildEntryPoints(server.globalConfiguration)
 
 	for newServerEntryPointName, newServerEntryPoint := range server.serverEntryPoints {
-		serverMiddlewares := []negroni.Handler{server.accessLoggerMiddleware, server.loggerMiddleware, metrics}
+		serverMiddlewares := []negroni.Handler{server.accessLoggerMiddleware, metrics}
+		if forwardedHeadersConfig := server.globalConfiguration.EntryPoints[newServerEntryPointName].ForwardedHeaders; forwardedHeadersConfig != nil {
+			xForwarded, err := forwardedheaders.NewXForwarded(forwardedHeadersConfig.Insecure, forwardedHeadersConfig.TrustedIPs)
+			if err != nil {
+				log.Fatal("Error starting server: ", err)
+			}
+			// the other middlewares, the access log included, must see the resolved client IP
+			serverMiddlewares = append([]negroni.Handler{xForwarded}, serverMiddlewares...)
+		}
 		if server.globalConfiguration.Web != nil && server.globalConfiguration.Web.Metrics != nil {
 			if server.globalConfiguration.Web.Metrics.Prometheus != nil {
 				metricsMiddleware := middlewares.NewMetricsWrapper(middlewares.NewPrometheus(newServerEntryPointName, server.globalConfiguration.Web.Metrics.Prometheus))
@@ -188,6 +227,15 @@ func (server *Server) startHTTPServers() {
 			statsRecorder = middlewares.NewStatsRecorder(server.globalConfiguration.Web.Statistics.RecentErrors)
 			serverMiddlewares = append(serverMiddlewares, statsRecorder)
 		}
+		// the clients out of the white list are rejected before any authentication
+		if len(server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistSourceRange) > 0 {
+			ipWhitelister, err := middlewares.NewIPWhitelister(server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistSourceRange,
+				server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistTrustedProxies)
+			if err != nil {
+				log.Fatal("Error starting server: ", err)
+			}
+			serverMiddlewares = append(serverMiddlewares, ipWhitelister)
+		}
 		if server.globalConfiguration.EntryPoints[newServerEntryPointName].Auth != nil {
 			authMiddleware, err := middlewares.NewAuthenticator(server.globalConfiguration.EntryPoints[newServerEntryPointName].Auth)
 			if err != nil {
@@ -204,7 +252,7 @@ func (server *Server) startHTTPServers() {
 		}
 		serverEntryPoint := server.serverEntryPoints[newServerEntryPointName]
 		serverEntryPoint.httpServer = newsrv
-		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration)
+		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration.EntryPoints[newServerEntryPointName])
 	}
 }
 
@@ -223,7 +271,7 @@ func (server *Server) listenProviders(stop chan bool) {
 			currentConfigurations := server.currentConfigurations.Get().(configs)
 			jsonConf, _ := json.Marshal(configMsg.Configuration)
 			log.Debugf("Configuration received from provider %s: %s", configMsg.ProviderName, string(jsonConf))
-			if configMsg.Configuration == nil || configMsg.Configuration.Backends == nil && configMsg.Configuration.Frontends == nil {
+			if configMsg.Configuration == nil || configMsg.Configuration.Backends == nil && configMsg.Configuration.Frontends == nil && configMsg.Configuration.TLS == nil {
 				log.Infof("Skipping empty Configuration for provider %s", configMsg.ProviderName)
 			} else if reflect.DeepEqual(currentConfigurations[configMsg.ProviderName], configMsg.Configuration) {
 				log.Infof("Skipping same configuration for provider %s", configMsg.ProviderName)
@@ -295,6 +343,7 @@ func (server *Server) listenConfigurations(stop chan bool) {
 			if err == nil {
 				for newServerEntryPointName, newServerEntryPoint := range newServerEntryPoints {
 					server.serverEntryPoints[newServerEntryPointName].httpRouter.UpdateHandler(newServerEntryPoint.httpRouter.GetHandler())
+					server.serverEntryPoints[newServerEntryPointName].certs.Set(newServerEntryPoint.certs.Get())
 					log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 				}
 				server.currentConfigurations.Set(newConfigurations)
@@ -410,13 +459,6 @@ func (server *Server) startProviders() {
 	}
 }
 
-func (server *Server) listenSignals() {
-	sig := <-server.signals
-	log.Infof("I have to go... %+v", sig)
-	log.Info("Stopping server")
-	server.Stop()
-}
-
 // creates a TLS config that allows terminating HTTPS for multiple domains using SNI
 func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, router *middlewares.HandlerSwitcher) (*tls.Config, error) {
 	if tlsOption == nil {
@@ -474,8 +516,26 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 		} else {
 			return nil, errors.New("Unknown entrypoint " + server.globalConfiguration.ACME.EntryPoint + " for ACME configuration")
 		}
+		if httpChallenge := server.globalConfiguration.ACME.HTTPChallenge; httpChallenge != nil {
+			if _, ok := server.serverEntryPoints[httpChallenge.EntryPoint]; !ok {
+				return nil, errors.New("Unknown entrypoint " + httpChallenge.EntryPoint + " for ACME HTTP challenge")
+			}
+		}
 	}
-	if len(config.Certificates) == 0 {
+	if entryPoint, ok := server.serverEntryPoints[entryPointName]; ok {
+		// the certificates of the providers are looked up on each handshake, so
+		// that they are updated on the configuration reloads
+		getCertificate := config.GetCertificate
+		config.GetCertificate = func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert := entryPoint.certs.Get().(entryPointCertificates).getCertificate(clientHello.ServerName); cert != nil {
+				return cert, nil
+			}
+			if getCertificate != nil {
+				return getCertificate(clientHello)
+			}
+			return nil, nil
+		}
+	} else if len(config.Certificates) == 0 {
 		return nil, errors.New("No certificates found for TLS entrypoint " + entryPointName)
 	}
 	// BuildNameToCertificate parses the CommonName and SubjectAlternateName fields
@@ -502,10 +562,12 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 	return config, nil
 }
 
-func (server *Server) startServer(srv *http.Server, globalConfiguration GlobalConfiguration) {
+func (server *Server) startServer(srv *http.Server, entryPoint *EntryPoint) {
 	log.Infof("Starting server on %s", srv.Addr)
 	var err error
-	if srv.TLSConfig != nil {
+	if entryPoint.ProxyProtocol != nil {
+		err = serveProxyProtocol(srv, entryPoint.ProxyProtocol)
+	} else if srv.TLSConfig != nil {
 		err = srv.ListenAndServeTLS("", "")
 	} else {
 		err = srv.ListenAndServe()
@@ -515,6 +577,40 @@ func (server *Server) startServer(srv *http.Server, globalConfiguration GlobalCo
 	}
 }
 
+// serveProxyProtocol serves the connections of an entry point reading the
+// PROXY protocol headers, so that the middlewares see the real client address
+func serveProxyProtocol(srv *http.Server, config *ProxyProtocol) error {
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	proxyListener, err := proxyprotocol.NewListener(tcpKeepAliveListener{listener.(*net.TCPListener)}, config.TrustedIPs)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	if srv.TLSConfig != nil {
+		return srv.Serve(tls.NewListener(proxyListener, srv.TLSConfig))
+	}
+	return srv.Serve(proxyListener)
+}
+
+// tcpKeepAliveListener sets TCP keep-alive timeouts on accepted connections,
+// as http.Server.ListenAndServe does
+type tcpKeepAliveListener struct {
+	*net.TCPListener
+}
+
+func (ln tcpKeepAliveListener) Accept() (net.Conn, error) {
+	tc, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	tc.SetKeepAlive(true)
+	tc.SetKeepAlivePeriod(3 * time.Minute)
+	return tc, nil
+}
+
 func (server *Server) prepareServer(entryPointName string, router *middlewares.HandlerSwitcher, entryPoint *EntryPoint, middlewares ...negroni.Handler) (*http.Server, error) {
 	log.Infof("Preparing server %s %+v", entryPointName, entryPoint)
 	// middlewares
@@ -529,20 +625,33 @@ func (server *Server) prepareServer(entryPointName string, router *middlewares.H
 		return nil, err
 	}
 
-	return &http.Server{
+	srv := &http.Server{
 		Addr:        entryPoint.Address,
 		Handler:     negroni,
 		TLSConfig:   tlsConfig,
 		IdleTimeout: time.Duration(server.globalConfiguration.IdleTimeout),
-	}, nil
+	}
+	if entryPoint.Timeouts != nil {
+		srv.ReadTimeout = time.Duration(entryPoint.Timeouts.ReadTimeout)
+		srv.ReadHeaderTimeout = time.Duration(entryPoint.Timeouts.ReadHeaderTimeout)
+		srv.WriteTimeout = time.Duration(entryPoint.Timeouts.WriteTimeout)
+		if entryPoint.Timeouts.IdleTimeout > 0 {
+			srv.IdleTimeout = time.Duration(entryPoint.Timeouts.IdleTimeout)
+		}
+	}
+	return srv, nil
 }
 
 func (server *Server) buildEntryPoints(globalConfiguration GlobalConfiguration) map[string]*serverEntryPoint {
 	serverEntryPoints := make(map[string]*serverEntryPoint)
 	for entryPointName := range globalConfiguration.EntryPoints {
 		router := server.buildDefaultHTTPRouter()
+		if globalConfiguration.ACME != nil && globalConfiguration.ACME.HTTPChallenge != nil && globalConfiguration.ACME.HTTPChallenge.EntryPoint == entryPointName {
+			globalConfiguration.ACME.AddRoutes(router)
+		}
 		serverEntryPoints[entryPointName] = &serverEntryPoint{
 			httpRouter: middlewares.NewHandlerSwitcher(router),
+			certs:      safe.New(entryPointCertificates{}),
 		}
 	}
 	return serverEntryPoints
@@ -554,8 +663,9 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 	serverEntryPoints := server.buildEntryPoints(globalConfiguration)
 	redirectHandlers := make(map[string]negroni.Handler)
 	backends := map[string]http.Handler{}
+	mirrorBackends := map[string]http.Handler{}
 	backendsHealthcheck := map[string]*healthcheck.BackendHealthCheck{}
-	backend2FrontendMap := map[string]string{}
+	backendsPassiveHealthCheck := map[string]*healthcheck.PassiveHealthCheck{}
 
 	for _, configuration := range configurations {
 		frontendNames := sortedFrontendNamesForConfig(configuration)
@@ -565,7 +675,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 
 			log.Debugf("Creating frontend %s", frontendName)
 
-			fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader))
+			fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader), forward.ErrorHandler(middlewares.NetErrorHandler))
 			if err != nil {
 				log.Errorf("Error creating forwarder for frontend %s: %v", frontendName, err)
 				log.Errorf("Skipping frontend %s...", frontendName)
@@ -597,174 +707,284 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 				}
 
 				entryPoint := globalConfiguration.EntryPoints[entryPointName]
-				negroni := negroni.New()
+				var entryPointRedirect negroni.Handler
 				if entryPoint.Redirect != nil {
 					if redirectHandlers[entryPointName] != nil {
-						negroni.Use(redirectHandlers[entryPointName])
+						entryPointRedirect = redirectHandlers[entryPointName]
 					} else if handler, err := server.loadEntryPointConfig(entryPointName, entryPoint); err != nil {
 						log.Errorf("Error loading entrypoint configuration for frontend %s: %v", frontendName, err)
 						log.Errorf("Skipping frontend %s...", frontendName)
 						continue frontend
 					} else {
 						saveFrontend := accesslog.NewSaveNegroniFrontend(handler, frontendName)
-						negroni.Use(saveFrontend)
+						entryPointRedirect = saveFrontend
 						redirectHandlers[entryPointName] = saveFrontend
 					}
 				}
-				if backends[entryPointName+frontend.Backend] == nil {
-					log.Debugf("Creating backend %s", frontend.Backend)
-					saveBackend := accesslog.NewSaveBackend(fwd, frontend.Backend)
-					saveFrontend := accesslog.NewSaveFrontend(saveBackend, frontendName)
-					rr, _ := roundrobin.New(saveFrontend)
-					if configuration.Backends[frontend.Backend] == nil {
-						log.Errorf("Undefined backend '%s' for frontend %s", frontend.Backend, frontendName)
-						log.Errorf("Skipping frontend %s...", frontendName)
-						continue frontend
-					}
-
-					lbMethod, err := types.NewLoadBalancerMethod(configuration.Backends[frontend.Backend].LoadBalancer)
-					if err != nil {
-						log.Errorf("Error loading load balancer method '%+v' for frontend %s: %v", configuration.Backends[frontend.Backend].LoadBalancer, frontendName, err)
-						log.Errorf("Skipping frontend %s...", frontendName)
-						continue frontend
-					}
-
-					stickysession := configuration.Backends[frontend.Backend].LoadBalancer.Sticky
-					cookiename := "_TRAEFIK_BACKEND"
-					var sticky *roundrobin.StickySession
-
-					if stickysession {
-						sticky = roundrobin.NewStickySession(cookiename)
-					}
-
-					var lb http.Handler
-					switch lbMethod {
-					case types.Drr:
-						log.Debugf("Creating load-balancer drr")
-						rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
-						if stickysession {
-							log.Debugf("Sticky session with cookie %v", cookiename)
-							rebalancer, _ = roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger), roundrobin.RebalancerStickySession(sticky))
+				for _, backendName := range frontendBackendNames(frontend) {
+					if backends[entryPointName+backendName] == nil {
+						log.Debugf("Creating backend %s", backendName)
+						// the mirrored requests go through the same chain, without the
+						// metrics of the live traffic of the backend
+						mirrorNegroni := negroni.New()
+						negroni := negroni.New()
+						if entryPointRedirect != nil {
+							negroni.Use(entryPointRedirect)
+							mirrorNegroni.Use(entryPointRedirect)
 						}
-						lb = rebalancer
-						for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-							url, err := url.Parse(server.URL)
+						if configuration.Backends[backendName] == nil {
+							log.Errorf("Undefined backend '%s' for frontend %s", backendName, frontendName)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+
+						var forwarder http.Handler = fwd
+						var roundTripper http.RoundTripper
+						if transportConfig := configuration.Backends[backendName].Transport; transportConfig != nil {
+							log.Debugf("Creating transport for backend %s", backendName)
+							transport, err := server.backendTransports.get(backendName, transportConfig, globalConfiguration)
 							if err != nil {
-								log.Errorf("Error parsing server URL %s: %v", server.URL, err)
+								log.Errorf("Error creating transport for backend %s: %v", backendName, err)
 								log.Errorf("Skipping frontend %s...", frontendName)
 								continue frontend
 							}
-							backend2FrontendMap[url.String()] = frontendName
-							log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-							if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-								log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+							roundTripper = transport
+							backendFwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader), forward.ErrorHandler(middlewares.NetErrorHandler), forward.RoundTripper(transport))
+							if err != nil {
+								log.Errorf("Error creating forwarder for backend %s: %v", backendName, err)
 								log.Errorf("Skipping frontend %s...", frontendName)
 								continue frontend
 							}
-							hcOpts := parseHealthCheckOptions(rebalancer, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, *globalConfiguration.HealthCheck)
+							backendFwd.TLSClientConfig = transport.TLSClientConfig
+							forwarder = backendFwd
+							if transportConfig.ProxyProtocolVersion > 0 {
+								forwarder = proxyprotocol.SourceAddrHandler(forwarder)
+							}
+						}
+						// the load balancers of the backend on all the entrypoints share
+						// the same passive health check
+						passiveHealthCheck := backendsPassiveHealthCheck[backendName]
+						if passiveHealthCheck == nil {
+							passiveHealthCheck = parsePassiveHealthCheckOptions(backendName, configuration.Backends[backendName].PassiveHealthCheck)
+						}
+						if passiveHealthCheck != nil {
+							log.Debugf("Setting up backend passive health check %s", passiveHealthCheck.PassiveOptions)
+							forwarder = passiveHealthCheck.Handler(forwarder)
+							backendsPassiveHealthCheck[backendName] = passiveHealthCheck
+						}
+						saveBackend := accesslog.NewSaveBackend(forwarder, backendName)
+						saveFrontend := accesslog.NewSaveFrontend(saveBackend, frontendName)
+						var lbNext http.Handler = saveFrontend
+						stickiness := parseStickinessOptions(backendName, configuration.Backends[backendName].LoadBalancer)
+						if stickiness != nil {
+							log.Debugf("Sticky session with cookie %v", stickiness.CookieName)
+							lbNext = stickiness.Stick(saveFrontend)
+						}
+						rr, _ := roundrobin.New(lbNext)
+
+						lbMethod, err := types.NewLoadBalancerMethod(configuration.Backends[backendName].LoadBalancer)
+						if err != nil {
+							log.Errorf("Error loading load balancer method '%+v' for frontend %s: %v", configuration.Backends[backendName].LoadBalancer, frontendName, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+
+						var lb http.Handler
+						switch lbMethod {
+						case types.Drr:
+							log.Debugf("Creating load-balancer drr")
+							rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
+							lb = rebalancer
+							if passiveHealthCheck != nil {
+								passiveHealthCheck.AddLoadBalancer(rebalancer)
+							}
+							if stickiness != nil {
+								stickiness.LB = rebalancer
+							}
+							for serverName, server := range configuration.Backends[backendName].Servers {
+								url, err := url.Parse(server.URL)
+								if err != nil {
+									log.Errorf("Error parsing server URL %s: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
+								if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								if passiveHealthCheck != nil {
+									passiveHealthCheck.AddServer(url, server.Weight)
+								}
+								hcOpts := parseHealthCheckOptions(rebalancer, backendName, configuration.Backends[backendName].HealthCheck, *globalConfiguration.HealthCheck)
+								if hcOpts != nil {
+									log.Debugf("Setting up backend health check %s", *hcOpts)
+									hcOpts.Transport = roundTripper
+									backendsHealthcheck[backendName] = healthcheck.NewBackendHealthCheck(*hcOpts)
+								}
+							}
+						case types.Wrr:
+							log.Debugf("Creating load-balancer wrr")
+							lb = rr
+							if passiveHealthCheck != nil {
+								passiveHealthCheck.AddLoadBalancer(rr)
+							}
+							if stickiness != nil {
+								stickiness.LB = rr
+							}
+							for serverName, server := range configuration.Backends[backendName].Servers {
+								url, err := url.Parse(server.URL)
+								if err != nil {
+									log.Errorf("Error parsing server URL %s: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
+								if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								if passiveHealthCheck != nil {
+									passiveHealthCheck.AddServer(url, server.Weight)
+								}
+							}
+							hcOpts := parseHealthCheckOptions(rr, backendName, configuration.Backends[backendName].HealthCheck, *globalConfiguration.HealthCheck)
 							if hcOpts != nil {
 								log.Debugf("Setting up backend health check %s", *hcOpts)
-								backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
+								hcOpts.Transport = roundTripper
+								backendsHealthcheck[backendName] = healthcheck.NewBackendHealthCheck(*hcOpts)
+							}
+						case types.LeastConn, types.P2C, types.ConsistentHash:
+							log.Debugf("Creating load-balancer %s", configuration.Backends[backendName].LoadBalancer.Method)
+							balancer, err := buildLoadBalancer(lbMethod, lbNext, configuration.Backends[backendName].LoadBalancer)
+							if err != nil {
+								log.Errorf("Error creating load-balancer for frontend %s: %v", frontendName, err)
+								log.Errorf("Skipping frontend %s...", frontendName)
+								continue frontend
+							}
+							lb = balancer
+							if passiveHealthCheck != nil {
+								passiveHealthCheck.AddLoadBalancer(balancer)
+							}
+							if stickiness != nil {
+								stickiness.LB = balancer
+							}
+							for serverName, server := range configuration.Backends[backendName].Servers {
+								url, err := url.Parse(server.URL)
+								if err != nil {
+									log.Errorf("Error parsing server URL %s: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
+								if err := balancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+									log.Errorf("Skipping frontend %s...", frontendName)
+									continue frontend
+								}
+								if passiveHealthCheck != nil {
+									passiveHealthCheck.AddServer(url, server.Weight)
+								}
+							}
+							hcOpts := parseHealthCheckOptions(balancer, backendName, configuration.Backends[backendName].HealthCheck, *globalConfiguration.HealthCheck)
+							if hcOpts != nil {
+								log.Debugf("Setting up backend health check %s", *hcOpts)
+								hcOpts.Transport = roundTripper
+								backendsHealthcheck[backendName] = healthcheck.NewBackendHealthCheck(*hcOpts)
 							}
 						}
-					case types.Wrr:
-						log.Debugf("Creating load-balancer wrr")
-						if stickysession {
-							log.Debugf("Sticky session with cookie %v", cookiename)
-							rr, _ = roundrobin.New(saveFrontend, roundrobin.EnableStickySession(sticky))
+						if stickiness != nil {
+							lb = stickiness.Handler(lb, lbNext)
 						}
-						lb = rr
-						for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-							url, err := url.Parse(server.URL)
+						maxConns := configuration.Backends[backendName].MaxConn
+						if maxConns != nil && maxConns.Amount != 0 {
+							extractFunc, err := middlewares.NewExtractor(maxConns.ExtractorFunc)
 							if err != nil {
-								log.Errorf("Error parsing server URL %s: %v", server.URL, err)
+								log.Errorf("Error creating connlimit: %v", err)
 								log.Errorf("Skipping frontend %s...", frontendName)
 								continue frontend
 							}
-							backend2FrontendMap[url.String()] = frontendName
-							log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-							if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-								log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+							log.Debugf("Creating load-balancer connlimit")
+							lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
+							if err != nil {
+								log.Errorf("Error creating connlimit: %v", err)
 								log.Errorf("Skipping frontend %s...", frontendName)
 								continue frontend
 							}
 						}
-						hcOpts := parseHealthCheckOptions(rr, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, *globalConfiguration.HealthCheck)
-						if hcOpts != nil {
-							log.Debugf("Setting up backend health check %s", *hcOpts)
-							backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
-						}
-					}
-					maxConns := configuration.Backends[frontend.Backend].MaxConn
-					if maxConns != nil && maxConns.Amount != 0 {
-						extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
-						if err != nil {
-							log.Errorf("Error creating connlimit: %v", err)
-							log.Errorf("Skipping frontend %s...", frontendName)
-							continue frontend
-						}
-						log.Debugf("Creating load-balancer connlimit")
-						lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
-						if err != nil {
-							log.Errorf("Error creating connlimit: %v", err)
-							log.Errorf("Skipping frontend %s...", frontendName)
-							continue frontend
-						}
-					}
-					// retry ?
-					if globalConfiguration.Retry != nil {
-						retries := len(configuration.Backends[frontend.Backend].Servers)
-						if globalConfiguration.Retry.Attempts > 0 {
-							retries = globalConfiguration.Retry.Attempts
+						// retry ?
+						if retry := mergeRetry(globalConfiguration.Retry, configuration.Backends[backendName].Retry); retry != nil {
+							if retry.Attempts <= 0 {
+								retry.Attempts = len(configuration.Backends[backendName].Servers)
+							}
+							lb = middlewares.NewRetry(lb, *retry)
+							log.Debugf("Creating retries max attempts %d", retry.Attempts)
 						}
-						lb = middlewares.NewRetry(retries, lb)
-						log.Debugf("Creating retries max attempts %d", retries)
-					}
 
-					if server.globalConfiguration.Web != nil && server.globalConfiguration.Web.Metrics != nil {
-						if server.globalConfiguration.Web.Metrics.Prometheus != nil {
-							metricsMiddlewareBackend := middlewares.NewMetricsWrapper(middlewares.NewPrometheus(frontend.Backend, server.globalConfiguration.Web.Metrics.Prometheus))
-							negroni.Use(metricsMiddlewareBackend)
-						}
-					}
-					if len(frontend.BasicAuth) > 0 {
-						users := types.Users{}
-						for _, user := range frontend.BasicAuth {
-							users = append(users, user)
+						if server.globalConfiguration.Web != nil && server.globalConfiguration.Web.Metrics != nil {
+							if server.globalConfiguration.Web.Metrics.Prometheus != nil {
+								metricsMiddlewareBackend := middlewares.NewMetricsWrapper(middlewares.NewPrometheus(backendName, server.globalConfiguration.Web.Metrics.Prometheus))
+								negroni.Use(metricsMiddlewareBackend)
+							}
 						}
+						if len(frontend.BasicAuth) > 0 {
+							users := types.Users{}
+							for _, user := range frontend.BasicAuth {
+								users = append(users, user)
+							}
 
-						auth := &types.Auth{}
-						auth.Basic = &types.Basic{
-							Users: users,
-						}
-						authMiddleware, err := middlewares.NewAuthenticator(auth)
-						if err != nil {
-							log.Fatal("Error creating Auth: ", err)
+							auth := &types.Auth{}
+							auth.Basic = &types.Basic{
+								Users: users,
+							}
+							authMiddleware, err := middlewares.NewAuthenticator(auth)
+							if err != nil {
+								log.Fatal("Error creating Auth: ", err)
+							}
+							negroni.Use(authMiddleware)
+							mirrorNegroni.Use(authMiddleware)
 						}
-						negroni.Use(authMiddleware)
-					}
-					if configuration.Backends[frontend.Backend].CircuitBreaker != nil {
-						log.Debugf("Creating circuit breaker %s", configuration.Backends[frontend.Backend].CircuitBreaker.Expression)
-						cbreaker, err := middlewares.NewCircuitBreaker(lb, configuration.Backends[frontend.Backend].CircuitBreaker.Expression, cbreaker.Logger(oxyLogger))
-						if err != nil {
-							log.Errorf("Error creating circuit breaker: %v", err)
-							log.Errorf("Skipping frontend %s...", frontendName)
-							continue frontend
+						if configuration.Backends[backendName].CircuitBreaker != nil {
+							log.Debugf("Creating circuit breaker %s", configuration.Backends[backendName].CircuitBreaker.Expression)
+							cbreaker, err := middlewares.NewCircuitBreaker(lb, configuration.Backends[backendName].CircuitBreaker.Expression, cbreaker.Logger(oxyLogger))
+							if err != nil {
+								log.Errorf("Error creating circuit breaker: %v", err)
+								log.Errorf("Skipping frontend %s...", frontendName)
+								continue frontend
+							}
+							negroni.Use(cbreaker)
+							mirrorNegroni.Use(cbreaker)
+						} else {
+							negroni.UseHandler(lb)
+							mirrorNegroni.UseHandler(lb)
 						}
-						negroni.Use(cbreaker)
+						backends[entryPointName+backendName] = negroni
+						mirrorBackends[entryPointName+backendName] = mirrorNegroni
 					} else {
-						negroni.UseHandler(lb)
+						log.Debugf("Reusing backend %s", backendName)
 					}
-					backends[entryPointName+frontend.Backend] = negroni
-				} else {
-					log.Debugf("Reusing backend %s", frontend.Backend)
 				}
 				if frontend.Priority > 0 {
 					newServerRoute.route.Priority(frontend.Priority)
 				}
-				server.wireFrontendBackend(newServerRoute, backends[entryPointName+frontend.Backend])
 
-				err := newServerRoute.route.GetError()
+				backendHandler, err := buildWeightedBackendsHandler(frontendName, frontend, entryPointName, backends)
+				if err != nil {
+					log.Errorf("Error creating weighted backends for frontend %s: %v", frontendName, err)
+					log.Errorf("Skipping frontend %s...", frontendName)
+					continue frontend
+				}
+				handler, err := server.buildFrontendHandler(frontendName, frontend, entryPointName, entryPoint, backends, mirrorBackends, backendHandler)
+				if err != nil {
+					log.Errorf("Error creating middlewares for frontend %s: %v", frontendName, err)
+					log.Errorf("Skipping frontend %s...", frontendName)
+					continue frontend
+				}
+				server.wireFrontendBackend(newServerRoute, handler)
+
+				err = newServerRoute.route.GetError()
 				if err != nil {
 					log.Errorf("Error building route: %s", err)
 				}
@@ -772,14 +992,186 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 		}
 	}
 	healthcheck.GetHealthCheck().SetBackendsConfiguration(server.routinesPool.Ctx(), backendsHealthcheck)
-	middlewares.SetBackend2FrontendMap(&backend2FrontendMap)
+	healthcheck.GetHealthCheck().SetPassiveBackends(backendsPassiveHealthCheck)
 	//sort routes
 	for _, serverEntryPoint := range serverEntryPoints {
 		serverEntryPoint.httpRouter.GetHandler().SortRoutes()
 	}
+	for entryPointName, certs := range loadEntryPointsCertificates(configurations, globalConfiguration) {
+		serverEntryPoints[entryPointName].certs.Set(certs)
+	}
 	return serverEntryPoints, nil
 }
 
+// frontendBackendNames returns the names of the backends used by the frontend,
+// the ones it forwards requests to followed by the ones of its error pages and
+// of its mirror, which are built the same way
+func frontendBackendNames(frontend *types.Frontend) []string {
+	names := frontend.BackendNames()
+	used := map[string]bool{}
+	for _, name := range names {
+		used[name] = true
+	}
+	var others []string
+	for _, errorPage := range frontend.Errors {
+		if !used[errorPage.Backend] {
+			used[errorPage.Backend] = true
+			others = append(others, errorPage.Backend)
+		}
+	}
+	if frontend.Mirror != nil && !used[frontend.Mirror.Backend] {
+		others = append(others, frontend.Mirror.Backend)
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// buildFrontendHandler wraps the backend handler with the middlewares configured
+// on the frontend. Unlike the backend handler, they are not shared between frontends.
+// The backends of the error pages and of the mirror are looked up in the backends
+// built for the entrypoint, the mirror ones being built without the backend metrics.
+func (server *Server) buildFrontendHandler(frontendName string, frontend *types.Frontend, entryPointName string, entryPoint *EntryPoint, backends map[string]http.Handler, mirrorBackends map[string]http.Handler, backendHandler http.Handler) (http.Handler, error) {
+	var frontendMiddlewares []negroni.Handler
+
+	if frontend.Buffering != nil {
+		buffering, err := middlewares.NewBuffering(backendHandler, frontend.Buffering)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Adding buffering to frontend %s", frontendName)
+		backendHandler = buffering
+	}
+
+	// sort error page names to get a predictable order
+	var errorPageNames []string
+	for errorPageName := range frontend.Errors {
+		errorPageNames = append(errorPageNames, errorPageName)
+	}
+	sort.Strings(errorPageNames)
+	for _, errorPageName := range errorPageNames {
+		errorPage := frontend.Errors[errorPageName]
+		errorBackendHandler, ok := backends[entryPointName+errorPage.Backend]
+		if !ok {
+			return nil, fmt.Errorf("undefined backend '%s' for error page %s", errorPage.Backend, errorPageName)
+		}
+		errorPagesHandler, err := middlewares.NewErrorPagesHandler(errorPage, errorBackendHandler)
+		if err != nil {
+			return nil, fmt.Errorf("error page %s: %v", errorPageName, err)
+		}
+		log.Debugf("Adding error page %s for frontend %s", errorPageName, frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, errorPagesHandler)
+	}
+
+	if len(frontend.WhitelistSourceRange) > 0 {
+		ipWhitelister, err := middlewares.NewIPWhitelister(frontend.WhitelistSourceRange, entryPoint.WhitelistTrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Adding IP whitelist middleware for frontend %s", frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, ipWhitelister)
+	}
+
+	if frontend.RateLimit != nil {
+		rateLimiter, err := middlewares.NewRateLimiter(frontend.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Adding rate limit middleware for frontend %s", frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, rateLimiter)
+	}
+
+	if frontend.Redirect != nil {
+		regex, replacement, err := server.buildRedirectRegex(frontend.Redirect.EntryPoint, frontend.Redirect.Regex, frontend.Redirect.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		redirect, err := middlewares.NewRedirect(regex, replacement, frontend.Redirect.Permanent)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Adding redirect middleware for frontend %s: %s -> %s", frontendName, regex, replacement)
+		frontendMiddlewares = append(frontendMiddlewares, redirect)
+	}
+
+	// the SSL redirect and the allowed hosts are enforced before any authentication
+	if secureMiddleware := middlewares.NewSecure(frontend.Headers); secureMiddleware != nil {
+		log.Debugf("Adding secure middleware for frontend %s", frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, secureMiddleware)
+	}
+
+	if frontend.Auth != nil {
+		authMiddleware, err := middlewares.NewAuthenticator(frontend.Auth)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Adding auth middleware for frontend %s", frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, authMiddleware)
+	}
+
+	if headerMiddleware := middlewares.NewHeaderFromStruct(frontend.Headers); headerMiddleware != nil {
+		log.Debugf("Adding header middleware for frontend %s", frontendName)
+		frontendMiddlewares = append(frontendMiddlewares, headerMiddleware)
+	}
+
+	if frontend.Mirror != nil {
+		mirrorBackendHandler, ok := mirrorBackends[entryPointName+frontend.Mirror.Backend]
+		if !ok {
+			return nil, fmt.Errorf("undefined backend '%s' for mirror", frontend.Mirror.Backend)
+		}
+		if server.globalConfiguration.Web != nil && server.globalConfiguration.Web.Metrics != nil && server.globalConfiguration.Web.Metrics.Prometheus != nil {
+			// mirrored requests are only accounted apart from the live traffic of the backend
+			n := negroni.New(middlewares.NewMetricsWrapper(middlewares.NewPrometheus("mirror-"+frontend.Mirror.Backend, server.globalConfiguration.Web.Metrics.Prometheus)))
+			n.UseHandler(mirrorBackendHandler)
+			mirrorBackendHandler = n
+		}
+		log.Debugf("Adding mirror middleware for frontend %s to backend %s", frontendName, frontend.Mirror.Backend)
+		// all the requests are mirrored unless a percentage is set, zero included
+		percent := 100
+		if frontend.Mirror.Percent != nil {
+			percent = *frontend.Mirror.Percent
+		}
+		frontendMiddlewares = append(frontendMiddlewares, middlewares.NewMirror(mirrorBackendHandler, percent, frontend.Mirror.MaxBodySize))
+	}
+
+	if len(frontendMiddlewares) == 0 {
+		return backendHandler, nil
+	}
+	n := negroni.New(frontendMiddlewares...)
+	n.UseHandler(backendHandler)
+	return n, nil
+}
+
+// buildWeightedBackendsHandler returns the handler of the frontend backend, or
+// splits the traffic between the handlers of the weighted backends of the frontend
+func buildWeightedBackendsHandler(frontendName string, frontend *types.Frontend, entryPointName string, backends map[string]http.Handler) (http.Handler, error) {
+	if frontend.WeightedBackends == nil || len(frontend.WeightedBackends.Backends) == 0 {
+		return backends[entryPointName+frontend.Backend], nil
+	}
+
+	cookieName := ""
+	if frontend.WeightedBackends.Sticky {
+		cookieName = frontend.WeightedBackends.CookieName
+		if len(cookieName) == 0 {
+			cookieName = loadbalancer.DefaultFrontendCookieName(frontendName)
+		}
+	}
+	weightedBackends := loadbalancer.NewWeightedBackends(cookieName)
+	total := 0
+	for _, backendName := range frontend.BackendNames() {
+		weight := frontend.WeightedBackends.Backends[backendName]
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight %d for backend %s", weight, backendName)
+		}
+		log.Debugf("Adding backend %s with weight %d to frontend %s", backendName, weight, frontendName)
+		weightedBackends.AddBackend(backendName, weight, backends[entryPointName+backendName])
+		total += weight
+	}
+	if total == 0 {
+		return nil, errors.New("all the backends have a zero weight")
+	}
+	return weightedBackends, nil
+}
+
 func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http.Handler) {
 	// add prefix
 	if len(serverRoute.addPrefix) > 0 {
@@ -814,23 +1206,9 @@ func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http
 }
 
 func (server *Server) loadEntryPointConfig(entryPointName string, entryPoint *EntryPoint) (negroni.Handler, error) {
-	regex := entryPoint.Redirect.Regex
-	replacement := entryPoint.Redirect.Replacement
-	if len(entryPoint.Redirect.EntryPoint) > 0 {
-		regex = "^(?:https?:\\/\\/)?([\\w\\._-]+)(?::\\d+)?(.*)$"
-		if server.globalConfiguration.EntryPoints[entryPoint.Redirect.EntryPoint] == nil {
-			return nil, errors.New("Unknown entrypoint " + entryPoint.Redirect.EntryPoint)
-		}
-		protocol := "http"
-		if server.globalConfiguration.EntryPoints[entryPoint.Redirect.EntryPoint].TLS != nil {
-			protocol = "https"
-		}
-		r, _ := regexp.Compile("(:\\d+)")
-		match := r.FindStringSubmatch(server.globalConfiguration.EntryPoints[entryPoint.Redirect.EntryPoint].Address)
-		if len(match) == 0 {
-			return nil, errors.New("Bad Address format: " + server.globalConfiguration.EntryPoints[entryPoint.Redirect.EntryPoint].Address)
-		}
-		replacement = protocol + "://$1" + match[0] + "$2"
+	regex, replacement, err := server.buildRedirectRegex(entryPoint.Redirect.EntryPoint, entryPoint.Redirect.Regex, entryPoint.Redirect.Replacement)
+	if err != nil {
+		return nil, err
 	}
 	rewrite, err := middlewares.NewRewrite(regex, replacement, true)
 	if err != nil {
@@ -841,6 +1219,28 @@ func (server *Server) loadEntryPointConfig(entryPointName string, entryPoint *En
 	return rewrite, nil
 }
 
+// buildRedirectRegex returns the regex and replacement of a redirection, those
+// of the target entry point take precedence when one is given
+func (server *Server) buildRedirectRegex(entryPointName, regex, replacement string) (string, string, error) {
+	if len(entryPointName) == 0 {
+		return regex, replacement, nil
+	}
+	entryPoint := server.globalConfiguration.EntryPoints[entryPointName]
+	if entryPoint == nil {
+		return "", "", errors.New("Unknown entrypoint " + entryPointName)
+	}
+	protocol := "http"
+	if entryPoint.TLS != nil {
+		protocol = "https"
+	}
+	r, _ := regexp.Compile("(:\\d+)")
+	match := r.FindStringSubmatch(entryPoint.Address)
+	if len(match) == 0 {
+		return "", "", errors.New("Bad Address format: " + entryPoint.Address)
+	}
+	return "^(?:https?:\\/\\/)?([\\w\\._-]+)(?::\\d+)?(.*)$", protocol + "://$1" + match[0] + "$2", nil
+}
+
 func (server *Server) buildDefaultHTTPRouter() *mux.Router {
 	router := mux.NewRouter()
 	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
@@ -849,6 +1249,29 @@ func (server *Server) buildDefaultHTTPRouter() *mux.Router {
 	return router
 }
 
+// balancer is a load balancer of the loadbalancer package
+type balancer interface {
+	http.Handler
+	healthcheck.LoadBalancer
+}
+
+func buildLoadBalancer(lbMethod types.LoadBalancerMethod, next http.Handler, config *types.LoadBalancer) (balancer, error) {
+	switch lbMethod {
+	case types.LeastConn:
+		return loadbalancer.NewLeastConn(next), nil
+	case types.P2C:
+		return loadbalancer.NewP2C(next), nil
+	case types.ConsistentHash:
+		hashKey := loadbalancer.DefaultHashKey
+		if config != nil && len(config.HashKey) > 0 {
+			hashKey = config.HashKey
+		}
+		log.Debugf("Hashing requests on %s", hashKey)
+		return loadbalancer.NewConsistentHash(next, hashKey)
+	}
+	return nil, fmt.Errorf("unsupported load-balancer method %d", lbMethod)
+}
+
 func parseHealthCheckOptions(lb healthcheck.LoadBalancer, backend string, hc *types.HealthCheck, hcConfig HealthCheckConfig) *healthcheck.Options {
 	if hc == nil || hc.Path == "" {
 		return nil
@@ -867,11 +1290,128 @@ func parseHealthCheckOptions(lb healthcheck.LoadBalancer, backend string, hc *ty
 		}
 	}
 
+	var timeout time.Duration
+	if hc.Timeout != "" {
+		timeoutOverride, err := time.ParseDuration(hc.Timeout)
+		switch {
+		case err != nil:
+			log.Errorf("Illegal healthcheck timeout for backend '%s': %s", backend, err)
+		case timeoutOverride <= 0:
+			log.Errorf("Healthcheck timeout smaller than zero for backend '%s'", backend)
+		default:
+			timeout = timeoutOverride
+		}
+	}
+
+	var statusRange [2]int
+	if hc.Status != "" {
+		var err error
+		statusRange, err = types.ParseHTTPCodeRange(hc.Status)
+		if err != nil {
+			log.Errorf("Illegal healthcheck status for backend '%s': %s", backend, err)
+		}
+	}
+
 	return &healthcheck.Options{
-		Path:     hc.Path,
-		Interval: interval,
-		LB:       lb,
+		Path:               hc.Path,
+		Port:               hc.Port,
+		Scheme:             hc.Scheme,
+		Hostname:           hc.Hostname,
+		Headers:            hc.Headers,
+		Interval:           interval,
+		Timeout:            timeout,
+		StatusRange:        statusRange,
+		HealthyThreshold:   hc.HealthyThreshold,
+		UnhealthyThreshold: hc.UnhealthyThreshold,
+		LB:                 lb,
+	}
+}
+
+// mergeRetry returns the retry configuration of a backend, its fields
+// overriding the global ones, nil when the requests are not retried
+func mergeRetry(global *types.Retry, backend *types.Retry) *types.Retry {
+	if global == nil && backend == nil {
+		return nil
+	}
+	retry := &types.Retry{}
+	if global != nil {
+		*retry = *global
+	}
+	if backend == nil {
+		return retry
+	}
+	if backend.Attempts != 0 {
+		retry.Attempts = backend.Attempts
+	}
+	if len(backend.Methods) > 0 {
+		retry.Methods = backend.Methods
+	}
+	if backend.InitialInterval != 0 {
+		retry.InitialInterval = backend.InitialInterval
 	}
+	if backend.MaxInterval != 0 {
+		retry.MaxInterval = backend.MaxInterval
+	}
+	if backend.Budget != 0 {
+		retry.Budget = backend.Budget
+	}
+	return retry
+}
+
+func parseStickinessOptions(backend string, loadBalancer *types.LoadBalancer) *loadbalancer.Stickiness {
+	if loadBalancer == nil || (!loadBalancer.Sticky && loadBalancer.Stickiness == nil) {
+		return nil
+	}
+	if loadBalancer.Stickiness == nil {
+		return loadbalancer.NewStickiness(backend, loadbalancer.StickinessOptions{})
+	}
+
+	options := loadbalancer.StickinessOptions{
+		CookieName: loadBalancer.Stickiness.CookieName,
+		Secure:     loadBalancer.Stickiness.Secure,
+		HTTPOnly:   loadBalancer.Stickiness.HTTPOnly,
+		MaxAge:     loadBalancer.Stickiness.MaxAge,
+		HashServer: loadBalancer.Stickiness.HashServer,
+	}
+	switch strings.ToLower(loadBalancer.Stickiness.SameSite) {
+	case "":
+	case "lax":
+		options.SameSite = "Lax"
+	case "strict":
+		options.SameSite = "Strict"
+	case "none":
+		options.SameSite = "None"
+	default:
+		log.Errorf("Invalid SameSite value %q for sticky sessions of backend %s, ignoring it", loadBalancer.Stickiness.SameSite, backend)
+	}
+	return loadbalancer.NewStickiness(backend, options)
+}
+
+func parsePassiveHealthCheckOptions(backend string, phc *types.PassiveHealthCheck) *healthcheck.PassiveHealthCheck {
+	if phc == nil {
+		return nil
+	}
+
+	options := healthcheck.PassiveOptions{
+		ConsecutiveErrors:  phc.ConsecutiveErrors,
+		MaxEjectionPercent: phc.MaxEjectionPercent,
+	}
+	if phc.BaseEjectionTime != "" {
+		baseEjectionTime, err := time.ParseDuration(phc.BaseEjectionTime)
+		if err != nil {
+			log.Errorf("Illegal passive health check base ejection time for backend '%s': %s", backend, err)
+		}
+		options.BaseEjectionTime = baseEjectionTime
+	}
+	if phc.MaxEjectionTime != "" {
+		maxEjectionTime, err := time.ParseDuration(phc.MaxEjectionTime)
+		if err != nil {
+			log.Errorf("Illegal passive health check max ejection time for backend '%s': %s", backend, err)
+		}
+		options.MaxEjectionTime = maxEjectionTime
+	}
+
+	return healthcheck.NewPassiveHealthCheck(backend, options)
 }
 
 func getRoute(serverRoute *serverRoute, route *types.Route) error {
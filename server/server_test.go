@@ -2,6 +2,8 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -9,7 +11,10 @@ import (
 
 	"github.com/containous/flaeg"
 	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/loadbalancer"
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/types"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/vulcand/oxy/roundrobin"
 )
 
@@ -144,6 +149,46 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 				LB:       lb,
 			},
 		},
+		{
+			desc: "request options",
+			hc: &types.HealthCheck{
+				Path:               "/path",
+				Port:               8080,
+				Scheme:             "https",
+				Hostname:           "health.localhost",
+				Headers:            map[string]string{"X-Health": "check"},
+				Timeout:            "3s",
+				Status:             "200-299",
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+			wantOpts: &healthcheck.Options{
+				Path:               "/path",
+				Port:               8080,
+				Scheme:             "https",
+				Hostname:           "health.localhost",
+				Headers:            map[string]string{"X-Health": "check"},
+				Interval:           globalInterval,
+				Timeout:            3 * time.Second,
+				StatusRange:        [2]int{200, 299},
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+				LB:                 lb,
+			},
+		},
+		{
+			desc: "unparseable timeout and status",
+			hc: &types.HealthCheck{
+				Path:    "/path",
+				Timeout: "unparseable",
+				Status:  "2xx",
+			},
+			wantOpts: &healthcheck.Options{
+				Path:     "/path",
+				Interval: globalInterval,
+				LB:       lb,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -158,3 +203,665 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestServerParseStickinessOptions(t *testing.T) {
+	tests := []struct {
+		desc         string
+		loadBalancer *types.LoadBalancer
+		wantOpts     *loadbalancer.StickinessOptions
+	}{
+		{
+			desc:         "nil load balancer",
+			loadBalancer: nil,
+			wantOpts:     nil,
+		},
+		{
+			desc:         "no sticky sessions",
+			loadBalancer: &types.LoadBalancer{Method: "wrr"},
+			wantOpts:     nil,
+		},
+		{
+			desc:         "sticky",
+			loadBalancer: &types.LoadBalancer{Sticky: true},
+			wantOpts: &loadbalancer.StickinessOptions{
+				CookieName: "_TRAEFIK_BACKEND_backend-foo",
+			},
+		},
+		{
+			desc: "stickiness",
+			loadBalancer: &types.LoadBalancer{
+				Stickiness: &types.Stickiness{
+					CookieName: "foo",
+					Secure:     true,
+					HTTPOnly:   true,
+					SameSite:   "Lax",
+					MaxAge:     60,
+					HashServer: true,
+				},
+			},
+			wantOpts: &loadbalancer.StickinessOptions{
+				CookieName: "foo",
+				Secure:     true,
+				HTTPOnly:   true,
+				SameSite:   "Lax",
+				MaxAge:     60,
+				HashServer: true,
+			},
+		},
+		{
+			desc: "invalid same site",
+			loadBalancer: &types.LoadBalancer{
+				Stickiness: &types.Stickiness{
+					SameSite: "loose",
+				},
+			},
+			wantOpts: &loadbalancer.StickinessOptions{
+				CookieName: "_TRAEFIK_BACKEND_backend-foo",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			stickiness := parseStickinessOptions("backend-foo", test.loadBalancer)
+			var gotOpts *loadbalancer.StickinessOptions
+			if stickiness != nil {
+				gotOpts = &stickiness.StickinessOptions
+			}
+			if !reflect.DeepEqual(gotOpts, test.wantOpts) {
+				t.Errorf("got stickiness options %+v, want %+v", gotOpts, test.wantOpts)
+			}
+		})
+	}
+}
+
+func TestServerMergeRetry(t *testing.T) {
+	tests := []struct {
+		desc      string
+		global    *types.Retry
+		backend   *types.Retry
+		wantRetry *types.Retry
+	}{
+		{
+			desc:      "no retry",
+			wantRetry: nil,
+		},
+		{
+			desc:      "global retry",
+			global:    &types.Retry{Attempts: 3, Budget: 20},
+			wantRetry: &types.Retry{Attempts: 3, Budget: 20},
+		},
+		{
+			desc:      "backend retry",
+			backend:   &types.Retry{Methods: types.HTTPMethods{"POST"}},
+			wantRetry: &types.Retry{Methods: types.HTTPMethods{"POST"}},
+		},
+		{
+			desc:    "backend overriding the global retry",
+			global:  &types.Retry{Attempts: 3, Budget: 20, InitialInterval: flaeg.Duration(time.Second)},
+			backend: &types.Retry{Attempts: 5, MaxInterval: flaeg.Duration(2 * time.Second)},
+			wantRetry: &types.Retry{
+				Attempts:        5,
+				Budget:          20,
+				InitialInterval: flaeg.Duration(time.Second),
+				MaxInterval:     flaeg.Duration(2 * time.Second),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			gotRetry := mergeRetry(test.global, test.backend)
+			if !reflect.DeepEqual(gotRetry, test.wantRetry) {
+				t.Errorf("got retry %+v, want %+v", gotRetry, test.wantRetry)
+			}
+		})
+	}
+}
+
+func TestServerBuildFrontendHandlerErrorPages(t *testing.T) {
+	backends := map[string]http.Handler{
+		"httperror": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "error page %s", r.URL.Path)
+		}),
+	}
+	backendHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	frontend := &types.Frontend{
+		Errors: map[string]*types.ErrorPage{
+			"server": {
+				Status:  []string{"500-599"},
+				Backend: "error",
+				Query:   "/{status}.html",
+			},
+		},
+	}
+
+	srv := NewServer(GlobalConfiguration{})
+	handler, err := srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, backends, nil, backendHandler)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest("GET", "http://example.com/foo", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", rw.Code, http.StatusServiceUnavailable)
+	}
+	if rw.Body.String() != "error page /503.html" {
+		t.Errorf("got body %q, want %q", rw.Body.String(), "error page /503.html")
+	}
+
+	frontend.Errors["server"].Backend = "undefined"
+	if _, err := srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, backends, nil, backendHandler); err == nil {
+		t.Error("expected an error for an undefined error backend")
+	}
+}
+
+func TestServerBuildFrontendHandlerSecureBeforeAuth(t *testing.T) {
+	backendHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "backend")
+	})
+
+	frontend := &types.Frontend{
+		Auth: &types.Auth{
+			Basic: &types.Basic{
+				Users: types.Users{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+			},
+		},
+		Headers: &types.Headers{
+			AllowedHosts: []string{"example.com"},
+			SSLRedirect:  true,
+		},
+	}
+
+	srv := NewServer(GlobalConfiguration{})
+	handler, err := srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, map[string]http.Handler{}, map[string]http.Handler{}, backendHandler)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	tests := []struct {
+		desc       string
+		url        string
+		wantStatus int
+	}{
+		{
+			desc:       "plain HTTP request redirected without credentials",
+			url:        "http://example.com/foo",
+			wantStatus: http.StatusMovedPermanently,
+		},
+		{
+			desc:       "disallowed host rejected without credentials",
+			url:        "https://other.com/foo",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "allowed request authenticated",
+			url:        "https://example.com/foo",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest("GET", test.url, nil))
+			if rw.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", rw.Code, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServerBuildFrontendHandlerMirror(t *testing.T) {
+	mirrored := make(chan string, 1)
+	mirrorBackends := map[string]http.Handler{
+		"httpmirror": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mirrored <- r.URL.Path
+			w.WriteHeader(http.StatusInternalServerError)
+		}),
+	}
+	backendHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "live")
+	})
+
+	frontend := &types.Frontend{
+		Mirror: &types.Mirror{
+			Backend: "mirror",
+		},
+	}
+
+	srv := NewServer(GlobalConfiguration{})
+	handler, err := srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, nil, mirrorBackends, backendHandler)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.RequestURI = req.URL.RequestURI()
+	handler.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK || rw.Body.String() != "live" {
+		t.Errorf("got status %d and body %q, want %d and %q", rw.Code, rw.Body.String(), http.StatusOK, "live")
+	}
+	select {
+	case path := <-mirrored:
+		if path != "/foo" {
+			t.Errorf("got mirrored path %s, want /foo", path)
+		}
+	case <-time.After(time.Second):
+		t.Error("request not mirrored")
+	}
+
+	// an explicit zero percent disables the mirroring
+	zero := 0
+	frontend.Mirror.Percent = &zero
+	handler, err = srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, nil, mirrorBackends, backendHandler)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	select {
+	case path := <-mirrored:
+		t.Errorf("got mirrored path %s, want no mirrored request", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	frontend.Mirror.Backend = "undefined"
+	if _, err := srv.buildFrontendHandler("frontend", frontend, "http", &EntryPoint{}, nil, mirrorBackends, backendHandler); err == nil {
+		t.Error("expected an error for an undefined mirror backend")
+	}
+}
+
+func TestServerLoadConfigErrorPagesBackend(t *testing.T) {
+	liveServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer liveServer.Close()
+	errorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "error page %s", r.URL.Path)
+	}))
+	defer errorServer.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		HealthCheck: &HealthCheckConfig{Interval: flaeg.Duration(5 * time.Second)},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {
+					EntryPoints: []string{"http"},
+					Backend:     "live",
+					Errors: map[string]*types.ErrorPage{
+						"server": {
+							Status:  []string{"500-599"},
+							Backend: "error",
+							Query:   "/{status}.html",
+						},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"live": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: liveServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+					},
+				},
+				"error": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: errorServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "drr",
+					},
+					HealthCheck: &types.HealthCheck{
+						Path: "/health",
+					},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.RequestURI = req.URL.RequestURI()
+	rw := httptest.NewRecorder()
+	srv.accessLoggerMiddleware.ServeHTTP(rw, req, entryPoints["http"].httpRouter.GetHandler().ServeHTTP)
+	if rw.Code != http.StatusServiceUnavailable || rw.Body.String() != "error page /503.html" {
+		t.Errorf("got status %d and body %q, want %d and %q", rw.Code, rw.Body.String(), http.StatusServiceUnavailable, "error page /503.html")
+	}
+	// the error backend is built like the others, with its health check
+	if _, ok := healthcheck.GetHealthCheck().Backends["error"]; !ok {
+		t.Error("health check of the error backend not set up")
+	}
+}
+
+func TestServerLoadConfigMirrorMetrics(t *testing.T) {
+	mirrored := make(chan struct{}, 1)
+	liveServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "live")
+	}))
+	defer liveServer.Close()
+	mirrorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored <- struct{}{}
+	}))
+	defer mirrorServer.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		Web: &WebProvider{
+			Metrics: &types.Metrics{
+				Prometheus: &types.Prometheus{},
+			},
+		},
+		HealthCheck: &HealthCheckConfig{Interval: flaeg.Duration(5 * time.Second)},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {
+					EntryPoints: []string{"http"},
+					Backend:     "metrics-live",
+					Mirror: &types.Mirror{
+						Backend: "metrics-mirror",
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"metrics-live": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: liveServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+					},
+				},
+				"metrics-mirror": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: mirrorServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+					},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.RequestURI = req.URL.RequestURI()
+	srv.accessLoggerMiddleware.ServeHTTP(httptest.NewRecorder(), req, entryPoints["http"].httpRouter.GetHandler().ServeHTTP)
+	select {
+	case <-mirrored:
+	case <-time.After(time.Second):
+		t.Fatal("request not mirrored")
+	}
+
+	// the mirrored request is only counted apart from the live traffic
+	wantRequests := map[string]float64{
+		"metrics-live":          1,
+		"metrics-mirror":        0,
+		"mirror-metrics-mirror": 1,
+	}
+	for service, want := range wantRequests {
+		var got float64
+		for i := 0; i < 20; i++ {
+			if got = countRequests(t, service); got == want {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got != want {
+			t.Errorf("got %v requests for service %s, want %v", got, service, want)
+		}
+	}
+}
+
+// countRequests returns the number of requests counted by the Prometheus
+// metrics of the service
+func countRequests(t *testing.T, service string) float64 {
+	metricFamilies, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	var count float64
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "traefik_requests_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "service" && label.GetValue() == service {
+					count += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestServerLoadConfigWeightedBackends(t *testing.T) {
+	var backendConfigs = map[string]*types.Backend{}
+	for _, version := range []string{"v1", "v2"} {
+		version := version
+		versionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, version)
+		}))
+		defer versionServer.Close()
+		backendConfigs[version] = &types.Backend{
+			Servers: map[string]types.Server{
+				"server": {
+					URL: versionServer.URL,
+				},
+			},
+			LoadBalancer: &types.LoadBalancer{
+				Method: "wrr",
+			},
+		}
+	}
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		HealthCheck: &HealthCheckConfig{Interval: flaeg.Duration(5 * time.Second)},
+	}
+	weightedBackends := &types.WeightedBackends{
+		Backends: map[string]int{"v1": 3, "v2": 1},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {
+					EntryPoints:      []string{"http"},
+					WeightedBackends: weightedBackends,
+				},
+			},
+			Backends: backendConfigs,
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	serve := func(handler http.Handler, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RequestURI = req.URL.RequestURI()
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		srv.accessLoggerMiddleware.ServeHTTP(rw, req, handler.ServeHTTP)
+		return rw
+	}
+
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	handler := entryPoints["http"].httpRouter.GetHandler()
+	versions := map[string]int{}
+	for i := 0; i < 8; i++ {
+		versions[serve(handler).Body.String()]++
+	}
+	if versions["v1"] != 6 || versions["v2"] != 2 {
+		t.Errorf("got requests per backend %v, want 6 on v1 and 2 on v2", versions)
+	}
+
+	weightedBackends.Sticky = true
+	entryPoints, err = srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	handler = entryPoints["http"].httpRouter.GetHandler()
+	rw := serve(handler)
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_TRAEFIK_FRONTEND_frontend" || cookies[0].Value != rw.Body.String() {
+		t.Fatalf("got cookies %v for backend %s, want a _TRAEFIK_FRONTEND_frontend cookie naming the backend", cookies, rw.Body.String())
+	}
+	for i := 0; i < 4; i++ {
+		if version := serve(handler, cookies[0]).Body.String(); version != cookies[0].Value {
+			t.Errorf("got backend %s, want the sticky backend %s", version, cookies[0].Value)
+		}
+	}
+}
+
+func TestServerPrepareServerTimeouts(t *testing.T) {
+	tests := []struct {
+		desc              string
+		timeouts          *EntryPointTimeouts
+		readTimeout       time.Duration
+		readHeaderTimeout time.Duration
+		writeTimeout      time.Duration
+		idleTimeout       time.Duration
+	}{
+		{
+			desc:        "global idle timeout",
+			idleTimeout: 180 * time.Second,
+		},
+		{
+			desc: "entrypoint timeouts",
+			timeouts: &EntryPointTimeouts{
+				ReadTimeout:       flaeg.Duration(10 * time.Second),
+				ReadHeaderTimeout: flaeg.Duration(2 * time.Second),
+				WriteTimeout:      flaeg.Duration(30 * time.Second),
+				IdleTimeout:       flaeg.Duration(60 * time.Second),
+			},
+			readTimeout:       10 * time.Second,
+			readHeaderTimeout: 2 * time.Second,
+			writeTimeout:      30 * time.Second,
+			idleTimeout:       60 * time.Second,
+		},
+		{
+			desc: "entrypoint timeouts without idle timeout",
+			timeouts: &EntryPointTimeouts{
+				ReadHeaderTimeout: flaeg.Duration(5 * time.Second),
+			},
+			readHeaderTimeout: 5 * time.Second,
+			idleTimeout:       180 * time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			srv := NewServer(GlobalConfiguration{IdleTimeout: flaeg.Duration(180 * time.Second)})
+			entryPoint := &EntryPoint{Address: ":8080", Timeouts: test.timeouts}
+			router := middlewares.NewHandlerSwitcher(srv.buildDefaultHTTPRouter())
+			httpServer, err := srv.prepareServer("http", router, entryPoint)
+			if err != nil {
+				t.Fatalf("got error: %s", err)
+			}
+			if httpServer.ReadTimeout != test.readTimeout {
+				t.Errorf("got read timeout %s, want %s", httpServer.ReadTimeout, test.readTimeout)
+			}
+			if httpServer.ReadHeaderTimeout != test.readHeaderTimeout {
+				t.Errorf("got read header timeout %s, want %s", httpServer.ReadHeaderTimeout, test.readHeaderTimeout)
+			}
+			if httpServer.WriteTimeout != test.writeTimeout {
+				t.Errorf("got write timeout %s, want %s", httpServer.WriteTimeout, test.writeTimeout)
+			}
+			if httpServer.IdleTimeout != test.idleTimeout {
+				t.Errorf("got idle timeout %s, want %s", httpServer.IdleTimeout, test.idleTimeout)
+			}
+		})
+	}
+}
+
+func TestServerStopRequestAcceptGraceTimeout(t *testing.T) {
+	srv := NewServer(GlobalConfiguration{
+		LifeCycle: &LifeCycle{RequestAcceptGraceTimeout: flaeg.Duration(200 * time.Millisecond)},
+	})
+	web := &WebProvider{server: srv}
+	ping := func() int {
+		rw := httptest.NewRecorder()
+		web.getPingHandler(rw, httptest.NewRequest("GET", "http://localhost/ping", nil))
+		return rw.Code
+	}
+
+	if code := ping(); code != http.StatusOK {
+		t.Fatalf("got ping status code %d before stopping, want %d", code, http.StatusOK)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.Stop()
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !srv.isTerminating() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if code := ping(); code != http.StatusServiceUnavailable {
+		t.Errorf("got ping status code %d while stopping, want %d", code, http.StatusServiceUnavailable)
+	}
+	select {
+	case <-stopped:
+		t.Error("server stopped before the request accept grace timeout")
+	default:
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not stopped after the request accept grace timeout")
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/proxyprotocol"
+	"github.com/containous/traefik/types"
+)
+
+// backendTransports keeps the transports of the backends between the
+// configuration reloads, so that their connections are reused
+type backendTransports struct {
+	transports map[string]*backendTransport
+	mutex      sync.Mutex
+}
+
+type backendTransport struct {
+	config    types.Transport
+	transport *http.Transport
+}
+
+// get returns the transport of a backend, building a new one when the backend
+// configuration changed
+func (bt *backendTransports) get(backendName string, config *types.Transport, globalConfiguration GlobalConfiguration) (*http.Transport, error) {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	if cached, ok := bt.transports[backendName]; ok {
+		if reflect.DeepEqual(cached.config, *config) {
+			return cached.transport, nil
+		}
+		cached.transport.CloseIdleConnections()
+		delete(bt.transports, backendName)
+	}
+
+	transport, err := newTransport(config, globalConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	if bt.transports == nil {
+		bt.transports = make(map[string]*backendTransport)
+	}
+	bt.transports[backendName] = &backendTransport{config: *config, transport: transport}
+	return transport, nil
+}
+
+// newTransport builds a transport with the settings of http.DefaultTransport,
+// overridden by the global and backend configurations
+func newTransport(config *types.Transport, globalConfiguration GlobalConfiguration) (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		DualStack: true,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   globalConfiguration.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: globalConfiguration.InsecureSkipVerify},
+	}
+
+	var err error
+	if len(config.DialTimeout) > 0 {
+		if dialer.Timeout, err = parseTransportDuration("dial timeout", config.DialTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.ResponseHeaderTimeout) > 0 {
+		if transport.ResponseHeaderTimeout, err = parseTransportDuration("response header timeout", config.ResponseHeaderTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.IdleConnTimeout) > 0 {
+		if transport.IdleConnTimeout, err = parseTransportDuration("idle connection timeout", config.IdleConnTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if config.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+
+	switch config.ProxyProtocolVersion {
+	case 0:
+	case 1, 2:
+		// The header carries the address of a single client, so the connections can't be shared between requests
+		dialer := &proxyprotocol.Dialer{Version: config.ProxyProtocolVersion, Dial: transport.DialContext}
+		transport.DialContext = dialer.DialContext
+		transport.DisableKeepAlives = true
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", config.ProxyProtocolVersion)
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := newTransportTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		if globalConfiguration.InsecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+func parseTransportDuration(name string, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("negative %s %s", name, value)
+	}
+	return duration, nil
+}
+
+func newTransportTLSConfig(config *types.TransportTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if len(config.RootCA) > 0 {
+		ca, _, err := readFileOrContent(config.RootCA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root CA: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid root CA: no PEM certificate found")
+		}
+	}
+
+	if len(config.CertFile) > 0 || len(config.KeyFile) > 0 {
+		cert, err := loadCertificate(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+func TestNewTransportTimeouts(t *testing.T) {
+	tests := []struct {
+		desc                  string
+		config                types.Transport
+		globalConfiguration   GlobalConfiguration
+		wantErr               bool
+		responseHeaderTimeout time.Duration
+		idleConnTimeout       time.Duration
+		maxIdleConnsPerHost   int
+	}{
+		{
+			desc:                "defaults",
+			globalConfiguration: GlobalConfiguration{MaxIdleConnsPerHost: 200},
+			idleConnTimeout:     90 * time.Second,
+			maxIdleConnsPerHost: 200,
+		},
+		{
+			desc: "backend settings",
+			config: types.Transport{
+				DialTimeout:           "2s",
+				ResponseHeaderTimeout: "5s",
+				IdleConnTimeout:       "1m",
+				MaxIdleConnsPerHost:   10,
+			},
+			globalConfiguration:   GlobalConfiguration{MaxIdleConnsPerHost: 200},
+			responseHeaderTimeout: 5 * time.Second,
+			idleConnTimeout:       time.Minute,
+			maxIdleConnsPerHost:   10,
+		},
+		{
+			desc:    "invalid duration",
+			config:  types.Transport{DialTimeout: "forever"},
+			wantErr: true,
+		},
+		{
+			desc:    "negative duration",
+			config:  types.Transport{ResponseHeaderTimeout: "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			transport, err := newTransport(&test.config, test.globalConfiguration)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if transport.ResponseHeaderTimeout != test.responseHeaderTimeout {
+				t.Errorf("got response header timeout %s, want %s", transport.ResponseHeaderTimeout, test.responseHeaderTimeout)
+			}
+			if transport.IdleConnTimeout != test.idleConnTimeout {
+				t.Errorf("got idle connection timeout %s, want %s", transport.IdleConnTimeout, test.idleConnTimeout)
+			}
+			if transport.MaxIdleConnsPerHost != test.maxIdleConnsPerHost {
+				t.Errorf("got max idle connections per host %d, want %d", transport.MaxIdleConnsPerHost, test.maxIdleConnsPerHost)
+			}
+		})
+	}
+}
+
+func TestNewTransportRootCA(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rootCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.TLS.Certificates[0].Certificate[0]}))
+	caFile, err := ioutil.TempFile("", "traefik-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	if _, err := caFile.WriteString(rootCA); err != nil {
+		t.Fatal(err)
+	}
+	caFile.Close()
+
+	tests := []struct {
+		desc    string
+		tls     *types.TransportTLS
+		wantErr bool
+	}{
+		{
+			desc:    "system roots",
+			wantErr: true,
+		},
+		{
+			desc: "root CA content",
+			tls:  &types.TransportTLS{RootCA: rootCA, ServerName: "example.com"},
+		},
+		{
+			desc: "root CA file",
+			tls:  &types.TransportTLS{RootCA: caFile.Name(), ServerName: "example.com"},
+		},
+		{
+			desc:    "wrong server name",
+			tls:     &types.TransportTLS{RootCA: rootCA, ServerName: "traefik.io"},
+			wantErr: true,
+		},
+		{
+			desc: "insecure skip verify",
+			tls:  &types.TransportTLS{InsecureSkipVerify: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			transport, err := newTransport(&types.Transport{TLS: test.tls}, GlobalConfiguration{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer transport.CloseIdleConnections()
+			client := &http.Client{Transport: transport}
+			resp, err := client.Get(backend.URL)
+			if test.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("got status code %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestNewTransportTLSConfigErrors(t *testing.T) {
+	tests := []struct {
+		desc string
+		tls  *types.TransportTLS
+	}{
+		{
+			desc: "invalid root CA",
+			tls:  &types.TransportTLS{RootCA: "not a certificate"},
+		},
+		{
+			desc: "missing client key",
+			tls:  &types.TransportTLS{CertFile: "not a certificate"},
+		},
+		{
+			desc: "invalid client certificate",
+			tls:  &types.TransportTLS{CertFile: "not a certificate", KeyFile: "not a key"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			if _, err := newTransportTLSConfig(test.tls); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestBackendTransportsReuse(t *testing.T) {
+	var transports backendTransports
+	config := &types.Transport{DialTimeout: "1s"}
+
+	first, err := transports.get("backend1", config, GlobalConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := transports.get("backend1", &types.Transport{DialTimeout: "1s"}, GlobalConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Error("expected the transport to be reused for an unchanged configuration")
+	}
+
+	other, err := transports.get("backend2", config, GlobalConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other == first {
+		t.Error("expected each backend to have its own transport")
+	}
+
+	changed, err := transports.get("backend1", &types.Transport{DialTimeout: "2s"}, GlobalConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed == first {
+		t.Error("expected a new transport for a changed configuration")
+	}
+}
+
+func TestNewTransportProxyProtocol(t *testing.T) {
+	tests := []struct {
+		desc    string
+		version int
+		wantErr bool
+	}{
+		{desc: "disabled"},
+		{desc: "v1", version: 1},
+		{desc: "v2", version: 2},
+		{desc: "unsupported version", version: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			transport, err := newTransport(&types.Transport{ProxyProtocolVersion: test.version}, GlobalConfiguration{})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if transport.DisableKeepAlives != (test.version > 0) {
+				t.Errorf("got keep-alives disabled %t with PROXY protocol version %d", transport.DisableKeepAlives, test.version)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/codegangsta/negroni"
 	"github.com/containous/mux"
 	"github.com/containous/traefik/autogen"
+	"github.com/containous/traefik/healthcheck"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/safe"
@@ -159,10 +160,11 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 }
 
 // healthResponse combines data returned by thoas/stats with statistics (if
-// they are enabled).
+// they are enabled) and the servers ejected by the passive health checks.
 type healthResponse struct {
 	*thoas_stats.Data
 	*middlewares.Stats
+	EjectedServers []healthcheck.EjectedServer `json:"ejected_servers,omitempty"`
 }
 
 func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, request *http.Request) {
@@ -170,11 +172,23 @@ func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, requ
 	if statsRecorder != nil {
 		health.Stats = statsRecorder.Data()
 	}
-	templatesRenderer.JSON(response, http.StatusOK, health)
+	health.EjectedServers = healthcheck.GetHealthCheck().EjectedServers()
+	templatesRenderer.JSON(response, provider.healthStatusCode(), health)
 }
 
 func (provider *WebProvider) getPingHandler(response http.ResponseWriter, request *http.Request) {
-	fmt.Fprint(response, "OK")
+	statusCode := provider.healthStatusCode()
+	response.WriteHeader(statusCode)
+	fmt.Fprint(response, http.StatusText(statusCode))
+}
+
+// healthStatusCode reports Traefik as unavailable once it is stopping, so that
+// the load balancers in front of it stop sending requests
+func (provider *WebProvider) healthStatusCode() int {
+	if provider.server != nil && provider.server.isTerminating() {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
 }
 
 func (provider *WebProvider) getConfigHandler(response http.ResponseWriter, request *http.Request) {
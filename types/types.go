@@ -4,9 +4,11 @@ import (
 	"encoding"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/containous/flaeg"
 	"github.com/docker/libkv/store"
 	"github.com/ryanuber/go-glob"
 )
@@ -18,6 +20,72 @@ type Backend struct {
 	LoadBalancer   *LoadBalancer     `json:"loadBalancer,omitempty"`
 	MaxConn        *MaxConn          `json:"maxConn,omitempty"`
 	HealthCheck    *HealthCheck      `json:"healthCheck,omitempty"`
+
+	PassiveHealthCheck *PassiveHealthCheck `json:"passiveHealthCheck,omitempty"`
+	Retry              *Retry              `json:"retry,omitempty"`
+	Transport          *Transport          `json:"transport,omitempty"`
+}
+
+// Transport holds the configuration of the connections to the servers of a backend
+type Transport struct {
+	DialTimeout           string        `json:"dialTimeout,omitempty"`
+	ResponseHeaderTimeout string        `json:"responseHeaderTimeout,omitempty"`
+	IdleConnTimeout       string        `json:"idleConnTimeout,omitempty"`
+	MaxIdleConnsPerHost   int           `json:"maxIdleConnsPerHost,omitempty"`
+	TLS                   *TransportTLS `json:"tls,omitempty"`
+	// ProxyProtocolVersion is the version of the PROXY protocol header sent to the servers, 1 or 2, no header being sent when 0
+	ProxyProtocolVersion int `json:"proxyProtocolVersion,omitempty"`
+}
+
+// TransportTLS holds the TLS configuration of the connections to the servers
+// of a backend, the CA and certificates being either file paths or PEM contents
+type TransportTLS struct {
+	ServerName         string `json:"serverName,omitempty"`
+	RootCA             string `json:"rootCA,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// PassiveHealthCheck holds the configuration ejecting the servers failing proxied requests
+type PassiveHealthCheck struct {
+	ConsecutiveErrors  int    `json:"consecutiveErrors,omitempty"`
+	BaseEjectionTime   string `json:"baseEjectionTime,omitempty"`
+	MaxEjectionTime    string `json:"maxEjectionTime,omitempty"`
+	MaxEjectionPercent int    `json:"maxEjectionPercent,omitempty"`
+}
+
+// Retry holds the configuration retrying the requests failing to reach a server
+type Retry struct {
+	Attempts        int            `json:"attempts,omitempty" description:"Number of attempts"`
+	Methods         HTTPMethods    `json:"methods,omitempty" description:"HTTP methods of the requests retried, the idempotent ones when empty"`
+	InitialInterval flaeg.Duration `json:"initialInterval,omitempty" description:"Maximum wait before the first retry, doubling with each attempt"`
+	MaxInterval     flaeg.Duration `json:"maxInterval,omitempty" description:"Maximum wait between two attempts"`
+	Budget          int            `json:"budget,omitempty" description:"Maximum percentage of the requests which can be retried, unlimited when 0"`
+}
+
+// HTTPMethods holds a list of HTTP methods
+type HTTPMethods []string
+
+//Set adds strings elem into the the parser
+//it splits str on "," and ";"
+func (m *HTTPMethods) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	*m = append(*m, strings.FieldsFunc(str, fargs)...)
+	return nil
+}
+
+//Get []string
+func (m *HTTPMethods) Get() interface{} { return HTTPMethods(*m) }
+
+//String return slice in a string
+func (m *HTTPMethods) String() string { return fmt.Sprintf("%v", *m) }
+
+//SetValue sets []string into the parser
+func (m *HTTPMethods) SetValue(val interface{}) {
+	*m = HTTPMethods(val.(HTTPMethods))
 }
 
 // MaxConn holds maximum connection configuration
@@ -28,8 +96,20 @@ type MaxConn struct {
 
 // LoadBalancer holds load balancing configuration.
 type LoadBalancer struct {
-	Method string `json:"method,omitempty"`
-	Sticky bool   `json:"sticky,omitempty"`
+	Method     string      `json:"method,omitempty"`
+	Sticky     bool        `json:"sticky,omitempty"`
+	Stickiness *Stickiness `json:"stickiness,omitempty"`
+	HashKey    string      `json:"hashKey,omitempty"`
+}
+
+// Stickiness holds sticky session configuration.
+type Stickiness struct {
+	CookieName string `json:"cookieName,omitempty"`
+	Secure     bool   `json:"secure,omitempty"`
+	HTTPOnly   bool   `json:"httpOnly,omitempty"`
+	SameSite   string `json:"sameSite,omitempty"`
+	MaxAge     int    `json:"maxAge,omitempty"`
+	HashServer bool   `json:"hashServer,omitempty"`
 }
 
 // CircuitBreaker holds circuit breaker configuration.
@@ -39,8 +119,16 @@ type CircuitBreaker struct {
 
 // HealthCheck holds HealthCheck configuration
 type HealthCheck struct {
-	Path     string `json:"path,omitempty"`
-	Interval string `json:"interval,omitempty"`
+	Path               string            `json:"path,omitempty"`
+	Port               int               `json:"port,omitempty"`
+	Scheme             string            `json:"scheme,omitempty"`
+	Hostname           string            `json:"hostname,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	Interval           string            `json:"interval,omitempty"`
+	Timeout            string            `json:"timeout,omitempty"`
+	Status             string            `json:"status,omitempty"`
+	HealthyThreshold   int               `json:"healthyThreshold,omitempty"`
+	UnhealthyThreshold int               `json:"unhealthyThreshold,omitempty"`
 }
 
 // Server holds server configuration.
@@ -62,6 +150,153 @@ type Frontend struct {
 	PassHostHeader bool             `json:"passHostHeader,omitempty"`
 	Priority       int              `json:"priority"`
 	BasicAuth      []string         `json:"basicAuth"`
+	Headers        *Headers         `json:"headers,omitempty"`
+
+	WhitelistSourceRange []string              `json:"whitelistSourceRange,omitempty"`
+	RateLimit            *RateLimit            `json:"ratelimit,omitempty"`
+	Auth                 *Auth                 `json:"auth,omitempty"`
+	Errors               map[string]*ErrorPage `json:"errors,omitempty"`
+	Redirect             *Redirect             `json:"redirect,omitempty"`
+	Mirror               *Mirror               `json:"mirror,omitempty"`
+	WeightedBackends     *WeightedBackends     `json:"weightedBackends,omitempty"`
+	Buffering            *Buffering            `json:"buffering,omitempty"`
+}
+
+// BackendNames returns the names of the backends of the frontend, sorted when
+// the frontend splits its traffic between several backends
+func (f *Frontend) BackendNames() []string {
+	if f.WeightedBackends == nil || len(f.WeightedBackends.Backends) == 0 {
+		return []string{f.Backend}
+	}
+	var names []string
+	for name := range f.WeightedBackends.Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WeightedBackends splits the traffic of a frontend between several backends
+// according to their weights, replacing the frontend backend
+type WeightedBackends struct {
+	Backends   map[string]int `json:"backends,omitempty"`
+	Sticky     bool           `json:"sticky,omitempty"`
+	CookieName string         `json:"cookieName,omitempty"`
+}
+
+// Mirror configures the mirroring of a sample of the frontend requests to a
+// secondary backend, the responses of which are discarded
+type Mirror struct {
+	Backend     string `json:"backend,omitempty"`
+	Percent     *int   `json:"percent,omitempty"`
+	MaxBodySize int64  `json:"maxBodySize,omitempty"`
+}
+
+// Buffering configures the buffering of the requests and responses of a
+// frontend, the sizes being in bytes and unlimited when zero
+type Buffering struct {
+	MaxRequestBodyBytes  int64  `json:"maxRequestBodyBytes,omitempty"`
+	MemRequestBodyBytes  int64  `json:"memRequestBodyBytes,omitempty"`
+	MaxResponseBodyBytes int64  `json:"maxResponseBodyBytes,omitempty"`
+	MemResponseBodyBytes int64  `json:"memResponseBodyBytes,omitempty"`
+	RetryExpression      string `json:"retryExpression,omitempty"`
+}
+
+// Redirect configures a redirection of a frontend to an entry point, or to an URL
+type Redirect struct {
+	EntryPoint  string `json:"entryPoint,omitempty"`
+	Regex       string `json:"regex,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	Permanent   bool   `json:"permanent,omitempty"`
+}
+
+// ErrorPage holds custom error page configuration
+// The page is requested on Backend at Query, where {status} is replaced by the status code
+type ErrorPage struct {
+	Status  []string `json:"status,omitempty"`
+	Backend string   `json:"backend,omitempty"`
+	Query   string   `json:"query,omitempty"`
+}
+
+// ParseHTTPCodeRange parses a single status code (e.g. 404) or a range of status codes (e.g. 500-599)
+func ParseHTTPCodeRange(status string) ([2]int, error) {
+	bounds := strings.SplitN(strings.TrimSpace(status), "-", 2)
+	from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return [2]int{}, fmt.Errorf("invalid status %q: %v", status, err)
+	}
+	to := from
+	if len(bounds) == 2 {
+		to, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return [2]int{}, fmt.Errorf("invalid status %q: %v", status, err)
+		}
+	}
+	if from > to {
+		return [2]int{}, fmt.Errorf("invalid status %q: %d is greater than %d", status, from, to)
+	}
+	return [2]int{from, to}, nil
+}
+
+// RateLimit holds the rate limiting configuration of a frontend
+type RateLimit struct {
+	RateSet       map[string]*Rate `json:"rateset,omitempty"`
+	ExtractorFunc string           `json:"extractorFunc,omitempty"`
+}
+
+// Rate holds the number of requests allowed on average and in burst over a period
+type Rate struct {
+	Period  flaeg.Duration `json:"period,omitempty"`
+	Average int64          `json:"average,omitempty"`
+	Burst   int64          `json:"burst,omitempty"`
+}
+
+// Headers holds the custom and security header configuration of a frontend
+type Headers struct {
+	CustomRequestHeaders  map[string]string `json:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders map[string]string `json:"customResponseHeaders,omitempty"`
+	RemoveRequestHeaders  []string          `json:"removeRequestHeaders,omitempty"`
+	RemoveResponseHeaders []string          `json:"removeResponseHeaders,omitempty"`
+
+	AllowedHosts            []string          `json:"allowedHosts,omitempty"`
+	HostsProxyHeaders       []string          `json:"hostsProxyHeaders,omitempty"`
+	SSLRedirect             bool              `json:"sslRedirect,omitempty"`
+	SSLTemporaryRedirect    bool              `json:"sslTemporaryRedirect,omitempty"`
+	SSLHost                 string            `json:"sslHost,omitempty"`
+	SSLProxyHeaders         map[string]string `json:"sslProxyHeaders,omitempty"`
+	STSSeconds              int64             `json:"stsSeconds,omitempty"`
+	STSIncludeSubdomains    bool              `json:"stsIncludeSubdomains,omitempty"`
+	STSPreload              bool              `json:"stsPreload,omitempty"`
+	ForceSTSHeader          bool              `json:"forceSTSHeader,omitempty"`
+	FrameDeny               bool              `json:"frameDeny,omitempty"`
+	CustomFrameOptionsValue string            `json:"customFrameOptionsValue,omitempty"`
+	ContentTypeNosniff      bool              `json:"contentTypeNosniff,omitempty"`
+	BrowserXSSFilter        bool              `json:"browserXssFilter,omitempty"`
+	ContentSecurityPolicy   string            `json:"contentSecurityPolicy,omitempty"`
+	ReferrerPolicy          string            `json:"referrerPolicy,omitempty"`
+	IsDevelopment           bool              `json:"isDevelopment,omitempty"`
+}
+
+// HasCustomHeadersDefined checks to see if any of the custom header elements have been set
+func (h *Headers) HasCustomHeadersDefined() bool {
+	return h != nil && (len(h.CustomRequestHeaders) != 0 ||
+		len(h.CustomResponseHeaders) != 0 ||
+		len(h.RemoveRequestHeaders) != 0 ||
+		len(h.RemoveResponseHeaders) != 0)
+}
+
+// HasSecureHeadersDefined checks to see if any of the secure header elements have been set
+func (h *Headers) HasSecureHeadersDefined() bool {
+	return h != nil && (len(h.AllowedHosts) != 0 ||
+		h.SSLRedirect ||
+		h.SSLTemporaryRedirect ||
+		h.STSSeconds != 0 ||
+		h.FrameDeny ||
+		h.CustomFrameOptionsValue != "" ||
+		h.ContentTypeNosniff ||
+		h.BrowserXSSFilter ||
+		h.ContentSecurityPolicy != "" ||
+		h.ReferrerPolicy != "")
 }
 
 // LoadBalancerMethod holds the method of load balancing to use.
@@ -72,11 +307,20 @@ const (
 	Wrr LoadBalancerMethod = iota
 	// Drr = Dynamic Round Robin
 	Drr
+	// LeastConn = Least Connections
+	LeastConn
+	// P2C = Power of Two Choices
+	P2C
+	// ConsistentHash = Consistent Hashing on the HashKey of the request
+	ConsistentHash
 )
 
 var loadBalancerMethodNames = []string{
 	"Wrr",
 	"Drr",
+	"LeastConn",
+	"P2C",
+	"ConsistentHash",
 }
 
 // NewLoadBalancerMethod create a new LoadBalancerMethod from a given LoadBalancer.
@@ -98,6 +342,22 @@ var ErrInvalidLoadBalancerMethod = errors.New("Invalid method, using default")
 type Configuration struct {
 	Backends  map[string]*Backend  `json:"backends,omitempty"`
 	Frontends map[string]*Frontend `json:"frontends,omitempty"`
+	TLS       []*TLSConfiguration  `json:"tls,omitempty"`
+}
+
+// TLSConfiguration holds a certificate supplied by a provider, served on the
+// given entrypoints, or on all the TLS ones if none is given
+type TLSConfiguration struct {
+	EntryPoints []string     `json:"entryPoints,omitempty"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+// Certificate holds a cert/key pair
+// Cert and Key could be either a file path, or the file content itself
+// The key is never marshalled, so that it does not show up in the logs and the API
+type Certificate struct {
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"-"`
 }
 
 // ConfigMessage hold configuration information exchanged between parts of traefik.
@@ -230,10 +490,11 @@ type Cluster struct {
 	Store *Store
 }
 
-// Auth holds authentication configuration (BASIC, DIGEST, users)
+// Auth holds authentication configuration (BASIC, DIGEST, FORWARD, users)
 type Auth struct {
 	Basic       *Basic
 	Digest      *Digest
+	Forward     *Forward
 	HeaderField string
 }
 
@@ -252,6 +513,14 @@ type Digest struct {
 	UsersFile string
 }
 
+// Forward authentication
+// Address is the URL of the authentication server, called for every request
+type Forward struct {
+	Address             string
+	TrustForwardHeader  bool
+	AuthResponseHeaders []string
+}
+
 // CanonicalDomain returns a lower case domain with trim space
 func CanonicalDomain(domain string) string {
 	return strings.ToLower(strings.TrimSpace(domain))
@@ -272,6 +541,37 @@ type Prometheus struct {
 	Buckets Buckets `description:"Buckets for latency metrics"`
 }
 
+// AccessLog holds the configuration settings for the access logger (middlewares/accesslog).
+type AccessLog struct {
+	FilePath string          `json:"file,omitempty" description:"Access log file path. Stdout is used when omitted or empty"`
+	Format   string          `json:"format,omitempty" description:"Access log format: json | common"`
+	Fields   AccessLogFields `json:"fields,omitempty" description:"Fields written by the json format, using the accesslog key names"`
+}
+
+// AccessLogFields holds the names of the fields written to the access log
+type AccessLogFields []string
+
+//Set adds strings elem into the the parser
+//it splits str on "," and ";"
+func (f *AccessLogFields) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	*f = append(*f, strings.FieldsFunc(str, fargs)...)
+	return nil
+}
+
+//Get []string
+func (f *AccessLogFields) Get() interface{} { return AccessLogFields(*f) }
+
+//String return slice in a string
+func (f *AccessLogFields) String() string { return fmt.Sprintf("%v", *f) }
+
+//SetValue sets []string into the parser
+func (f *AccessLogFields) SetValue(val interface{}) {
+	*f = AccessLogFields(val.(AccessLogFields))
+}
+
 // Buckets holds Prometheus Buckets
 type Buckets []float64
 
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHTTPCodeRange(t *testing.T) {
+	testCases := []struct {
+		status   string
+		expected [2]int
+		err      bool
+	}{
+		{status: "404", expected: [2]int{404, 404}},
+		{status: "500-599", expected: [2]int{500, 599}},
+		{status: " 500 - 599 ", expected: [2]int{500, 599}},
+		{status: "599-500", err: true},
+		{status: "foo", err: true},
+		{status: "500-", err: true},
+	}
+
+	for _, test := range testCases {
+		httpCodeRange, err := ParseHTTPCodeRange(test.status)
+		if test.err {
+			assert.Error(t, err, test.status)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, test.expected, httpCodeRange, test.status)
+	}
+}
@@ -0,0 +1,88 @@
+package whitelist
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IP allows to check that addresses are in a white list
+type IP struct {
+	whiteListsIPs []*net.IP
+	whiteListsNet []*net.IPNet
+}
+
+// NewIP builds a new IP given a list of CIDR-Strings or plain IPs to whitelist
+func NewIP(whitelistStrings []string) (*IP, error) {
+	if len(whitelistStrings) == 0 {
+		return nil, fmt.Errorf("no whitelists provided")
+	}
+
+	ip := IP{}
+
+	for _, whitelistString := range whitelistStrings {
+		whitelistString = strings.TrimSpace(whitelistString)
+		if whitelistString == "" {
+			continue
+		}
+		ipAddr := net.ParseIP(whitelistString)
+		if ipAddr != nil {
+			ip.whiteListsIPs = append(ip.whiteListsIPs, &ipAddr)
+		} else {
+			_, whitelist, err := net.ParseCIDR(whitelistString)
+			if err != nil {
+				return nil, fmt.Errorf("parsing CIDR whitelist %s: %v", whitelistString, err)
+			}
+			ip.whiteListsNet = append(ip.whiteListsNet, whitelist)
+		}
+	}
+	// a white list of blank entries would deny all the traffic
+	if len(ip.whiteListsIPs) == 0 && len(ip.whiteListsNet) == 0 {
+		return nil, fmt.Errorf("no valid whitelists provided in %q", whitelistStrings)
+	}
+
+	return &ip, nil
+}
+
+// Contains checks if provided address is in the white list
+func (ip *IP) Contains(addr string) (bool, net.IP, error) {
+	ipAddr, err := ipFromRemoteAddr(addr)
+	if err != nil {
+		return false, nil, fmt.Errorf("unable to parse address: %s: %s", addr, err)
+	}
+
+	return ip.ContainsIP(ipAddr), ipAddr, nil
+}
+
+// ContainsIP checks if provided address is in the white list
+func (ip *IP) ContainsIP(addr net.IP) bool {
+	for _, whiteListIP := range ip.whiteListsIPs {
+		if whiteListIP.Equal(addr) {
+			return true
+		}
+	}
+
+	for _, whiteListNet := range ip.whiteListsNet {
+		if whiteListNet.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ipFromRemoteAddr(addr string) (net.IP, error) {
+	userIP := net.ParseIP(addr)
+	if userIP == nil {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		userIP = net.ParseIP(host)
+		if userIP == nil {
+			return nil, fmt.Errorf("can't parse IP from address %s", host)
+		}
+	}
+
+	return userIP, nil
+}
@@ -0,0 +1,77 @@
+package whitelist
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIP(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		whitelistStrings []string
+		expectError      bool
+	}{
+		{
+			desc:        "nil whitelist",
+			expectError: true,
+		},
+		{
+			desc:             "blank whitelists",
+			whitelistStrings: []string{"", " "},
+			expectError:      true,
+		},
+		{
+			desc:             "invalid CIDR",
+			whitelistStrings: []string{"foo"},
+			expectError:      true,
+		},
+		{
+			desc:             "CIDRs and IPs",
+			whitelistStrings: []string{"1.2.3.4/24", "10.0.0.1", "fe80::/16"},
+		},
+	}
+
+	for _, test := range testCases {
+		ip, err := NewIP(test.whitelistStrings)
+		if test.expectError {
+			assert.Error(t, err, test.desc)
+			assert.Nil(t, ip, test.desc)
+		} else {
+			assert.NoError(t, err, test.desc)
+			assert.NotNil(t, ip, test.desc)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	ip, err := NewIP([]string{"1.2.3.4/24", "10.0.0.1", "fe80::/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "1.2.3.1", expected: true},
+		{addr: "1.2.3.254:8080", expected: true},
+		{addr: "10.0.0.1", expected: true},
+		{addr: "[fe80::1]:8080", expected: true},
+		{addr: "1.2.4.1", expected: false},
+		{addr: "10.0.0.2:80", expected: false},
+		{addr: "2001:db8::1", expected: false},
+	}
+
+	for _, test := range testCases {
+		contains, _, err := ip.Contains(test.addr)
+		assert.NoError(t, err, test.addr)
+		assert.Equal(t, test.expected, contains, test.addr)
+	}
+
+	_, _, err = ip.Contains("foo")
+	assert.Error(t, err)
+
+	assert.True(t, ip.ContainsIP(net.ParseIP("1.2.3.5")))
+}